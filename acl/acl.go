@@ -0,0 +1,235 @@
+// Package acl implements Redis style access control lists: users with
+// passwords, the commands and command categories they may run and the
+// keys they may access.
+package acl
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DefaultUser is the user connections start as
+const DefaultUser = "default"
+
+// Categories are the command categories rules can refer to with @
+var Categories = []string{
+	"keyspace", "read", "write", "set", "sortedset", "list", "hash", "string",
+	"bitmap", "hyperloglog", "geo", "stream", "pubsub", "admin", "fast",
+	"slow", "blocking", "dangerous", "connection", "transaction", "scripting",
+}
+
+var (
+	// ErrWrongPass is returned when authentication fails
+	ErrWrongPass = errors.New("invalid username-password pair or user is disabled")
+
+	// ErrNoUser is returned for users that don't exist
+	ErrNoUser = errors.New("no such user")
+)
+
+// Commands tells the ACL about the commands of the server
+type Commands interface {
+	// Exists reports whether a command, or a command|subcommand, exists
+	Exists(name string) bool
+}
+
+// RuleError reports an invalid rule given to SetUser
+type RuleError struct {
+	Rule string
+	Err  error
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("Error in ACL SETUSER modifier '%s': %s", e.Rule, e.Err)
+}
+
+func (e *RuleError) Unwrap() error {
+	return e.Err
+}
+
+// PermError reports a command or key a user is not allowed to use
+type PermError struct {
+	User string
+
+	// Reason is "command" or "key"
+	Reason string
+
+	// Object is the command or the key
+	Object string
+}
+
+func (e *PermError) Error() string {
+	if e.Reason == "key" {
+		return "No permissions to access a key"
+	}
+
+	return fmt.Sprintf("User %s has no permissions to run the '%s' command", e.User, e.Object)
+}
+
+// ACL holds the users of a server
+type ACL struct {
+	commands Commands
+	log      *Log
+
+	mu    sync.RWMutex
+	users map[string]*User
+}
+
+// New returns an ACL with only the default user, who may do anything
+// without a password
+func New(commands Commands) *ACL {
+	a := &ACL{
+		commands: commands,
+		log:      NewLog(DefaultLogLen),
+		users:    map[string]*User{},
+	}
+	a.users[DefaultUser] = defaultUser()
+
+	return a
+}
+
+func defaultUser() *User {
+	return &User{
+		Name:     DefaultUser,
+		Enabled:  true,
+		NoPass:   true,
+		Keys:     []string{"*"},
+		Commands: []Rule{{Allow: true, Name: "all", Category: true}},
+	}
+}
+
+// Log returns the log of denied commands and failed authentications
+func (a *ACL) Log() *Log {
+	return a.log
+}
+
+// User returns a user
+func (a *ACL) User(name string) (*User, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	u, ok := a.users[name]
+	return u, ok
+}
+
+// Users returns the names of every user, sorted
+func (a *ACL) Users() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	names := make([]string, 0, len(a.users))
+	for name := range a.users {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// List describes every user, sorted by name
+func (a *ACL) List() []string {
+	names := a.Users()
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	list := make([]string, 0, len(names))
+	for _, name := range names {
+		if u, ok := a.users[name]; ok {
+			list = append(list, u.Describe())
+		}
+	}
+
+	return list
+}
+
+// SetUser creates or changes a user by applying rules in order. A new user
+// starts disabled without passwords, commands or keys. When a rule is
+// invalid the user is left unchanged.
+func (a *ACL) SetUser(name string, rules ...string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	u, err := a.buildUser(a.users[name], name, rules)
+	if err != nil {
+		return err
+	}
+	a.users[name] = u
+
+	return nil
+}
+
+func (a *ACL) buildUser(current *User, name string, rules []string) (*User, error) {
+	var u *User
+	if current != nil {
+		u = current.clone()
+	} else {
+		u = &User{Name: name}
+	}
+
+	for _, rule := range rules {
+		if err := u.apply(rule, a.commands); err != nil {
+			return nil, &RuleError{Rule: rule, Err: err}
+		}
+	}
+
+	return u, nil
+}
+
+// DelUser deletes users and returns how many existed. The default user
+// can't be deleted.
+func (a *ACL) DelUser(names ...string) (int, error) {
+	if slices.Contains(names, DefaultUser) {
+		return 0, errors.New("The 'default' user cannot be removed")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	deleted := 0
+	for _, name := range names {
+		if _, ok := a.users[name]; ok {
+			delete(a.users, name)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// Authenticate checks the password of a user
+func (a *ACL) Authenticate(name, password string) error {
+	u, ok := a.User(name)
+	if !ok || !u.CheckPassword(password) {
+		return ErrWrongPass
+	}
+
+	return nil
+}
+
+// Check returns a PermError unless the user may run command, or its
+// subcommand when not empty, on keys. categories are those of the command.
+func (a *ACL) Check(name, command, subcommand string, categories []string, keys []string) error {
+	u, ok := a.User(name)
+	if !ok {
+		return ErrNoUser
+	}
+
+	if !u.CanRun(command, subcommand, categories) {
+		object := strings.ToLower(command)
+		if subcommand != "" {
+			object += "|" + strings.ToLower(subcommand)
+		}
+		return &PermError{User: name, Reason: "command", Object: object}
+	}
+
+	for _, key := range keys {
+		if !u.CanAccessKey(key) {
+			return &PermError{User: name, Reason: "key", Object: key}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package acl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCommands knows a few commands and their categories
+type testCommands map[string][]string
+
+func (c testCommands) Exists(name string) bool {
+	name, _, _ = strings.Cut(name, "|")
+	_, ok := c[name]
+	return ok
+}
+
+var commands = testCommands{
+	"get":    {"read", "string", "fast"},
+	"set":    {"write", "string", "slow"},
+	"hset":   {"write", "hash", "fast"},
+	"config": {"admin", "slow", "dangerous"},
+}
+
+func check(a *ACL, user, command string, keys ...string) error {
+	command, subcommand, _ := strings.Cut(command, "|")
+	return a.Check(user, command, subcommand, commands[command], keys)
+}
+
+func TestDefaultUser(t *testing.T) {
+	a := New(commands)
+
+	assert.NoError(t, a.Authenticate(DefaultUser, "anything"))
+	assert.NoError(t, check(a, DefaultUser, "config|set", "any"))
+	assert.Equal(t, []string{"user default on nopass ~* +@all"}, a.List())
+}
+
+func TestSetUser(t *testing.T) {
+	a := New(commands)
+
+	require.NoError(t, a.SetUser("alice", "on", ">secret", "~cache:*", "+@read", "+set", "-@dangerous"))
+
+	u, ok := a.User("alice")
+	require.True(t, ok)
+	assert.Equal(t, "user alice on #"+HashPassword("secret")+" ~cache:* +@read +set -@dangerous", u.Describe())
+
+	assert.NoError(t, a.Authenticate("alice", "secret"))
+	assert.ErrorIs(t, a.Authenticate("alice", "wrong"), ErrWrongPass)
+	assert.ErrorIs(t, a.Authenticate("bob", "secret"), ErrWrongPass)
+
+	assert.NoError(t, check(a, "alice", "get", "cache:1"))
+	assert.NoError(t, check(a, "alice", "set", "cache:1"))
+
+	var perr *PermError
+	require.ErrorAs(t, check(a, "alice", "hset", "cache:1"), &perr)
+	assert.Equal(t, "User alice has no permissions to run the 'hset' command", perr.Error())
+
+	require.ErrorAs(t, check(a, "alice", "get", "user:1"), &perr)
+	assert.Equal(t, PermError{User: "alice", Reason: "key", Object: "user:1"}, *perr)
+
+	require.ErrorAs(t, check(a, "alice", "config|get"), &perr)
+	assert.Equal(t, "config|get", perr.Object)
+
+	// rules apply on top of the existing ones
+	require.NoError(t, a.SetUser("alice", "+config|get", "off"))
+	assert.NoError(t, check(a, "alice", "config|get"))
+	assert.Error(t, check(a, "alice", "config|set"))
+	assert.ErrorIs(t, a.Authenticate("alice", "secret"), ErrWrongPass, "disabled users can't log in")
+}
+
+func TestSetUserRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []string
+		want  string
+	}{
+		{name: "new user", want: "user u off -@all"},
+		{name: "all", rules: []string{"on", "nopass", "allkeys", "allcommands"}, want: "user u on nopass ~* +@all"},
+		{name: "minus all resets", rules: []string{"+get", "+@write", "-@all", "+get"}, want: "user u off +get"},
+		{name: "password removed", rules: []string{">a", ">b", "<a"}, want: "user u off #" + HashPassword("b") + " -@all"},
+		{name: "nopass clears passwords", rules: []string{">a", "nopass"}, want: "user u off nopass -@all"},
+		{name: "hash", rules: []string{"#" + HashPassword("a")}, want: "user u off #" + HashPassword("a") + " -@all"},
+		{name: "reset", rules: []string{"on", "~*", "+@all", "reset"}, want: "user u off -@all"},
+		{name: "resetkeys", rules: []string{"~a", "~b", "resetkeys", "~c"}, want: "user u off ~c -@all"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(commands)
+			require.NoError(t, a.SetUser("u", tt.rules...))
+
+			u, _ := a.User("u")
+			assert.Equal(t, tt.want, u.Describe())
+		})
+	}
+}
+
+func TestSetUserErrors(t *testing.T) {
+	a := New(commands)
+	require.NoError(t, a.SetUser("alice", "on", "+get"))
+
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{rule: "+nosuchcommand", want: "Error in ACL SETUSER modifier '+nosuchcommand': Unknown command or category name in ACL"},
+		{rule: "-@nosuchcategory", want: "Error in ACL SETUSER modifier '-@nosuchcategory': Unknown command or category name in ACL"},
+		{rule: "#abc", want: "Error in ACL SETUSER modifier '#abc': The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters"},
+		{rule: "<missing", want: "Error in ACL SETUSER modifier '<missing': no such password"},
+		{rule: "bogus", want: "Error in ACL SETUSER modifier 'bogus': Syntax error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			err := a.SetUser("alice", "+set", tt.rule)
+			require.Error(t, err)
+			assert.Equal(t, tt.want, err.Error())
+
+			// nothing of a failed call is applied
+			assert.Error(t, check(a, "alice", "set"))
+		})
+	}
+}
+
+func TestDelUser(t *testing.T) {
+	a := New(commands)
+	require.NoError(t, a.SetUser("alice"))
+	require.NoError(t, a.SetUser("bob"))
+
+	n, err := a.DelUser("alice", "carol")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"bob", "default"}, a.Users())
+
+	_, err = a.DelUser("bob", "default")
+	assert.Error(t, err)
+	assert.Equal(t, []string{"bob", "default"}, a.Users())
+
+	assert.ErrorIs(t, check(a, "alice", "get"), ErrNoUser)
+}
+
+func TestLog(t *testing.T) {
+	l := NewLog(2)
+
+	l.Add("command", "set", "alice", "id=1")
+	l.Add("key", "user:1", "alice", "id=1")
+	l.Add("command", "set", "alice", "id=2")
+
+	entries := l.Entries(-1)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "set", entries[0].Object)
+	assert.Equal(t, 2, entries[0].Count)
+	assert.Equal(t, "id=2", entries[0].ClientInfo)
+	assert.Equal(t, "user:1", entries[1].Object)
+
+	l.Add("auth", "AUTH", "bob", "id=3")
+	entries = l.Entries(-1)
+	require.Len(t, entries, 2, "the log is bounded")
+	assert.Equal(t, "AUTH", entries[0].Object)
+	assert.Equal(t, "set", entries[1].Object)
+
+	assert.Len(t, l.Entries(1), 1)
+
+	l.Reset()
+	assert.Empty(t, l.Entries(-1))
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.acl")
+
+	a := New(commands)
+	require.NoError(t, a.SetUser("alice", "on", ">secret", "~cache:*", "+@read"))
+	require.NoError(t, a.SetUser(DefaultUser, "resetpass", ">admin"))
+	require.NoError(t, a.Save(path))
+
+	b := New(commands)
+	require.NoError(t, b.Load(path))
+	assert.Equal(t, a.List(), b.List())
+	assert.NoError(t, b.Authenticate("alice", "secret"))
+
+	t.Run("default user is created", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "users.acl")
+		require.NoError(t, os.WriteFile(path, []byte("# users\nuser bob on nopass ~* +get\n"), 0600))
+
+		c := New(commands)
+		require.NoError(t, c.Load(path))
+		assert.Equal(t, []string{"bob", "default"}, c.Users())
+	})
+
+	t.Run("names and patterns with spaces", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "users.acl")
+
+		c := New(commands)
+		require.NoError(t, c.SetUser("bob smith", "on", "nopass", "~foo bar", "~say\"hi\"", "+get"))
+		require.NoError(t, c.Save(path))
+		u, _ := c.User("bob smith")
+		assert.Equal(t, `user "bob smith" on nopass "~foo bar" "~say\"hi\"" +get`, u.Describe())
+
+		d := New(commands)
+		require.NoError(t, d.Load(path))
+		assert.Equal(t, c.List(), d.List())
+		u, _ = d.User("bob smith")
+		assert.Equal(t, []string{"foo bar", `say"hi"`}, u.Keys)
+	})
+
+	t.Run("errors keep the users", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "users.acl")
+		require.NoError(t, os.WriteFile(path, []byte("user bob on\nuser carol +nosuchcommand\n"), 0600))
+
+		c := New(commands)
+		err := c.Load(path)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), ":2:")
+		assert.Equal(t, []string{"default"}, c.Users())
+	})
+}
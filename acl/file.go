@@ -0,0 +1,94 @@
+package acl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/helewud/redis-clone/resp"
+)
+
+// Load replaces the users with those of an ACL file, which holds one user
+// per line as written by Save. The default user is created with its usual
+// rules when the file doesn't mention it. On error nothing changes.
+func (a *ACL) Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	users := map[string]*User{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		args, err := resp.SplitArgs(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		if len(args) < 2 || args[0] != "user" {
+			return fmt.Errorf("%s:%d: lines must start with user and a name", path, n)
+		}
+		if _, ok := users[args[1]]; ok {
+			return fmt.Errorf("%s:%d: duplicate user '%s'", path, n, args[1])
+		}
+
+		u, err := a.buildUser(nil, args[1], args[2:])
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		users[u.Name] = u
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if _, ok := users[DefaultUser]; !ok {
+		users[DefaultUser] = defaultUser()
+	}
+
+	a.mu.Lock()
+	a.users = users
+	a.mu.Unlock()
+
+	return nil
+}
+
+// Save writes the users to an ACL file, replacing it at once
+func (a *ACL) Save(path string) error {
+	if path == "" {
+		return errors.New("no ACL file configured")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for _, line := range a.List() {
+		w.WriteString(line + "\n")
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,90 @@
+package acl
+
+// Match reports whether str matches the glob-style pattern the way Redis
+// matches keys: * matches any run of characters including none, ? any single
+// character, [abc] and [a-z] a set or range, [^a] anything else and \x the
+// character x.
+func Match(pattern, str string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			// collapse runs of stars
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(str); i++ {
+				if Match(pattern[1:], str[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(str) == 0 {
+				return false
+			}
+			str = str[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(str) == 0 {
+				return false
+			}
+			var ok bool
+			pattern, ok = matchClass(pattern[1:], str[0])
+			if !ok {
+				return false
+			}
+			str = str[1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(str) == 0 || str[0] != pattern[0] {
+				return false
+			}
+			str = str[1:]
+			pattern = pattern[1:]
+		}
+	}
+
+	return len(str) == 0
+}
+
+// matchClass matches c against the class at the start of pattern, just past
+// its [, and returns the pattern after the class
+func matchClass(pattern string, c byte) (string, bool) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+
+	match := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) > 1:
+			match = match || pattern[1] == c
+			pattern = pattern[2:]
+		case len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']':
+			lo, hi := pattern[0], pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			match = match || (c >= lo && c <= hi)
+			pattern = pattern[3:]
+		default:
+			match = match || pattern[0] == c
+			pattern = pattern[1:]
+		}
+	}
+
+	// skip the closing bracket, an unclosed class ends the pattern
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+
+	return pattern, match != negate
+}
@@ -0,0 +1,108 @@
+package acl
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultLogLen is how many entries the log keeps, like Redis's
+// acllog-max-len
+const DefaultLogLen = 128
+
+// LogEntry is a denied command or a failed authentication. Repeated
+// failures of the same kind are grouped into one entry.
+type LogEntry struct {
+	ID int64
+
+	// Count is how many times it happened
+	Count int
+
+	// Reason is "command", "key" or "auth"
+	Reason string
+
+	// Context is where it happened, always "toplevel" for now
+	Context string
+
+	// Object is the command, the key or AUTH
+	Object string
+
+	Username   string
+	ClientInfo string
+	Created    time.Time
+	Updated    time.Time
+}
+
+// Log keeps the latest ACL failures, newest first
+type Log struct {
+	mu      sync.Mutex
+	entries []LogEntry
+	maxLen  int
+	nextID  int64
+}
+
+func NewLog(maxLen int) *Log {
+	return &Log{maxLen: maxLen}
+}
+
+// Add records a failure. One matching a recent entry bumps its count
+// instead of adding another.
+func (l *Log) Add(reason, object, username, clientInfo string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for i, e := range l.entries {
+		if e.Reason == reason && e.Object == object && e.Username == username {
+			e.Count++
+			e.ClientInfo = clientInfo
+			e.Updated = now
+
+			// move it back to the front
+			copy(l.entries[1:i+1], l.entries[:i])
+			l.entries[0] = e
+			return
+		}
+	}
+
+	entry := LogEntry{
+		ID:         l.nextID,
+		Count:      1,
+		Reason:     reason,
+		Context:    "toplevel",
+		Object:     object,
+		Username:   username,
+		ClientInfo: clientInfo,
+		Created:    now,
+		Updated:    now,
+	}
+	l.nextID++
+
+	l.entries = append([]LogEntry{entry}, l.entries...)
+	if len(l.entries) > l.maxLen {
+		l.entries = l.entries[:l.maxLen]
+	}
+}
+
+// Entries returns up to n entries, newest first, or all of them when n is
+// negative
+func (l *Log) Entries(n int) []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if n < 0 || n > len(l.entries) {
+		n = len(l.entries)
+	}
+
+	entries := make([]LogEntry, n)
+	copy(entries, l.entries)
+
+	return entries
+}
+
+// Reset empties the log
+func (l *Log) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = nil
+}
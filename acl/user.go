@@ -0,0 +1,248 @@
+package acl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/helewud/redis-clone/resp"
+)
+
+// User is an ACL user. Users are never changed in place: SetUser replaces
+// them, so a User can be read without locking.
+type User struct {
+	Name     string
+	Enabled  bool
+	NoPass   bool
+	Password []string // SHA-256 hashes, hex encoded
+
+	// Commands are the command rules in the order they were given, the
+	// last one matching a command decides
+	Commands []Rule
+
+	// Keys are the glob patterns of the keys the user may access
+	Keys []string
+}
+
+// Rule allows or denies a command, a command|subcommand or, when Category
+// is set, a whole category where "all" stands for every command
+type Rule struct {
+	Allow    bool
+	Name     string
+	Category bool
+}
+
+func (r Rule) String() string {
+	sign := "-"
+	if r.Allow {
+		sign = "+"
+	}
+	if r.Category {
+		return sign + "@" + r.Name
+	}
+
+	return sign + r.Name
+}
+
+// HashPassword returns the hash of a password as stored on users
+func HashPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+func (u *User) clone() *User {
+	c := *u
+	c.Password = slices.Clone(u.Password)
+	c.Commands = slices.Clone(u.Commands)
+	c.Keys = slices.Clone(u.Keys)
+
+	return &c
+}
+
+// CheckPassword reports whether password lets the user in
+func (u *User) CheckPassword(password string) bool {
+	if !u.Enabled {
+		return false
+	}
+	if u.NoPass {
+		return true
+	}
+
+	return slices.Contains(u.Password, HashPassword(password))
+}
+
+// CanRun reports whether the user may run command, or its subcommand when
+// not empty, which belongs to categories
+func (u *User) CanRun(command, subcommand string, categories []string) bool {
+	command = strings.ToLower(command)
+	full := command
+	if subcommand != "" {
+		full += "|" + strings.ToLower(subcommand)
+	}
+
+	allowed := false
+	for _, r := range u.Commands {
+		var match bool
+		switch {
+		case r.Category:
+			match = r.Name == "all" || slices.Contains(categories, r.Name)
+		default:
+			match = r.Name == command || r.Name == full
+		}
+		if match {
+			allowed = r.Allow
+		}
+	}
+
+	return allowed
+}
+
+// CanAccessKey reports whether key matches one of the key patterns
+func (u *User) CanAccessKey(key string) bool {
+	for _, pattern := range u.Keys {
+		if Match(pattern, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Describe returns the rules that recreate the user, as shown by ACL LIST.
+// Names and key patterns are quoted when Load couldn't read them back
+// otherwise.
+func (u *User) Describe() string {
+	parts := []string{"user", quote(u.Name)}
+
+	if u.Enabled {
+		parts = append(parts, "on")
+	} else {
+		parts = append(parts, "off")
+	}
+	if u.NoPass {
+		parts = append(parts, "nopass")
+	}
+	for _, hash := range u.Password {
+		parts = append(parts, "#"+hash)
+	}
+	for _, pattern := range u.Keys {
+		parts = append(parts, quote("~"+pattern))
+	}
+	if len(u.Commands) == 0 {
+		parts = append(parts, "-@all")
+	}
+	for _, r := range u.Commands {
+		parts = append(parts, r.String())
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func quote(s string) string {
+	if resp.NeedsQuotes(s) {
+		return resp.QuoteArg(s)
+	}
+
+	return s
+}
+
+// apply changes the user by one ACL rule such as on, >password, ~key* or
+// +@read. commands tells whether a command exists.
+func (u *User) apply(rule string, commands Commands) error {
+	lower := strings.ToLower(rule)
+
+	switch lower {
+	case "on":
+		u.Enabled = true
+	case "off":
+		u.Enabled = false
+	case "nopass":
+		u.NoPass = true
+		u.Password = nil
+	case "resetpass":
+		u.NoPass = false
+		u.Password = nil
+	case "allkeys":
+		u.Keys = []string{"*"}
+	case "resetkeys":
+		u.Keys = nil
+	case "allcommands":
+		u.Commands = []Rule{{Allow: true, Name: "all", Category: true}}
+	case "nocommands":
+		u.Commands = nil
+	case "allchannels", "resetchannels":
+		// there are no channels yet, accepted for compatibility
+	case "reset":
+		*u = User{Name: u.Name}
+	default:
+		return u.applyPrefixed(rule, commands)
+	}
+
+	return nil
+}
+
+func (u *User) applyPrefixed(rule string, commands Commands) error {
+	if rule == "" {
+		return fmt.Errorf("Syntax error")
+	}
+
+	switch rule[0] {
+	case '>':
+		u.addPassword(HashPassword(rule[1:]))
+	case '<':
+		hash := HashPassword(rule[1:])
+		if !slices.Contains(u.Password, hash) {
+			return fmt.Errorf("no such password")
+		}
+		u.Password = slices.DeleteFunc(u.Password, func(h string) bool { return h == hash })
+	case '#':
+		hash := strings.ToLower(rule[1:])
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != 2*sha256.Size {
+			return fmt.Errorf("The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters")
+		}
+		u.addPassword(hash)
+	case '!':
+		hash := strings.ToLower(rule[1:])
+		if !slices.Contains(u.Password, hash) {
+			return fmt.Errorf("no such password")
+		}
+		u.Password = slices.DeleteFunc(u.Password, func(h string) bool { return h == hash })
+	case '~':
+		if !slices.Contains(u.Keys, rule[1:]) {
+			u.Keys = append(u.Keys, rule[1:])
+		}
+	case '+', '-':
+		r := Rule{Allow: rule[0] == '+', Name: strings.ToLower(rule[1:])}
+		if strings.HasPrefix(r.Name, "@") {
+			r.Category = true
+			r.Name = r.Name[1:]
+			if r.Name != "all" && !slices.Contains(Categories, r.Name) {
+				return fmt.Errorf("Unknown command or category name in ACL")
+			}
+		} else if !commands.Exists(r.Name) {
+			return fmt.Errorf("Unknown command or category name in ACL")
+		}
+
+		// -@all drops every rule before it, as nothing they allow survives
+		if r.Category && r.Name == "all" {
+			u.Commands = nil
+			if !r.Allow {
+				return nil
+			}
+		}
+		u.Commands = append(u.Commands, r)
+	default:
+		return fmt.Errorf("Syntax error")
+	}
+
+	return nil
+}
+
+func (u *User) addPassword(hash string) {
+	u.NoPass = false
+	if !slices.Contains(u.Password, hash) {
+		u.Password = append(u.Password, hash)
+	}
+}
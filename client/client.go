@@ -0,0 +1,166 @@
+// Package client is a Go client for the server, built on the resp package.
+package client
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/helewud/redis-clone/resp"
+)
+
+// Options configure a Client. Zero values fall back to the defaults noted on
+// each field.
+type Options struct {
+	// Addr is the host:port of the server, defaults to localhost:6379
+	Addr string
+
+	// Protocol is the RESP version negotiated with HELLO on connect,
+	// defaults to RESP2 which needs no handshake
+	Protocol resp.Protocol
+
+	// DialTimeout bounds establishing a connection, defaults to 5s
+	DialTimeout time.Duration
+
+	// PoolSize is the maximum number of open connections, defaults to 10
+	PoolSize int
+
+	// HealthCheckInterval is how long a connection may sit idle before it
+	// is pinged on checkout, defaults to 1m. Negative disables the check.
+	HealthCheckInterval time.Duration
+
+	// MaxConnAge retires connections older than this, zero keeps them
+	MaxConnAge time.Duration
+
+	// Dialer opens the network connection, defaults to a net.Dialer
+	Dialer func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// HashCodec maps structs to hashes in HSetStruct, HGetAllStruct and
+	// HSetChanged
+	HashCodec HashCodec
+}
+
+func (o *Options) setDefaults() {
+	if o.Addr == "" {
+		o.Addr = "localhost:6379"
+	}
+	if o.Protocol == 0 {
+		o.Protocol = resp.Resp2
+	}
+	if o.DialTimeout == 0 {
+		o.DialTimeout = 5 * time.Second
+	}
+	if o.PoolSize <= 0 {
+		o.PoolSize = 10
+	}
+	if o.HealthCheckInterval == 0 {
+		o.HealthCheckInterval = time.Minute
+	}
+	if o.HealthCheckInterval < 0 {
+		o.HealthCheckInterval = 0
+	}
+	if o.Dialer == nil {
+		o.Dialer = (&net.Dialer{}).DialContext
+	}
+}
+
+// Client is a pool of connections to one server. It is safe for concurrent
+// use.
+type Client struct {
+	opts Options
+	pool *pool
+}
+
+// New returns a client for the server described by opts. Connections are
+// opened lazily on first use.
+func New(opts Options) *Client {
+	opts.setDefaults()
+
+	c := &Client{opts: opts}
+	c.pool = newPool(opts.PoolSize, opts.HealthCheckInterval, opts.MaxConnAge, c.dial)
+
+	return c
+}
+
+// Dial returns a client for addr after checking the server answers PING
+func Dial(ctx context.Context, addr string) (*Client, error) {
+	c := New(Options{Addr: addr})
+
+	if _, err := c.Do(ctx, "PING"); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, c.opts.DialTimeout)
+	defer cancel()
+
+	netConn, err := c.opts.Dialer(dialCtx, "tcp", c.opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	cn := newConn(netConn)
+
+	if c.opts.Protocol != resp.Resp2 {
+		if err := cn.writeCommand([]any{"HELLO", int(c.opts.Protocol)}); err != nil {
+			cn.Close()
+			return nil, err
+		}
+
+		replies, err := cn.roundTrip(ctx, 1)
+		if err == nil {
+			err = replyError(replies[0])
+		}
+		if err != nil {
+			cn.Close()
+			return nil, err
+		}
+
+		cn.writer.SetProtocol(c.opts.Protocol)
+	}
+
+	return cn, nil
+}
+
+// Do sends a command and waits for its reply. Arguments are sent as bulk
+// strings, numbers are formatted in base 10. An error reply is returned as
+// an Error.
+func (c *Client) Do(ctx context.Context, args ...any) (resp.Value, error) {
+	cn, err := c.pool.get(ctx)
+	if err != nil {
+		return resp.Value{}, err
+	}
+	defer c.pool.put(cn)
+
+	if err := cn.writeCommand(args); err != nil {
+		cn.broken = true
+		return resp.Value{}, err
+	}
+
+	replies, err := cn.roundTrip(ctx, 1)
+	if err != nil {
+		return resp.Value{}, err
+	}
+
+	return replies[0], replyError(replies[0])
+}
+
+// Pipeline returns a pipeline that sends its queued commands in one go
+func (c *Client) Pipeline() *Pipeline {
+	return &Pipeline{client: c}
+}
+
+// PoolStats reports the connections held by the client
+func (c *Client) PoolStats() PoolStats {
+	return c.pool.stats()
+}
+
+// Close closes the idle connections and makes further calls fail with
+// ErrClosed. Connections in use are closed as they are released.
+func (c *Client) Close() error {
+	return c.pool.close()
+}
@@ -0,0 +1,262 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/helewud/redis-clone/resp"
+	"github.com/helewud/redis-clone/server"
+	"github.com/helewud/redis-clone/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startServer runs a server in-process on a random local port
+func startServer(t *testing.T) string {
+	t.Helper()
+
+	store, err := storage.NewAof(filepath.Join(t.TempDir(), "storage.store"))
+	require.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go server.New(store, nil).Serve(ln)
+
+	t.Cleanup(func() {
+		ln.Close()
+		store.Close()
+	})
+
+	return ln.Addr().String()
+}
+
+func TestDial(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("server running", func(t *testing.T) {
+		c, err := Dial(ctx, startServer(t))
+		require.NoError(t, err)
+		require.NoError(t, c.Close())
+	})
+
+	t.Run("nothing listening", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := ln.Addr().String()
+		ln.Close()
+
+		_, err = Dial(ctx, addr)
+		assert.Error(t, err)
+	})
+}
+
+func TestClientDo(t *testing.T) {
+	ctx := context.Background()
+	c := New(Options{Addr: startServer(t)})
+	defer c.Close()
+
+	pong, err := String(c.Do(ctx, "PING"))
+	require.NoError(t, err)
+	assert.Equal(t, "PONG", pong)
+
+	ok, err := String(c.Do(ctx, "SET", "client:key", []byte("hello\r\nworld")))
+	require.NoError(t, err)
+	assert.Equal(t, "OK", ok)
+
+	value, err := Bytes(c.Do(ctx, "GET", "client:key"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello\r\nworld"), value)
+
+	_, err = String(c.Do(ctx, "GET", "client:missing"))
+	assert.ErrorIs(t, err, ErrNil)
+
+	_, err = c.Do(ctx, "SET", "client:key")
+	assert.Equal(t, Error("ERR wrong number of arguments for 'set' command"), err)
+
+	n, err := Int(c.Do(ctx, "SET", "client:number", 42))
+	require.Error(t, err)
+	assert.Zero(t, n)
+
+	n, err = Int(c.Do(ctx, "GET", "client:number"))
+	require.NoError(t, err)
+	assert.Equal(t, 42, n)
+}
+
+func TestClientHashes(t *testing.T) {
+	ctx := context.Background()
+	addr := startServer(t)
+
+	for _, proto := range []resp.Protocol{resp.Resp2, resp.Resp3} {
+		t.Run(fmt.Sprintf("resp%d", proto), func(t *testing.T) {
+			c := New(Options{Addr: addr, Protocol: proto})
+			defer c.Close()
+
+			key := fmt.Sprintf("client:hash:%d", proto)
+			_, err := c.Do(ctx, "HSET", key, "name", "alice")
+			require.NoError(t, err)
+			_, err = c.Do(ctx, "HSET", key, "role", "admin")
+			require.NoError(t, err)
+
+			reply, err := c.Do(ctx, "HGETALL", key)
+			require.NoError(t, err)
+			if proto == resp.Resp3 {
+				assert.Equal(t, resp.RespTMap, reply.T)
+			} else {
+				assert.Equal(t, resp.RespTArray, reply.T)
+			}
+
+			fields, err := StringMap(reply, nil)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]string{"name": "alice", "role": "admin"}, fields)
+		})
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	ctx := context.Background()
+	c := New(Options{Addr: startServer(t)})
+	defer c.Close()
+
+	p := c.Pipeline()
+	for i := 0; i < 100; i++ {
+		p.Do("SET", fmt.Sprintf("pipeline:%d", i), i)
+	}
+	p.Do("SET", "pipeline:bad")
+	p.Do("GET", "pipeline:99")
+	assert.Equal(t, 102, p.Len())
+
+	replies, err := p.Exec(ctx)
+	require.NoError(t, err)
+	require.Len(t, replies, 102)
+	assert.Equal(t, 0, p.Len())
+
+	for _, reply := range replies[:100] {
+		assert.Equal(t, "OK", reply.String)
+	}
+	assert.Equal(t, Error("ERR wrong number of arguments for 'set' command"), Err(replies))
+
+	n, err := Int(replies[101], nil)
+	require.NoError(t, err)
+	assert.Equal(t, 99, n)
+}
+
+func TestPool(t *testing.T) {
+	ctx := context.Background()
+	addr := startServer(t)
+
+	t.Run("bounded", func(t *testing.T) {
+		c := New(Options{Addr: addr, PoolSize: 2})
+		defer c.Close()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, err := c.Do(ctx, "SET", fmt.Sprintf("pool:%d", i), i)
+				assert.NoError(t, err)
+			}(i)
+		}
+		wg.Wait()
+
+		stats := c.PoolStats()
+		assert.LessOrEqual(t, stats.TotalConns, 2)
+		assert.Equal(t, stats.TotalConns, stats.IdleConns)
+	})
+
+	t.Run("waits for a free connection", func(t *testing.T) {
+		c := New(Options{Addr: addr, PoolSize: 1})
+		defer c.Close()
+
+		cn, err := c.pool.get(ctx)
+		require.NoError(t, err)
+
+		waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err = c.Do(waitCtx, "PING")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		c.pool.put(cn)
+		_, err = c.Do(ctx, "PING")
+		assert.NoError(t, err)
+	})
+
+	t.Run("health check replaces dead connections", func(t *testing.T) {
+		c := New(Options{Addr: addr, PoolSize: 1, HealthCheckInterval: time.Nanosecond})
+		defer c.Close()
+
+		_, err := c.Do(ctx, "PING")
+		require.NoError(t, err)
+
+		// break the idle connection behind the pool's back
+		c.pool.idle[0].netConn.Close()
+
+		_, err = c.Do(ctx, "PING")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, c.PoolStats().TotalConns)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		c := New(Options{Addr: addr})
+		require.NoError(t, c.Close())
+
+		_, err := c.Do(ctx, "PING")
+		assert.ErrorIs(t, err, ErrClosed)
+	})
+}
+
+func TestContextCancel(t *testing.T) {
+	// a server that accepts connections and never answers
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	c := New(Options{Addr: ln.Addr().String()})
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err = c.Do(ctx, "PING")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, c.PoolStats().TotalConns)
+}
+
+func TestCanceledDo(t *testing.T) {
+	c := New(Options{Addr: startServer(t), PoolSize: 1})
+	defer c.Close()
+
+	ctx := context.Background()
+	_, err := c.Do(ctx, "PING")
+	require.NoError(t, err)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	// the pool may hand out the idle connection before noticing the
+	// context is done, so try a few times
+	for i := 0; i < 20; i++ {
+		_, err := c.Do(canceled, "PING", "stale")
+		assert.ErrorIs(t, err, context.Canceled)
+
+		pong, err := String(c.Do(ctx, "PING", "fresh"))
+		require.NoError(t, err)
+		require.Equal(t, "fresh", pong)
+	}
+}
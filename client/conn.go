@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/helewud/redis-clone/resp"
+)
+
+// conn is a single connection to the server. It is not safe for concurrent
+// use, the pool hands it to one caller at a time.
+type conn struct {
+	netConn net.Conn
+	reader  *resp.Reader
+	writer  *resp.Writer
+
+	createdAt time.Time
+	usedAt    time.Time
+
+	// broken is set after an I/O or protocol error, the connection is then
+	// in an unknown state and must not be reused
+	broken bool
+}
+
+func newConn(netConn net.Conn) *conn {
+	now := time.Now()
+	return &conn{
+		netConn:   netConn,
+		reader:    resp.NewReader(netConn),
+		writer:    resp.NewWriter(netConn),
+		createdAt: now,
+		usedAt:    now,
+	}
+}
+
+func (cn *conn) Close() error {
+	cn.reader.Release()
+	return cn.netConn.Close()
+}
+
+// writeArg writes a single command argument as a bulk string
+func (cn *conn) writeArg(arg any) error {
+	if b, ok := arg.([]byte); ok {
+		return cn.writer.WriteBulk(b)
+	}
+
+	return cn.writer.WriteBulkString(argString(arg))
+}
+
+// argString formats a command argument the way it is sent to the server
+func argString(arg any) string {
+	switch a := arg.(type) {
+	case string:
+		return a
+	case []byte:
+		return string(a)
+	case int:
+		return strconv.Itoa(a)
+	case int64:
+		return strconv.FormatInt(a, 10)
+	case uint64:
+		return strconv.FormatUint(a, 10)
+	case float64:
+		return strconv.FormatFloat(a, 'f', -1, 64)
+	case bool:
+		if a {
+			return "1"
+		}
+		return "0"
+	case nil:
+		return ""
+	case fmt.Stringer:
+		return a.String()
+	default:
+		return fmt.Sprint(a)
+	}
+}
+
+// writeCommand buffers a command, it is sent on the next flush
+func (cn *conn) writeCommand(args []any) error {
+	if err := cn.writer.WriteArrayHeader(len(args)); err != nil {
+		return err
+	}
+
+	for _, arg := range args {
+		if err := cn.writeArg(arg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readReply reads the next reply and detaches it from the read buffers so
+// it outlives the connection going back to the pool
+func (cn *conn) readReply() (resp.Value, error) {
+	v, err := cn.reader.Read()
+	if err != nil {
+		return resp.Value{}, err
+	}
+
+	return v.Clone(), nil
+}
+
+// roundTrip sends the buffered commands and reads n replies. The context
+// bounds the whole exchange, cancelling it interrupts blocked I/O.
+func (cn *conn) roundTrip(ctx context.Context, n int) ([]resp.Value, error) {
+	// the commands are already buffered, sending them with the next ones
+	// would shift every later reply
+	if err := ctx.Err(); err != nil {
+		cn.broken = true
+		return nil, err
+	}
+
+	deadline, _ := ctx.Deadline()
+	if err := cn.netConn.SetDeadline(deadline); err != nil {
+		cn.broken = true
+		return nil, err
+	}
+
+	// unblock reads and writes as soon as the context is done
+	stop := context.AfterFunc(ctx, func() {
+		cn.netConn.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	replies, err := cn.exchange(n)
+	if err != nil {
+		cn.broken = true
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+
+	cn.usedAt = time.Now()
+	return replies, nil
+}
+
+func (cn *conn) exchange(n int) ([]resp.Value, error) {
+	if err := cn.writer.Flush(); err != nil {
+		return nil, err
+	}
+
+	replies := make([]resp.Value, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := cn.readReply()
+		if err != nil {
+			return nil, err
+		}
+		replies = append(replies, v)
+	}
+
+	return replies, nil
+}
@@ -0,0 +1,457 @@
+package client
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/helewud/redis-clone/resp"
+)
+
+// Codec encodes the values of struct fields that have no natural string
+// form, such as nested structs, slices and maps
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec is the default Codec
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// Field is a single hash field
+type Field struct {
+	Name  string
+	Value []byte
+}
+
+// HashCodec maps Go structs to hash fields.
+//
+// Exported fields become hash fields named after their `redis` struct tag,
+// or after the Go field name when untagged. A tag of "-" skips the field
+// and the "omitempty" option leaves out zero values. Strings, byte slices,
+// booleans, numbers, time.Time and encoding.TextMarshaler values are stored
+// as text, anything else goes through Nested. Embedded structs without a
+// tag are flattened into the parent.
+type HashCodec struct {
+	// Nested encodes fields that are not stored as text, defaults to
+	// JSONCodec
+	Nested Codec
+}
+
+func (hc HashCodec) nested() Codec {
+	if hc.Nested == nil {
+		return JSONCodec{}
+	}
+	return hc.Nested
+}
+
+// Marshal returns the hash fields of the struct v points to, in field order
+func (hc HashCodec) Marshal(v any) ([]Field, error) {
+	rv, err := structValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []Field
+	for _, info := range cachedFields(rv.Type()) {
+		fv, ok := fieldByIndex(rv, info.index)
+		if !ok || (info.omitEmpty && fv.IsZero()) {
+			continue
+		}
+
+		data, err := hc.encode(fv)
+		if err != nil {
+			return nil, fmt.Errorf("client: field %s: %w", info.name, err)
+		}
+		fields = append(fields, Field{Name: info.name, Value: data})
+	}
+
+	return fields, nil
+}
+
+// Unmarshal sets the fields of the struct dst points to from a hash. Hash
+// fields without a matching struct field are ignored.
+func (hc HashCodec) Unmarshal(hash map[string]string, dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("client: Unmarshal needs a non-nil struct pointer, got %T", dst)
+	}
+	rv = rv.Elem()
+
+	for _, info := range cachedFields(rv.Type()) {
+		data, ok := hash[info.name]
+		if !ok {
+			continue
+		}
+		field, ok := allocField(rv, info.index)
+		if !ok {
+			continue
+		}
+
+		if err := hc.decode(field, data); err != nil {
+			return fmt.Errorf("client: field %s: %w", info.name, err)
+		}
+	}
+
+	return nil
+}
+
+// Diff returns the fields whose encoding differs between two values of the
+// same struct type. Fields left out by omitempty in new are still compared,
+// so clearing a field is picked up.
+func (hc HashCodec) Diff(old, new any) ([]Field, error) {
+	ov, err := structValue(old)
+	if err != nil {
+		return nil, err
+	}
+	nv, err := structValue(new)
+	if err != nil {
+		return nil, err
+	}
+	if ov.Type() != nv.Type() {
+		return nil, fmt.Errorf("client: Diff of %s and %s", ov.Type(), nv.Type())
+	}
+
+	var fields []Field
+	for _, info := range cachedFields(nv.Type()) {
+		newData, err := hc.encodeAt(nv, info)
+		if err != nil {
+			return nil, err
+		}
+		oldData, err := hc.encodeAt(ov, info)
+		if err != nil {
+			return nil, err
+		}
+
+		if newData != nil && string(newData) != string(oldData) {
+			fields = append(fields, Field{Name: info.name, Value: newData})
+		}
+	}
+
+	return fields, nil
+}
+
+// encodeAt encodes a field, returning nil when a nil embedded pointer
+// hides it
+func (hc HashCodec) encodeAt(rv reflect.Value, info fieldInfo) ([]byte, error) {
+	fv, ok := fieldByIndex(rv, info.index)
+	if !ok {
+		return nil, nil
+	}
+
+	data, err := hc.encode(fv)
+	if err != nil {
+		return nil, fmt.Errorf("client: field %s: %w", info.name, err)
+	}
+	if data == nil {
+		data = []byte{}
+	}
+
+	return data, nil
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+func (hc HashCodec) encode(fv reflect.Value) ([]byte, error) {
+	if fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			return []byte{}, nil
+		}
+		fv = fv.Elem()
+	}
+
+	if fv.Type() == timeType {
+		return fv.Interface().(time.Time).AppendFormat(nil, time.RFC3339Nano), nil
+	}
+	if fv.Type().Implements(textMarshalerType) {
+		return fv.Interface().(encoding.TextMarshaler).MarshalText()
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		return []byte(fv.String()), nil
+	case reflect.Bool:
+		return strconv.AppendBool(nil, fv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(nil, fv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.AppendUint(nil, fv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.AppendFloat(nil, fv.Float(), 'g', -1, fv.Type().Bits()), nil
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			return append([]byte{}, fv.Bytes()...), nil
+		}
+	}
+
+	return hc.nested().Marshal(fv.Interface())
+}
+
+func (hc HashCodec) decode(fv reflect.Value, data string) error {
+	if fv.Kind() == reflect.Pointer {
+		// nil pointers are stored as empty strings
+		if data == "" {
+			fv.Set(reflect.Zero(fv.Type()))
+			return nil
+		}
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		fv = fv.Elem()
+	}
+
+	if fv.Type() == timeType {
+		if data == "" {
+			fv.Set(reflect.Zero(timeType))
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, data)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if reflect.PointerTo(fv.Type()).Implements(textUnmarshalType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(data))
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(data)
+		return nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(data)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(data, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(data, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(data, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+		return nil
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			if data == "" {
+				fv.SetBytes(nil)
+			} else {
+				fv.SetBytes([]byte(data))
+			}
+			return nil
+		}
+	}
+
+	if data == "" {
+		fv.Set(reflect.Zero(fv.Type()))
+		return nil
+	}
+
+	return hc.nested().Unmarshal([]byte(data), fv.Addr().Interface())
+}
+
+type fieldInfo struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]fieldInfo
+
+func cachedFields(t reflect.Type) []fieldInfo {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.([]fieldInfo)
+	}
+
+	fields, _ := fieldCache.LoadOrStore(t, structFields(t, nil))
+	return fields.([]fieldInfo)
+}
+
+func structFields(t reflect.Type, parent []int) []fieldInfo {
+	var fields []fieldInfo
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, hasTag := sf.Tag.Lookup("redis")
+		if tag == "-" {
+			continue
+		}
+
+		index := append(append([]int{}, parent...), i)
+
+		// flatten untagged embedded structs
+		if sf.Anonymous && !hasTag {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, structFields(ft, index)...)
+				continue
+			}
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+
+		fields = append(fields, fieldInfo{
+			name:      name,
+			index:     index,
+			omitEmpty: opts == "omitempty",
+		})
+	}
+
+	return fields
+}
+
+func structValue(v any) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("client: expected a struct, got %T", v)
+	}
+
+	return rv, nil
+}
+
+// fieldByIndex walks to a field, reporting false when a nil embedded
+// pointer is in the way
+func fieldByIndex(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				return reflect.Value{}, false
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+
+	return rv, true
+}
+
+// allocField walks to a field, allocating nil embedded pointers on the way.
+// Like encoding/json it reports false when one of them is unexported, as it
+// can't be set.
+func allocField(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				if !rv.CanSet() {
+					return reflect.Value{}, false
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+
+	return rv, true
+}
+
+// ScanHash decodes a HGETALL reply into the struct dst points to using the
+// default HashCodec
+func ScanHash(v resp.Value, dst any) error {
+	return scanHash(HashCodec{}, v, replyError(v), dst)
+}
+
+func scanHash(hc HashCodec, v resp.Value, err error, dst any) error {
+	hash, err := StringMap(v, err)
+	if err != nil {
+		return err
+	}
+
+	return hc.Unmarshal(hash, dst)
+}
+
+// HSetStruct stores the fields of a struct in the hash at key
+func (c *Client) HSetStruct(ctx context.Context, key string, v any) error {
+	fields, err := c.opts.HashCodec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return errors.New("client: struct has no fields to store")
+	}
+
+	_, err = c.Do(ctx, hsetArgs(key, fields)...)
+	return err
+}
+
+// HGetAllStruct loads the hash at key into the struct dst points to. It
+// returns ErrNil when the hash does not exist.
+func (c *Client) HGetAllStruct(ctx context.Context, key string, dst any) error {
+	v, err := c.Do(ctx, "HGETALL", key)
+	return scanHash(c.opts.HashCodec, v, err, dst)
+}
+
+// HSetChanged stores only the fields that differ between old and new,
+// skipping the round trip when nothing changed. It returns the number of
+// fields written.
+func (c *Client) HSetChanged(ctx context.Context, key string, old, new any) (int, error) {
+	fields, err := c.opts.HashCodec.Diff(old, new)
+	if err != nil || len(fields) == 0 {
+		return 0, err
+	}
+
+	if _, err := c.Do(ctx, hsetArgs(key, fields)...); err != nil {
+		return 0, err
+	}
+
+	return len(fields), nil
+}
+
+func hsetArgs(key string, fields []Field) []any {
+	args := make([]any, 0, 2+2*len(fields))
+	args = append(args, "HSET", key)
+	for _, f := range fields {
+		args = append(args, f.Name, f.Value)
+	}
+
+	return args
+}
@@ -0,0 +1,197 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type address struct {
+	City    string `json:"city"`
+	Country string `json:"country"`
+}
+
+type audit struct {
+	CreatedBy string `redis:"created_by"`
+}
+
+type user struct {
+	audit
+
+	Name     string            `redis:"name"`
+	Age      int               `redis:"age"`
+	Admin    bool              `redis:"admin"`
+	Score    float64           `redis:"score,omitempty"`
+	Avatar   []byte            `redis:"avatar"`
+	Joined   time.Time         `redis:"joined"`
+	Nickname *string           `redis:"nickname"`
+	Address  address           `redis:"address"`
+	Tags     []string          `redis:"tags,omitempty"`
+	Meta     map[string]string `redis:"meta,omitempty"`
+	Password string            `redis:"-"`
+	internal string
+}
+
+func TestHashCodec(t *testing.T) {
+	nick := "ally"
+	u := user{
+		audit:    audit{CreatedBy: "admin"},
+		Name:     "alice",
+		Age:      30,
+		Admin:    true,
+		Avatar:   []byte{0x00, 0xff},
+		Joined:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Nickname: &nick,
+		Address:  address{City: "Lagos", Country: "NG"},
+		Tags:     []string{"a", "b"},
+		Password: "secret",
+		internal: "hidden",
+	}
+
+	fields, err := HashCodec{}.Marshal(&u)
+	require.NoError(t, err)
+
+	got := map[string]string{}
+	var names []string
+	for _, f := range fields {
+		got[f.Name] = string(f.Value)
+		names = append(names, f.Name)
+	}
+
+	assert.Equal(t, []string{
+		"created_by", "name", "age", "admin", "avatar", "joined", "nickname", "address", "tags",
+	}, names)
+	assert.Equal(t, "admin", got["created_by"])
+	assert.Equal(t, "30", got["age"])
+	assert.Equal(t, "true", got["admin"])
+	assert.Equal(t, "\x00\xff", got["avatar"])
+	assert.Equal(t, "2024-05-01T12:00:00Z", got["joined"])
+	assert.Equal(t, "ally", got["nickname"])
+	assert.JSONEq(t, `{"city":"Lagos","country":"NG"}`, got["address"])
+	assert.JSONEq(t, `["a","b"]`, got["tags"])
+
+	var decoded user
+	require.NoError(t, HashCodec{}.Unmarshal(got, &decoded))
+
+	u.Password = ""
+	u.internal = ""
+	assert.Equal(t, u, decoded)
+}
+
+func TestHashCodecEmbeddedPointers(t *testing.T) {
+	type Audit struct {
+		CreatedBy string `redis:"created_by"`
+	}
+	type exported struct {
+		*Audit
+		Name string `redis:"name"`
+	}
+	type unexported struct {
+		*audit
+		Name string `redis:"name"`
+	}
+	hash := map[string]string{"created_by": "admin", "name": "alice"}
+
+	var e exported
+	require.NoError(t, HashCodec{}.Unmarshal(hash, &e))
+	require.NotNil(t, e.Audit)
+	assert.Equal(t, "admin", e.CreatedBy)
+
+	// a nil unexported pointer can't be allocated, its fields are skipped
+	var u unexported
+	require.NoError(t, HashCodec{}.Unmarshal(hash, &u))
+	assert.Nil(t, u.audit)
+	assert.Equal(t, "alice", u.Name)
+
+	// one already set is filled in
+	u = unexported{audit: &audit{}}
+	require.NoError(t, HashCodec{}.Unmarshal(hash, &u))
+	assert.Equal(t, "admin", u.CreatedBy)
+}
+
+func TestHashCodecErrors(t *testing.T) {
+	_, err := HashCodec{}.Marshal("not a struct")
+	assert.Error(t, err)
+
+	var u user
+	assert.Error(t, HashCodec{}.Unmarshal(map[string]string{}, u))
+	assert.Error(t, HashCodec{}.Unmarshal(map[string]string{"age": "old"}, &u))
+}
+
+func TestHashCodecDiff(t *testing.T) {
+	old := user{Name: "alice", Age: 30, Score: 1.5, Tags: []string{"a"}}
+	updated := old
+	updated.Age = 31
+	updated.Score = 0
+	updated.Tags = []string{"a"}
+
+	fields, err := HashCodec{}.Diff(&old, &updated)
+	require.NoError(t, err)
+	assert.Equal(t, []Field{
+		{Name: "age", Value: []byte("31")},
+		{Name: "score", Value: []byte("0")},
+	}, fields)
+
+	fields, err = HashCodec{}.Diff(old, old)
+	require.NoError(t, err)
+	assert.Empty(t, fields)
+
+	_, err = HashCodec{}.Diff(old, address{})
+	assert.Error(t, err)
+}
+
+// countingCodec wraps JSON and counts how often it is used
+type countingCodec struct {
+	marshals int
+}
+
+func (c *countingCodec) Marshal(v any) ([]byte, error) {
+	c.marshals++
+	return json.Marshal(v)
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func TestClientHashStructs(t *testing.T) {
+	ctx := context.Background()
+	codec := &countingCodec{}
+	c := New(Options{Addr: startServer(t), HashCodec: HashCodec{Nested: codec}})
+	defer c.Close()
+
+	u := user{Name: "bob", Age: 41, Address: address{City: "Accra"}}
+	require.NoError(t, c.HSetStruct(ctx, "user:2", &u))
+	assert.Equal(t, 1, codec.marshals)
+
+	var loaded user
+	require.NoError(t, c.HGetAllStruct(ctx, "user:2", &loaded))
+	assert.Equal(t, u, loaded)
+
+	updated := loaded
+	updated.Age = 42
+	n, err := c.HSetChanged(ctx, "user:2", &loaded, &updated)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	age, err := Int(c.Do(ctx, "HGET", "user:2", "age"))
+	require.NoError(t, err)
+	assert.Equal(t, 42, age)
+
+	n, err = c.HSetChanged(ctx, "user:2", &updated, &updated)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	reply, err := c.Do(ctx, "HGETALL", "user:2")
+	require.NoError(t, err)
+
+	var fromReply user
+	require.NoError(t, ScanHash(reply, &fromReply))
+	assert.Equal(t, updated, fromReply)
+
+	assert.ErrorIs(t, c.HGetAllStruct(ctx, "user:missing", &loaded), ErrNil)
+}
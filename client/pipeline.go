@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+
+	"github.com/helewud/redis-clone/resp"
+)
+
+// Pipeline queues commands and sends them over a single connection with
+// one write, then reads all the replies. It is not safe for concurrent use.
+type Pipeline struct {
+	client *Client
+	cmds   [][]any
+}
+
+// Do queues a command
+func (p *Pipeline) Do(args ...any) {
+	p.cmds = append(p.cmds, args)
+}
+
+// Len returns the number of queued commands
+func (p *Pipeline) Len() int {
+	return len(p.cmds)
+}
+
+// Exec sends the queued commands and returns their replies in order. Error
+// replies are returned as values, use Err to find the first one. The
+// pipeline is empty afterwards and can be reused.
+func (p *Pipeline) Exec(ctx context.Context) ([]resp.Value, error) {
+	cmds := p.cmds
+	p.cmds = nil
+
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+
+	cn, err := p.client.pool.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.client.pool.put(cn)
+
+	for _, args := range cmds {
+		if err := cn.writeCommand(args); err != nil {
+			cn.broken = true
+			return nil, err
+		}
+	}
+
+	return cn.roundTrip(ctx, len(cmds))
+}
+
+// Err returns the first error reply among replies
+func Err(replies []resp.Value) error {
+	for _, v := range replies {
+		if err := replyError(v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned when using a client after Close
+var ErrClosed = errors.New("client: closed")
+
+// PoolStats describes the connections held by a client
+type PoolStats struct {
+	// TotalConns is the number of open connections, idle or in use
+	TotalConns int
+
+	// IdleConns is the number of connections waiting in the pool
+	IdleConns int
+}
+
+// pool bounds the number of connections to the server. Callers wait for a
+// free slot once all of them are in use.
+type pool struct {
+	dial func(ctx context.Context) (*conn, error)
+
+	// slots holds one token per connection that may be open
+	slots chan struct{}
+
+	// healthCheck is how long a connection may sit idle before it is
+	// pinged on checkout, zero disables the check
+	healthCheck time.Duration
+	maxConnAge  time.Duration
+
+	mu     sync.Mutex
+	idle   []*conn
+	total  int
+	closed bool
+}
+
+func newPool(size int, healthCheck, maxConnAge time.Duration, dial func(ctx context.Context) (*conn, error)) *pool {
+	return &pool{
+		dial:        dial,
+		slots:       make(chan struct{}, size),
+		healthCheck: healthCheck,
+		maxConnAge:  maxConnAge,
+	}
+}
+
+// get checks out a connection, reusing a healthy idle one when possible
+func (p *pool) get(ctx context.Context) (*conn, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	for {
+		cn, err := p.popIdle()
+		if err != nil {
+			<-p.slots
+			return nil, err
+		}
+		if cn == nil {
+			break
+		}
+
+		if p.healthy(ctx, cn) {
+			return cn, nil
+		}
+		p.remove(cn)
+	}
+
+	cn, err := p.dial(ctx)
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.total++
+	p.mu.Unlock()
+
+	return cn, nil
+}
+
+func (p *pool) popIdle() (*conn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, ErrClosed
+	}
+
+	if len(p.idle) == 0 {
+		return nil, nil
+	}
+
+	// reuse the most recently used connection first
+	cn := p.idle[len(p.idle)-1]
+	p.idle = p.idle[:len(p.idle)-1]
+
+	return cn, nil
+}
+
+// healthy reports whether an idle connection can be handed out. Old
+// connections are retired and those idle for a while are pinged first.
+func (p *pool) healthy(ctx context.Context, cn *conn) bool {
+	now := time.Now()
+
+	if p.maxConnAge > 0 && now.Sub(cn.createdAt) > p.maxConnAge {
+		return false
+	}
+
+	if p.healthCheck > 0 && now.Sub(cn.usedAt) > p.healthCheck {
+		if err := cn.writeCommand([]any{"PING"}); err != nil {
+			return false
+		}
+
+		replies, err := cn.roundTrip(ctx, 1)
+		if err != nil {
+			return false
+		}
+
+		return replies[0].String == "PONG"
+	}
+
+	return true
+}
+
+// put returns a connection to the pool, or closes it when it is broken
+func (p *pool) put(cn *conn) {
+	p.mu.Lock()
+	if cn.broken || p.closed {
+		p.mu.Unlock()
+		p.remove(cn)
+	} else {
+		p.idle = append(p.idle, cn)
+		p.mu.Unlock()
+	}
+
+	<-p.slots
+}
+
+// remove closes a connection that is not coming back to the pool
+func (p *pool) remove(cn *conn) {
+	cn.Close()
+
+	p.mu.Lock()
+	p.total--
+	p.mu.Unlock()
+}
+
+func (p *pool) stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return PoolStats{TotalConns: p.total, IdleConns: len(p.idle)}
+}
+
+// close closes the idle connections, the ones in use are closed when they
+// are put back
+func (p *pool) close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	p.closed = true
+
+	idle := p.idle
+	p.idle = nil
+	p.total -= len(idle)
+	p.mu.Unlock()
+
+	var err error
+	for _, cn := range idle {
+		if cerr := cn.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
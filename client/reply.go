@@ -0,0 +1,144 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/helewud/redis-clone/resp"
+)
+
+// ErrNil is returned by the reply helpers for a null reply
+var ErrNil = errors.New("client: nil reply")
+
+// Error is an error reply sent by the server
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+func replyError(v resp.Value) error {
+	if v.T == resp.RespTError {
+		return Error(v.String)
+	}
+
+	return nil
+}
+
+// The helpers below convert a reply to a Go type. They take the result of
+// Do directly, so they compose as in:
+//
+//	name, err := client.String(c.Do(ctx, "GET", "name"))
+
+// String converts a string reply
+func String(v resp.Value, err error) (string, error) {
+	b, err := Bytes(v, err)
+	return string(b), err
+}
+
+// Bytes converts a string reply, returning the payload of bulk strings as
+// is
+func Bytes(v resp.Value, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	switch v.T {
+	case resp.RespTBulk, resp.RespTVerbatim:
+		return v.Bulk, nil
+	case resp.RespTString, resp.RespTBigNumber:
+		return []byte(v.String), nil
+	case resp.RespTNull:
+		return nil, ErrNil
+	default:
+		return nil, fmt.Errorf("client: unexpected %s reply for string", v.T)
+	}
+}
+
+// Int converts an integer reply, or a string reply holding an integer
+func Int(v resp.Value, err error) (int, error) {
+	if err != nil {
+		return 0, err
+	}
+
+	switch v.T {
+	case resp.RespTInteger:
+		return v.Number, nil
+	case resp.RespTBoolean:
+		if v.Boolean {
+			return 1, nil
+		}
+		return 0, nil
+	case resp.RespTBulk, resp.RespTString:
+		s, _ := String(v, nil)
+		return strconv.Atoi(s)
+	case resp.RespTNull:
+		return 0, ErrNil
+	default:
+		return 0, fmt.Errorf("client: unexpected %s reply for integer", v.T)
+	}
+}
+
+// Strings converts an array or set reply of strings
+func Strings(v resp.Value, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	switch v.T {
+	case resp.RespTArray, resp.RespTSet, resp.RespTPush, resp.RespTMap:
+	case resp.RespTNull:
+		return nil, ErrNil
+	default:
+		return nil, fmt.Errorf("client: unexpected %s reply for array", v.T)
+	}
+
+	values := make([]string, len(v.Array))
+	for i, item := range v.Array {
+		s, err := String(item, nil)
+		if err != nil && err != ErrNil {
+			return nil, err
+		}
+		values[i] = s
+	}
+
+	return values, nil
+}
+
+// StringMap converts a map reply, or the flat field/value array RESP2
+// clients receive in its place
+func StringMap(v resp.Value, err error) (map[string]string, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	switch v.T {
+	case resp.RespTMap, resp.RespTArray:
+	case resp.RespTNull:
+		return nil, ErrNil
+	default:
+		return nil, fmt.Errorf("client: unexpected %s reply for map", v.T)
+	}
+
+	if len(v.Array)%2 != 0 {
+		return nil, fmt.Errorf("client: odd number of elements in map reply")
+	}
+
+	values := make(map[string]string, len(v.Array)/2)
+	for i := 0; i < len(v.Array); i += 2 {
+		key, err := String(v.Array[i], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := String(v.Array[i+1], nil)
+		if err != nil && err != ErrNil {
+			return nil, err
+		}
+
+		values[key] = value
+	}
+
+	return values, nil
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/helewud/redis-clone/resp"
+)
+
+func TestReplyHelpers(t *testing.T) {
+	bulk := func(s string) resp.Value { return resp.Value{T: resp.RespTBulk, Bulk: []byte(s)} }
+
+	t.Run("string", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			v       resp.Value
+			want    string
+			wantErr error
+		}{
+			{name: "bulk", v: bulk("hello"), want: "hello"},
+			{name: "simple", v: resp.Value{T: resp.RespTString, String: "OK"}, want: "OK"},
+			{name: "verbatim", v: resp.Value{T: resp.RespTVerbatim, Format: "txt", Bulk: []byte("hi")}, want: "hi"},
+			{name: "null", v: resp.Value{T: resp.RespTNull}, wantErr: ErrNil},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := String(tt.v, nil)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("String() error = %v, want %v", err, tt.wantErr)
+				}
+				if got != tt.want {
+					t.Errorf("String() = %q, want %q", got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("int", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			v       resp.Value
+			want    int
+			wantErr bool
+		}{
+			{name: "integer", v: resp.Value{T: resp.RespTInteger, Number: -3}, want: -3},
+			{name: "bulk", v: bulk("12"), want: 12},
+			{name: "boolean", v: resp.Value{T: resp.RespTBoolean, Boolean: true}, want: 1},
+			{name: "not a number", v: bulk("abc"), wantErr: true},
+			{name: "array", v: resp.Value{T: resp.RespTArray}, wantErr: true},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := Int(tt.v, nil)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("Int() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if got != tt.want {
+					t.Errorf("Int() = %d, want %d", got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("strings", func(t *testing.T) {
+		v := resp.Value{T: resp.RespTSet, Array: []resp.Value{bulk("a"), {T: resp.RespTNull}, bulk("b")}}
+
+		got, err := Strings(v, nil)
+		if err != nil {
+			t.Fatalf("Strings() error = %v", err)
+		}
+		if want := []string{"a", "", "b"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Strings() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("string map", func(t *testing.T) {
+		v := resp.Value{T: resp.RespTMap, Array: []resp.Value{bulk("k1"), bulk("v1"), bulk("k2"), bulk("v2")}}
+
+		got, err := StringMap(v, nil)
+		if err != nil {
+			t.Fatalf("StringMap() error = %v", err)
+		}
+		if want := map[string]string{"k1": "v1", "k2": "v2"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("StringMap() = %v, want %v", got, want)
+		}
+
+		if _, err := StringMap(resp.Value{T: resp.RespTArray, Array: []resp.Value{bulk("k1")}}, nil); err == nil {
+			t.Error("StringMap() expected an error for an odd number of elements")
+		}
+	})
+
+	t.Run("passes errors through", func(t *testing.T) {
+		want := Error("ERR failed")
+		if _, err := String(resp.Value{}, want); err != want {
+			t.Errorf("String() error = %v, want %v", err, want)
+		}
+	})
+}
@@ -0,0 +1,360 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/helewud/redis-clone/resp"
+)
+
+// ErrNoNodes is returned by a Ring without any node
+var ErrNoNodes = errors.New("client: ring has no nodes")
+
+// RingOptions configure a Ring
+type RingOptions struct {
+	// Addrs are the nodes the ring starts with
+	Addrs []string
+
+	// VirtualNodes is the number of points each node gets on the ring,
+	// defaults to 160. More points spread keys more evenly.
+	VirtualNodes int
+
+	// Options are used for the client of every node, Addr is set per node
+	Options Options
+}
+
+// Ring shards keys over independent servers with consistent hashing. Each
+// node owns many points on a hash ring and a key belongs to the node of the
+// first point at or after its hash, so adding or removing a node only moves
+// the keys of that node.
+//
+// When a key contains a hash tag, a non-empty part between the first { and
+// the next }, only the tag is hashed. Keys sharing a tag live on the same
+// node.
+type Ring struct {
+	opts RingOptions
+
+	mu     sync.RWMutex
+	nodes  map[string]*Client
+	points []ringPoint
+}
+
+type ringPoint struct {
+	hash uint64
+	addr string
+}
+
+func NewRing(opts RingOptions) *Ring {
+	if opts.VirtualNodes <= 0 {
+		opts.VirtualNodes = 160
+	}
+
+	r := &Ring{
+		opts:  opts,
+		nodes: map[string]*Client{},
+	}
+	for _, addr := range opts.Addrs {
+		r.AddNode(addr)
+	}
+
+	return r
+}
+
+func hashKey(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	sum := h.Sum64()
+
+	// fnv barely mixes the last bytes, finish with a murmur3 style mixer
+	// so similar keys spread over the whole ring
+	sum ^= sum >> 33
+	sum *= 0xff51afd7ed558ccd
+	sum ^= sum >> 33
+	sum *= 0xc4ceb9fe1a85ec53
+	sum ^= sum >> 33
+
+	return sum
+}
+
+// HashTag returns the part of key that is hashed to pick a node
+func HashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return key
+	}
+
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return key
+	}
+
+	return key[start+1 : start+1+end]
+}
+
+// AddNode adds a server to the ring, doing nothing if it is already there
+func (r *Ring) AddNode(addr string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.nodes[addr]; ok {
+		return
+	}
+
+	opts := r.opts.Options
+	opts.Addr = addr
+	r.nodes[addr] = New(opts)
+
+	for i := 0; i < r.opts.VirtualNodes; i++ {
+		r.points = append(r.points, ringPoint{
+			hash: hashKey(addr + "#" + strconv.Itoa(i)),
+			addr: addr,
+		})
+	}
+	slices.SortFunc(r.points, func(a, b ringPoint) int {
+		if a.hash != b.hash {
+			if a.hash < b.hash {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.addr, b.addr)
+	})
+}
+
+// RemoveNode takes a server off the ring and closes its client
+func (r *Ring) RemoveNode(addr string) error {
+	r.mu.Lock()
+	client, ok := r.nodes[addr]
+	if ok {
+		delete(r.nodes, addr)
+		r.points = slices.DeleteFunc(r.points, func(p ringPoint) bool {
+			return p.addr == addr
+		})
+	}
+	r.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	return client.Close()
+}
+
+// Nodes returns the addresses of the servers on the ring
+func (r *Ring) Nodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	addrs := make([]string, 0, len(r.nodes))
+	for addr := range r.nodes {
+		addrs = append(addrs, addr)
+	}
+	slices.Sort(addrs)
+
+	return addrs
+}
+
+// NodeFor returns the address of the server owning key
+func (r *Ring) NodeFor(key string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.nodeFor(key)
+}
+
+func (r *Ring) nodeFor(key string) (string, error) {
+	if len(r.points) == 0 {
+		return "", ErrNoNodes
+	}
+
+	hash := hashKey(HashTag(key))
+	i, _ := slices.BinarySearchFunc(r.points, hash, func(p ringPoint, h uint64) int {
+		switch {
+		case p.hash < h:
+			return -1
+		case p.hash > h:
+			return 1
+		default:
+			return 0
+		}
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+
+	return r.points[i].addr, nil
+}
+
+// Client returns the client of the server owning key
+func (r *Ring) Client(key string) (*Client, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	addr, err := r.nodeFor(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.nodes[addr], nil
+}
+
+// Do sends a single key command to the server owning its key, which is the
+// first argument after the command name
+func (r *Ring) Do(ctx context.Context, args ...any) (resp.Value, error) {
+	if len(args) < 2 {
+		return resp.Value{}, errors.New("client: ring commands need a key")
+	}
+
+	client, err := r.Client(argString(args[1]))
+	if err != nil {
+		return resp.Value{}, err
+	}
+
+	return client.Do(ctx, args...)
+}
+
+// FanOut runs one command per key, built by cmd, and returns the replies in
+// the order of keys. Keys are grouped by node, each node gets its commands
+// in a single pipeline and the nodes are queried in parallel.
+func (r *Ring) FanOut(ctx context.Context, keys []string, cmd func(key string) []any) ([]resp.Value, error) {
+	type batch struct {
+		client    *Client
+		positions []int
+	}
+
+	r.mu.RLock()
+	batches := map[string]*batch{}
+	for i, key := range keys {
+		addr, err := r.nodeFor(key)
+		if err != nil {
+			r.mu.RUnlock()
+			return nil, err
+		}
+
+		b, ok := batches[addr]
+		if !ok {
+			b = &batch{client: r.nodes[addr]}
+			batches[addr] = b
+		}
+		b.positions = append(b.positions, i)
+	}
+	r.mu.RUnlock()
+
+	replies := make([]resp.Value, len(keys))
+
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+	for _, b := range batches {
+		wg.Add(1)
+		go func(b *batch) {
+			defer wg.Done()
+
+			p := b.client.Pipeline()
+			for _, i := range b.positions {
+				p.Do(cmd(keys[i])...)
+			}
+
+			values, err := p.Exec(ctx)
+			if err != nil {
+				errMu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				errMu.Unlock()
+				return
+			}
+
+			for j, i := range b.positions {
+				replies[i] = values[j]
+			}
+		}(b)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return replies, nil
+}
+
+// MGet gets several keys spread over the ring. Missing keys are null
+// values in the result.
+func (r *Ring) MGet(ctx context.Context, keys ...string) ([]resp.Value, error) {
+	return r.FanOut(ctx, keys, func(key string) []any {
+		return []any{"GET", key}
+	})
+}
+
+// MSet sets several keys spread over the ring from alternating keys and
+// values
+func (r *Ring) MSet(ctx context.Context, pairs ...string) error {
+	if len(pairs)%2 != 0 {
+		return errors.New("client: MSet needs key/value pairs")
+	}
+
+	keys := make([]string, 0, len(pairs)/2)
+	values := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		keys = append(keys, pairs[i])
+		values[pairs[i]] = pairs[i+1]
+	}
+
+	replies, err := r.FanOut(ctx, keys, func(key string) []any {
+		return []any{"SET", key, values[key]}
+	})
+	if err != nil {
+		return err
+	}
+
+	return Err(replies)
+}
+
+// Del deletes several keys spread over the ring and returns how many
+// existed
+func (r *Ring) Del(ctx context.Context, keys ...string) (int, error) {
+	replies, err := r.FanOut(ctx, keys, func(key string) []any {
+		return []any{"DEL", key}
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := Err(replies); err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, v := range replies {
+		if v.T != resp.RespTNull {
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// Close closes the clients of every node
+func (r *Ring) Close() error {
+	r.mu.Lock()
+	nodes := r.nodes
+	r.nodes = map[string]*Client{}
+	r.points = nil
+	r.mu.Unlock()
+
+	var err error
+	for _, client := range nodes {
+		if cerr := client.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
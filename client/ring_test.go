@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/helewud/redis-clone/resp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashTag(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"user:1", "user:1"},
+		{"{user:1}:name", "user:1"},
+		{"cart:{user:1}", "user:1"},
+		{"{}:name", "{}:name"},
+		{"{user", "{user"},
+		{"a{b}{c}", "b"},
+		{"}{a}", "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			assert.Equal(t, tt.want, HashTag(tt.key))
+		})
+	}
+}
+
+func ringOwners(t *testing.T, r *Ring, keys []string) map[string]string {
+	t.Helper()
+
+	owners := make(map[string]string, len(keys))
+	for _, key := range keys {
+		addr, err := r.NodeFor(key)
+		require.NoError(t, err)
+		owners[key] = addr
+	}
+
+	return owners
+}
+
+func TestRingKeyMovement(t *testing.T) {
+	r := NewRing(RingOptions{Addrs: []string{"node-a:6379", "node-b:6379", "node-c:6379"}})
+	defer r.Close()
+
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+	}
+	before := ringOwners(t, r, keys)
+
+	perNode := map[string]int{}
+	for _, addr := range before {
+		perNode[addr]++
+	}
+	for addr, n := range perNode {
+		assert.InDelta(t, len(keys)/3, n, float64(len(keys))/10, addr)
+	}
+
+	t.Run("add node", func(t *testing.T) {
+		r.AddNode("node-d:6379")
+		defer r.RemoveNode("node-d:6379")
+
+		moved := 0
+		for key, addr := range ringOwners(t, r, keys) {
+			if addr != before[key] {
+				moved++
+				assert.Equal(t, "node-d:6379", addr, "keys may only move to the new node")
+			}
+		}
+		assert.InDelta(t, len(keys)/4, moved, float64(len(keys))/10)
+	})
+
+	t.Run("remove node", func(t *testing.T) {
+		require.NoError(t, r.RemoveNode("node-b:6379"))
+		defer r.AddNode("node-b:6379")
+
+		for key, addr := range ringOwners(t, r, keys) {
+			if before[key] != "node-b:6379" {
+				assert.Equal(t, before[key], addr, "only keys of the removed node may move")
+			}
+		}
+	})
+
+	assert.Equal(t, before, ringOwners(t, r, keys))
+}
+
+func TestRingHashTagsShareNode(t *testing.T) {
+	r := NewRing(RingOptions{Addrs: []string{"node-a:6379", "node-b:6379", "node-c:6379"}})
+	defer r.Close()
+
+	want, err := r.NodeFor("{user:1}:profile")
+	require.NoError(t, err)
+	for _, key := range []string{"{user:1}:cart", "{user:1}:sessions", "orders:{user:1}"} {
+		addr, err := r.NodeFor(key)
+		require.NoError(t, err)
+		assert.Equal(t, want, addr, key)
+	}
+}
+
+func TestRingNoNodes(t *testing.T) {
+	r := NewRing(RingOptions{})
+
+	_, err := r.NodeFor("key")
+	assert.ErrorIs(t, err, ErrNoNodes)
+
+	_, err = r.Do(context.Background(), "GET", "key")
+	assert.ErrorIs(t, err, ErrNoNodes)
+}
+
+func TestRingFanOut(t *testing.T) {
+	ctx := context.Background()
+	r := NewRing(RingOptions{Addrs: []string{startServer(t), startServer(t), startServer(t)}})
+	defer r.Close()
+
+	pairs := []string{}
+	keys := []string{}
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("ring:%d", i)
+		keys = append(keys, key)
+		pairs = append(pairs, key, fmt.Sprintf("value-%d", i))
+	}
+	require.NoError(t, r.MSet(ctx, pairs...))
+
+	values, err := r.MGet(ctx, append(keys, "ring:missing")...)
+	require.NoError(t, err)
+	require.Len(t, values, len(keys)+1)
+	for i := range keys {
+		assert.Equal(t, fmt.Sprintf("value-%d", i), string(values[i].Bulk))
+	}
+	assert.Equal(t, resp.RespTNull, values[len(keys)].T)
+
+	value, err := String(r.Do(ctx, "GET", "ring:7"))
+	require.NoError(t, err)
+	assert.Equal(t, "value-7", value)
+
+	deleted, err := r.Del(ctx, "ring:1", "ring:2", "ring:missing")
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	_, err = String(r.Do(ctx, "GET", "ring:1"))
+	assert.ErrorIs(t, err, ErrNil)
+}
+
+func TestRingDoNumericKey(t *testing.T) {
+	ctx := context.Background()
+	r := NewRing(RingOptions{Addrs: []string{startServer(t), startServer(t), startServer(t)}})
+	defer r.Close()
+
+	// numeric keys go to the node owning the key as it is sent
+	for i := 0; i < 10; i++ {
+		_, err := r.Do(ctx, "SET", i, "value")
+		require.NoError(t, err)
+
+		c, err := r.Client(strconv.Itoa(i))
+		require.NoError(t, err)
+		value, err := String(c.Do(ctx, "GET", i))
+		require.NoError(t, err, i)
+		assert.Equal(t, "value", value)
+	}
+}
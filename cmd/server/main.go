@@ -1,86 +1,217 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
 	"net"
-	"strings"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/helewud/redis-clone/commands"
+	"github.com/helewud/redis-clone/config"
+	"github.com/helewud/redis-clone/logger"
 	"github.com/helewud/redis-clone/resp"
+	"github.com/helewud/redis-clone/server"
 	"github.com/helewud/redis-clone/storage"
 )
 
 func main() {
-	// Create a new server
-	n, err := net.Listen("tcp", ":6379")
+	cfg, err := config.Parse(os.Args[0], os.Args[1:], os.Stderr)
 	if err != nil {
-		fmt.Println(err)
+		// flag already reported bad flags
+		if !errors.Is(err, flag.ErrHelp) && !errors.Is(err, config.ErrUsage) {
+			slog.Error("reading the config", "err", err)
+		}
 		return
 	}
-	defer n.Close()
+	settings := cfg.Config()
 
-	store, err := restoreStoreBackup("storage.store")
+	logFile, err := logger.Setup(settings)
 	if err != nil {
-		fmt.Println(err)
+		slog.Error("opening the log", "err", err)
 		return
 	}
+	if logFile != nil {
+		defer logFile.Close()
+	}
 
-	// listen
-	for {
-		// Listen for connections
-		conn, err := n.Accept()
-		if err != nil {
-			fmt.Println(err)
+	var certs *server.TLS
+	if settings.TLSPort != 0 {
+		if certs, err = server.NewTLS(settings); err != nil {
+			slog.Error("loading the TLS certificates", "err", err)
 			return
 		}
+	}
+
+	// Create the listeners
+	listeners, err := listen(settings, certs)
+	if err != nil {
+		slog.Error("listening", "err", err)
+		return
+	}
+	defer closeAll(listeners)
+
+	store, err := restoreStoreBackup(settings.AOFPath())
+	if err != nil {
+		slog.Error("restoring the AOF", "path", settings.AOFPath(), "err", err)
+		return
+	}
+	defer store.Close()
+	store.SetFsync(settings.AppendFsync)
 
-		go handleConn(conn, store)
+	srv := server.New(store, cfg)
+	if err := srv.LoadACL(); err != nil {
+		slog.Error("loading the ACL", "err", err)
+		return
 	}
+
+	// SIGINT and SIGTERM shut down gracefully, the same way as SHUTDOWN
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		slog.Warn("shutting down", "signal", sig.String())
+		shutdown(srv)
+	}()
+
+	// SIGHUP reopens the log file and reloads the TLS certificates
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	go func() {
+		for range hangups {
+			reload(logFile, certs)
+		}
+	}()
+
+	slog.Info("ready to accept connections", "addrs", addrs(listeners))
+
+	// listen, returns once shut down
+	if err := serve(srv, listeners); err != nil {
+		slog.Error("serving", "err", err)
+		return
+	}
+	slog.Info("server stopped")
 }
 
-func handleConn(conn net.Conn, store *storage.Aof) {
-	defer conn.Close()
+// reload reopens the log file, which logrotate may have moved, and reloads
+// the TLS certificates
+func reload(logFile *logger.File, certs *server.TLS) {
+	if logFile != nil {
+		if err := logFile.Reopen(); err != nil {
+			slog.Error("reopening the log file", "err", err)
+		}
+	}
 
-	for {
-		value, err := validateRespInput(conn)
-		if err != nil {
-			fmt.Printf("resp input validation error: %q \n", err)
-			continue
+	if certs != nil {
+		if err := certs.Reload(); err != nil {
+			slog.Error("reloading the TLS certificates", "err", err)
+			return
 		}
+		slog.Info("reloaded TLS certificates")
+	}
+}
 
-		command := strings.ToUpper(value.Array[0].Bulk)
-		args := value.Array[1:]
+func addrs(listeners []net.Listener) []string {
+	res := make([]string, len(listeners))
+	for i, ln := range listeners {
+		res[i] = ln.Addr().String()
+	}
 
-		handler, err := validateRespCommand(command)
+	return res
+}
+
+func shutdown(srv *server.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), server.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("shutting down", "err", err)
+	}
+}
+
+// listen opens a listener for every configured TCP and TLS address and the
+// Unix socket
+func listen(settings config.Config, certs *server.TLS) (listeners []net.Listener, err error) {
+	defer func() {
 		if err != nil {
-			fmt.Printf("resp command error: %q \n", err)
+			closeAll(listeners)
+		}
+	}()
 
-			temp := resp.Value{T: resp.RespTString, String: ""}
-			conn.Write(temp.Marshal())
+	for _, addr := range settings.Addrs() {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return listeners, err
+		}
+		listeners = append(listeners, ln)
+	}
 
-			continue
+	for _, addr := range settings.TLSAddrs() {
+		ln, err := server.ListenTLS(addr, certs)
+		if err != nil {
+			return listeners, err
 		}
+		listeners = append(listeners, ln)
+	}
+
+	if settings.UnixSocket != "" {
+		ln, err := server.ListenUnix(settings.UnixSocket, settings.UnixSocketPerm)
+		if err != nil {
+			return listeners, err
+		}
+		listeners = append(listeners, ln)
+	}
 
-		result := handler(args)
-		conn.Write(result.Marshal())
+	if len(listeners) == 0 {
+		return nil, errors.New("nothing to listen on, set a port, tls-port or unixsocket")
+	}
+
+	return listeners, nil
+}
+
+func closeAll(listeners []net.Listener) {
+	for _, ln := range listeners {
+		ln.Close()
+	}
+}
+
+// serve accepts connections on every listener until the server is shut
+// down. When one listener fails the server is shut down as a whole.
+func serve(srv *server.Server, listeners []net.Listener) error {
+	errs := make(chan error, len(listeners))
+	for _, ln := range listeners {
+		go func() { errs <- srv.Serve(ln) }()
+	}
 
-		if strings.Contains(command, "SET") {
-			store.Write(*value)
+	var first error
+	for range listeners {
+		err := <-errs
+		if errors.Is(err, server.ErrServerClosed) || first != nil {
+			continue
 		}
+
+		first = err
+		go shutdown(srv)
 	}
 
+	return first
 }
 
-func handleRespValue(value resp.Value) error {
-	command := strings.ToUpper(value.Array[0].Bulk)
-	args := value.Array[1:]
+func handleRespValue(session *commands.Session, value resp.Value) error {
+	if value.T != resp.RespTArray || len(value.Array) == 0 {
+		return errors.New("invalid command: expected a non-empty array")
+	}
 
-	handler, ok := commands.Handlers[command]
-	if !ok {
+	command := string(value.Array[0].Bulk)
+	if _, ok := commands.Commands.Lookup(command); !ok {
 		return fmt.Errorf("invalid command: %v", command)
 	}
 
-	handler(args)
+	commands.Commands.Call(session, value)
 
 	return nil
 }
@@ -91,37 +222,14 @@ func restoreStoreBackup(backupPath string) (*storage.Aof, error) {
 		return nil, err
 	}
 
-	err = store.Read(handleRespValue)
+	// replayed commands run in a session of their own
+	session := commands.NewSession(0, "aof")
+	err = store.Read(func(value resp.Value) error {
+		return handleRespValue(session, value)
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	return store, nil
 }
-
-func validateRespInput(conn net.Conn) (*resp.Value, error) {
-	reader := resp.NewReader(conn)
-	value, err := reader.Read()
-	if err != nil {
-		return nil, err
-	}
-
-	if value.T != resp.RespTArray {
-		return nil, fmt.Errorf("invalid request, expected array")
-	}
-
-	if len(value.Array) == 0 {
-		return nil, fmt.Errorf("invalid request, expected array length > 0")
-	}
-
-	return &value, nil
-}
-
-func validateRespCommand(command string) (commands.RespHandler, error) {
-	handler, ok := commands.Handlers[command]
-	if !ok {
-		return nil, fmt.Errorf("invalid command: %v", command)
-	}
-
-	return handler, nil
-}
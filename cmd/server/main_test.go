@@ -2,11 +2,11 @@ package main
 
 import (
 	"fmt"
-	"net"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/helewud/redis-clone/commands"
 	"github.com/helewud/redis-clone/resp"
 	"github.com/helewud/redis-clone/storage"
 	"github.com/stretchr/testify/assert"
@@ -24,9 +24,9 @@ func TestHandleRespValue(t *testing.T) {
 			value: resp.Value{
 				T: resp.RespTArray,
 				Array: []resp.Value{
-					{T: resp.RespTBulk, Bulk: "SET"},
-					{T: resp.RespTBulk, Bulk: "key"},
-					{T: resp.RespTBulk, Bulk: "value"},
+					{T: resp.RespTBulk, Bulk: []byte("SET")},
+					{T: resp.RespTBulk, Bulk: []byte("key")},
+					{T: resp.RespTBulk, Bulk: []byte("value")},
 				},
 			},
 			expectError: false,
@@ -36,17 +36,27 @@ func TestHandleRespValue(t *testing.T) {
 			value: resp.Value{
 				T: resp.RespTArray,
 				Array: []resp.Value{
-					{T: resp.RespTBulk, Bulk: "INVALID"},
-					{T: resp.RespTBulk, Bulk: "key"},
+					{T: resp.RespTBulk, Bulk: []byte("INVALID")},
+					{T: resp.RespTBulk, Bulk: []byte("key")},
 				},
 			},
 			expectError: true,
 		},
+		{
+			name:        "empty array",
+			value:       resp.Value{T: resp.RespTArray, Array: []resp.Value{}},
+			expectError: true,
+		},
+		{
+			name:        "not an array",
+			value:       resp.Value{T: resp.RespTBulk, Bulk: []byte("SET")},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := handleRespValue(tt.value)
+			err := handleRespValue(commands.NewSession(0, "aof"), tt.value)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -73,17 +83,17 @@ func TestRestoreStoreBackup(t *testing.T) {
 			{
 				T: resp.RespTArray,
 				Array: []resp.Value{
-					{T: resp.RespTBulk, Bulk: "SET"},
-					{T: resp.RespTBulk, Bulk: "key1"},
-					{T: resp.RespTBulk, Bulk: "value1"},
+					{T: resp.RespTBulk, Bulk: []byte("SET")},
+					{T: resp.RespTBulk, Bulk: []byte("key1")},
+					{T: resp.RespTBulk, Bulk: []byte("value1")},
 				},
 			},
 			{
 				T: resp.RespTArray,
 				Array: []resp.Value{
-					{T: resp.RespTBulk, Bulk: "SET"},
-					{T: resp.RespTBulk, Bulk: "key2"},
-					{T: resp.RespTBulk, Bulk: "value2"},
+					{T: resp.RespTBulk, Bulk: []byte("SET")},
+					{T: resp.RespTBulk, Bulk: []byte("key2")},
+					{T: resp.RespTBulk, Bulk: []byte("value2")},
 				},
 			},
 		}
@@ -105,109 +115,20 @@ func TestRestoreStoreBackup(t *testing.T) {
 			key := testValue.Array[1].Bulk
 			expectedValue := testValue.Array[2].Bulk
 
-			getHandler, err := validateRespCommand("GET")
-			require.NoError(t, err)
+			get, ok := commands.Commands.Lookup("GET")
+			require.True(t, ok)
 
-			getResult := getHandler([]resp.Value{{T: resp.RespTBulk, Bulk: key}})
+			getResult := get.Handler(commands.NewSession(1, "127.0.0.1:50000"), []resp.Value{{T: resp.RespTBulk, Bulk: key}})
 			assert.Equal(t, resp.Value{T: resp.RespTBulk, Bulk: expectedValue}, getResult,
 				fmt.Sprintf("Restored value for key %s does not match", key))
 		}
 	})
-}
-
-func TestValidateRespInput(t *testing.T) {
-	tests := []struct {
-		name        string
-		input       []byte
-		expectError bool
-		expectValue *resp.Value
-	}{
-		{
-			name:        "valid SET command",
-			input:       []byte("*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n"),
-			expectError: false,
-			expectValue: &resp.Value{
-				T: resp.RespTArray,
-				Array: []resp.Value{
-					{T: resp.RespTBulk, Bulk: "SET"},
-					{T: resp.RespTBulk, Bulk: "key"},
-					{T: resp.RespTBulk, Bulk: "value"},
-				},
-			},
-		},
-		{
-			name:        "empty array",
-			input:       []byte("*0\r\n"),
-			expectError: true,
-		},
-		{
-			name:        "invalid type",
-			input:       []byte("+Simple String\r\n"),
-			expectError: true,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			clientConn, serverConn := net.Pipe()
-			defer clientConn.Close()
-			defer serverConn.Close()
-
-			// Write input in a separate goroutine
-			go func(data []byte) {
-				_, _ = clientConn.Write(data)
-				_ = clientConn.Close() // signal EOF to the other end
-			}(tt.input)
 
-			// Now read from `serverConn` as if it's the "server side"
-			value, err := validateRespInput(serverConn)
+	t.Run("empty command", func(t *testing.T) {
+		path := filepath.Join(tmpDir, "empty.store")
+		require.NoError(t, os.WriteFile(path, []byte("*0\r\n"), 0644))
 
-			if tt.expectError {
-				assert.Error(t, err, "expected an error but got none")
-				return
-			}
-
-			require.NoError(t, err, "unexpected error when reading input")
-			assert.Equal(t, tt.expectValue, value, "parsed Value mismatch")
-		})
-	}
-}
-
-// Test validateRespCommand
-func TestValidateRespCommand(t *testing.T) {
-	tests := []struct {
-		name        string
-		command     string
-		expectError bool
-	}{
-		{
-			name:        "valid SET command",
-			command:     "SET",
-			expectError: false,
-		},
-		{
-			name:        "valid GET command",
-			command:     "GET",
-			expectError: false,
-		},
-		{
-			name:        "invalid command",
-			command:     "INVALID",
-			expectError: true,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			handler, err := validateRespCommand(tt.command)
-
-			if tt.expectError {
-				assert.Error(t, err)
-				assert.Nil(t, handler)
-			} else {
-				assert.NoError(t, err)
-				assert.NotNil(t, handler)
-			}
-		})
-	}
+		_, err := restoreStoreBackup(path)
+		assert.Error(t, err)
+	})
 }
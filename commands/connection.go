@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/helewud/redis-clone/resp"
+)
+
+// Version is the server version reported to clients
+const Version = "7.2.0"
+
+// hello negotiates the protocol of the session and replies with a map
+// describing the server: HELLO [protover [AUTH username password]
+// [SETNAME clientname]]. Replies are encoded with the new protocol from
+// this one on. Unauthenticated clients must log in with the AUTH option.
+func hello(s *Session, args []resp.Value) resp.Value {
+	proto := s.Proto
+	var user, password, name string
+	var auth, setName bool
+
+	if len(args) > 0 {
+		version, err := strconv.Atoi(string(args[0].Bulk))
+		if err != nil {
+			return resp.Value{
+				T:      resp.RespTError,
+				String: "ERR Protocol version is not an integer or out of range",
+			}
+		}
+
+		if version != int(resp.Resp2) && version != int(resp.Resp3) {
+			return resp.Value{
+				T:      resp.RespTError,
+				String: "NOPROTO unsupported protocol version",
+			}
+		}
+		proto = resp.Protocol(version)
+
+		for i := 1; i < len(args); i++ {
+			option := string(args[i].Bulk)
+			more := len(args) - i - 1
+
+			switch {
+			case strings.EqualFold(option, "AUTH") && more >= 2:
+				auth = true
+				user, password = string(args[i+1].Bulk), string(args[i+2].Bulk)
+				i += 2
+			case strings.EqualFold(option, "SETNAME") && more >= 1:
+				setName = true
+				name = string(args[i+1].Bulk)
+				i++
+				if !ValidClientName(name) {
+					return resp.Value{
+						T:      resp.RespTError,
+						String: "ERR Client names cannot contain spaces, newlines or special characters.",
+					}
+				}
+			default:
+				return ErrorReply("ERR Syntax error in HELLO option '" + strings.ToLower(option) + "'")
+			}
+		}
+	}
+
+	if auth {
+		if err := s.Authenticate(user, password); err != nil {
+			return resp.Value{
+				T:      resp.RespTError,
+				String: "WRONGPASS invalid username-password pair or user is disabled.",
+			}
+		}
+	}
+	if !s.Authenticated {
+		return resp.Value{
+			T: resp.RespTError,
+			String: "NOAUTH HELLO must be called with the client already authenticated, otherwise the " +
+				"HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and " +
+				"select the RESP protocol version at the same time",
+		}
+	}
+
+	if setName {
+		s.Name = name
+	}
+	s.Proto = proto
+
+	return resp.Value{
+		T: resp.RespTMap,
+		Array: []resp.Value{
+			{T: resp.RespTBulk, Bulk: []byte("server")},
+			{T: resp.RespTBulk, Bulk: []byte("redis")},
+			{T: resp.RespTBulk, Bulk: []byte("version")},
+			{T: resp.RespTBulk, Bulk: []byte(Version)},
+			{T: resp.RespTBulk, Bulk: []byte("proto")},
+			{T: resp.RespTInteger, Number: int(s.Proto)},
+			{T: resp.RespTBulk, Bulk: []byte("id")},
+			{T: resp.RespTInteger, Number: int(s.ID)},
+			{T: resp.RespTBulk, Bulk: []byte("mode")},
+			{T: resp.RespTBulk, Bulk: []byte("standalone")},
+			{T: resp.RespTBulk, Bulk: []byte("role")},
+			{T: resp.RespTBulk, Bulk: []byte("master")},
+			{T: resp.RespTBulk, Bulk: []byte("modules")},
+			{T: resp.RespTArray, Array: []resp.Value{}},
+		},
+	}
+}
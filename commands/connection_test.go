@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/helewud/redis-clone/resp"
+)
+
+func TestHello(t *testing.T) {
+	tests := []struct {
+		name      string
+		proto     resp.Protocol
+		args      []resp.Value
+		wantProto resp.Protocol
+		wantErr   string
+	}{
+		{
+			name:      "no arguments keeps protocol",
+			proto:     resp.Resp3,
+			wantProto: resp.Resp3,
+		},
+		{
+			name:      "switch to resp3",
+			proto:     resp.Resp2,
+			args:      []resp.Value{{T: resp.RespTBulk, Bulk: []byte("3")}},
+			wantProto: resp.Resp3,
+		},
+		{
+			name:      "switch back to resp2",
+			proto:     resp.Resp3,
+			args:      []resp.Value{{T: resp.RespTBulk, Bulk: []byte("2")}},
+			wantProto: resp.Resp2,
+		},
+		{
+			name:      "unsupported version",
+			proto:     resp.Resp2,
+			args:      []resp.Value{{T: resp.RespTBulk, Bulk: []byte("4")}},
+			wantProto: resp.Resp2,
+			wantErr:   "NOPROTO unsupported protocol version",
+		},
+		{
+			name:      "non numeric version",
+			proto:     resp.Resp2,
+			args:      []resp.Value{{T: resp.RespTBulk, Bulk: []byte("three")}},
+			wantProto: resp.Resp2,
+			wantErr:   "ERR Protocol version is not an integer or out of range",
+		},
+		{
+			name:  "unknown option",
+			proto: resp.Resp2,
+			args: []resp.Value{
+				{T: resp.RespTBulk, Bulk: []byte("3")},
+				{T: resp.RespTBulk, Bulk: []byte("FOO")},
+			},
+			wantProto: resp.Resp2,
+			wantErr:   "ERR Syntax error in HELLO option 'foo'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSession(7, "127.0.0.1:50000")
+			s.Proto = tt.proto
+
+			got := hello(s, tt.args)
+			if s.Proto != tt.wantProto {
+				t.Errorf("hello() proto = %v, want %v", s.Proto, tt.wantProto)
+			}
+
+			if tt.wantErr != "" {
+				want := resp.Value{T: resp.RespTError, String: tt.wantErr}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("hello() = %v, want %v", got, want)
+				}
+				return
+			}
+
+			if got.T != resp.RespTMap {
+				t.Fatalf("hello() type = %v, want %v", got.T, resp.RespTMap)
+			}
+			want := resp.Value{T: resp.RespTInteger, Number: int(tt.wantProto)}
+			if !reflect.DeepEqual(got.Array[5], want) {
+				t.Errorf("hello() proto field = %v, want %v", got.Array[5], want)
+			}
+			want = resp.Value{T: resp.RespTInteger, Number: 7}
+			if !reflect.DeepEqual(got.Array[7], want) {
+				t.Errorf("hello() id field = %v, want %v", got.Array[7], want)
+			}
+		})
+	}
+}
+
+func TestHelloOptions(t *testing.T) {
+	bulks := func(strs ...string) []resp.Value {
+		args := make([]resp.Value, len(strs))
+		for i, s := range strs {
+			args[i] = resp.Value{T: resp.RespTBulk, Bulk: []byte(s)}
+		}
+		return args
+	}
+
+	s := NewSession(7, "127.0.0.1:50000")
+	s.Authenticated = false
+	s.SetAuthenticator(func(user, password string) error {
+		if user != "alice" || password != "pw" {
+			return errors.New("wrong password")
+		}
+		return nil
+	})
+
+	got := hello(s, bulks("3"))
+	if got.T != resp.RespTError || !strings.HasPrefix(got.String, "NOAUTH ") {
+		t.Errorf("hello() without AUTH = %v, want NOAUTH", got)
+	}
+
+	got = hello(s, bulks("3", "AUTH", "alice", "nope"))
+	if got.T != resp.RespTError || !strings.HasPrefix(got.String, "WRONGPASS ") {
+		t.Errorf("hello() with a wrong password = %v, want WRONGPASS", got)
+	}
+
+	got = hello(s, bulks("3", "SETNAME", "bad name", "AUTH", "alice", "pw"))
+	if got.T != resp.RespTError || s.Authenticated {
+		t.Errorf("hello() with a bad name = %v, authenticated %v, want an error", got, s.Authenticated)
+	}
+
+	got = hello(s, bulks("3", "auth", "alice", "pw", "setname", "worker"))
+	if got.T != resp.RespTMap {
+		t.Fatalf("hello() = %v, want a map", got)
+	}
+	if s.Proto != resp.Resp3 || s.User != "alice" || !s.Authenticated || s.Name != "worker" {
+		t.Errorf("hello() session = %+v, want alice on resp3 named worker", s)
+	}
+
+	got = hello(s, bulks("2", "AUTH", "alice"))
+	want := resp.Value{T: resp.RespTError, String: "ERR Syntax error in HELLO option 'auth'"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hello() = %v, want %v", got, want)
+	}
+}
@@ -9,84 +9,77 @@ import (
 var HSETs = map[string]map[string]string{}
 var HSETsMu = sync.RWMutex{}
 
-func hset(args []resp.Value) resp.Value {
-	if len(args) != 3 {
+// hset sets one or more fields: HSET key field value [field value ...]
+// and replies with the number of fields that were added
+func hset(s *Session, args []resp.Value) resp.Value {
+	// the arity only ensures one pair, every field needs a value
+	if len(args)%2 == 0 {
 		return resp.Value{
 			T:      resp.RespTError,
-			String: "ERR wrong number of arguments for 'HSET' command",
+			String: "ERR wrong number of arguments for 'hset' command",
 		}
 	}
 
-	rkey := args[0].Bulk
-	pkey := args[1].Bulk
-	value := args[2].Bulk
+	rkey := string(args[0].Bulk)
 
-	res := resp.Value{
-		T:      resp.RespTString,
-		String: "OK",
-	}
+	res := resp.Value{T: resp.RespTInteger}
 
 	HSETsMu.Lock()
-	_, ok := HSETs[rkey]
+	hash, ok := HSETs[rkey]
 	if !ok {
-		HSETs[rkey] = map[string]string{
-			pkey: value,
-		}
+		hash = map[string]string{}
+		HSETs[rkey] = hash
 	}
-	if ok {
-		HSETs[rkey][pkey] = value
+	for i := 1; i < len(args); i += 2 {
+		field := string(args[i].Bulk)
+		if _, exists := hash[field]; !exists {
+			res.Number++
+		}
+		hash[field] = string(args[i+1].Bulk)
 	}
 	HSETsMu.Unlock()
 
 	return res
 }
 
-func hget(args []resp.Value) resp.Value {
-	if len(args) != 2 {
-		return resp.Value{
-			T:      resp.RespTError,
-			String: "ERR wrong number of arguments for 'HGET' command",
-		}
-	}
-
-	rkey := args[0].Bulk
-	pkey := args[1].Bulk
+func hget(s *Session, args []resp.Value) resp.Value {
+	rkey := string(args[0].Bulk)
+	pkey := string(args[1].Bulk)
 
 	res := resp.Value{T: resp.RespTNull}
 
 	HSETsMu.RLock()
-	if value, ok := HSETs[rkey][pkey]; ok {
+	hash, ok := HSETs[rkey]
+	if value, found := hash[pkey]; found {
 		res.T = resp.RespTBulk
-		res.Bulk = value
+		res.Bulk = []byte(value)
 	}
 	HSETsMu.RUnlock()
+	lookedUp(ok)
 
 	return res
 }
 
-func hgetall(args []resp.Value) resp.Value {
-	if len(args) != 1 {
-		return resp.Value{
-			T:      resp.RespTError,
-			String: "ERR wrong number of arguments for 'HGETALL' command",
-		}
-	}
-
-	rkey := args[0].Bulk
+// hgetall replies with the fields and values of a hash. They are copied
+// as strings under the lock and streamed by the writer once it's released,
+// so a large hash costs no Value per field and a slow client doesn't hold
+// up writers.
+func hgetall(s *Session, args []resp.Value) resp.Value {
+	rkey := string(args[0].Bulk)
 
-	val := []resp.Value{}
 	res := resp.Value{T: resp.RespTNull}
 
 	HSETsMu.RLock()
-	if value, ok := HSETs[rkey]; ok {
+	value, ok := HSETs[rkey]
+	if ok {
+		res.T = resp.RespTMap
+		res.Strings = make([]string, 0, 2*len(value))
 		for k, v := range value {
-			val = append(val, resp.Value{T: resp.RespTBulk, Bulk: k})
-			val = append(val, resp.Value{T: resp.RespTBulk, Bulk: v})
+			res.Strings = append(res.Strings, k, v)
 		}
-		res.T = resp.RespTArray
-		res.Array = val
 	}
 	HSETsMu.RUnlock()
+	lookedUp(ok)
 
 	return res
 }
@@ -8,6 +8,8 @@ import (
 )
 
 func TestHash(t *testing.T) {
+	s := NewSession(1, "127.0.0.1:50000")
+
 	// Clear the map before testing
 	HSETs = map[string]map[string]string{}
 
@@ -29,27 +31,24 @@ func TestHash(t *testing.T) {
 			scenario: "success",
 
 			hsetArgs: []resp.Value{
-				{T: resp.RespTBulk, Bulk: "hash1"},
-				{T: resp.RespTBulk, Bulk: "field1"},
-				{T: resp.RespTBulk, Bulk: "value1"},
+				{T: resp.RespTBulk, Bulk: []byte("hash1")},
+				{T: resp.RespTBulk, Bulk: []byte("field1")},
+				{T: resp.RespTBulk, Bulk: []byte("value1")},
 			},
-			wantHset: resp.Value{T: resp.RespTString, String: "OK"},
+			wantHset: resp.Value{T: resp.RespTInteger, Number: 1},
 
 			hgetArgs: []resp.Value{
-				{T: resp.RespTBulk, Bulk: "hash1"},
-				{T: resp.RespTBulk, Bulk: "field1"},
+				{T: resp.RespTBulk, Bulk: []byte("hash1")},
+				{T: resp.RespTBulk, Bulk: []byte("field1")},
 			},
-			wantHget: resp.Value{T: resp.RespTBulk, Bulk: "value1"},
+			wantHget: resp.Value{T: resp.RespTBulk, Bulk: []byte("value1")},
 
 			hgetallArgs: []resp.Value{
-				{T: resp.RespTBulk, Bulk: "hash1"},
+				{T: resp.RespTBulk, Bulk: []byte("hash1")},
 			},
 			wantHgetall: resp.Value{
-				T: "array",
-				Array: []resp.Value{
-					{T: resp.RespTBulk, Bulk: "field1"},
-					{T: resp.RespTBulk, Bulk: "value1"},
-				},
+				T:       resp.RespTMap,
+				Strings: []string{"field1", "value1"},
 			},
 		},
 		{
@@ -57,8 +56,8 @@ func TestHash(t *testing.T) {
 			scenario: "hget_only",
 
 			hgetArgs: []resp.Value{
-				{T: resp.RespTBulk, Bulk: "hash1"},
-				{T: resp.RespTBulk, Bulk: "nonexistent"},
+				{T: resp.RespTBulk, Bulk: []byte("hash1")},
+				{T: resp.RespTBulk, Bulk: []byte("nonexistent")},
 			},
 			wantHget: resp.Value{T: resp.RespTNull},
 		},
@@ -68,21 +67,21 @@ func TestHash(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			switch tt.scenario {
 			case "success":
-				got := hset(tt.hsetArgs)
+				got := hset(s, tt.hsetArgs)
 				if !reflect.DeepEqual(got, tt.wantHset) {
 					t.Errorf("hset() = %v, want %v", got, tt.wantHset)
 				}
-				got = hget(tt.hgetArgs)
+				got = hget(s, tt.hgetArgs)
 				if !reflect.DeepEqual(got, tt.wantHget) {
 					t.Errorf("hget() = %v, want %v", got, tt.wantHget)
 				}
-				got = hgetall(tt.hgetallArgs)
+				got = hgetall(s, tt.hgetallArgs)
 				if !reflect.DeepEqual(got, tt.wantHgetall) {
 					t.Errorf("hgetall() = %v, want %v", got, tt.wantHgetall)
 				}
 
 			case "hget_only":
-				got := hget(tt.hgetArgs)
+				got := hget(s, tt.hgetArgs)
 				if !reflect.DeepEqual(got, tt.wantHget) {
 					t.Errorf("hget() = %v, want %v", got, tt.wantHget)
 				}
@@ -91,6 +90,37 @@ func TestHash(t *testing.T) {
 	}
 }
 
+func TestHsetMultipleFields(t *testing.T) {
+	s := NewSession(1, "127.0.0.1:50000")
+
+	HSETs = map[string]map[string]string{}
+
+	bulk := func(s string) resp.Value { return resp.Value{T: resp.RespTBulk, Bulk: []byte(s)} }
+
+	got := hset(s, []resp.Value{bulk("user:1"), bulk("name"), bulk("alice"), bulk("age"), bulk("30")})
+	want := resp.Value{T: resp.RespTInteger, Number: 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hset() = %v, want %v", got, want)
+	}
+
+	// updating an existing field adds nothing
+	got = hset(s, []resp.Value{bulk("user:1"), bulk("age"), bulk("31")})
+	want = resp.Value{T: resp.RespTInteger, Number: 0}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hset() = %v, want %v", got, want)
+	}
+
+	wantHash := map[string]string{"name": "alice", "age": "31"}
+	if !reflect.DeepEqual(HSETs["user:1"], wantHash) {
+		t.Errorf("HSETs[user:1] = %v, want %v", HSETs["user:1"], wantHash)
+	}
+
+	got = hset(s, []resp.Value{bulk("user:1"), bulk("name"), bulk("bob"), bulk("age")})
+	if got.T != resp.RespTError {
+		t.Errorf("hset() with a dangling field = %v, want an error", got)
+	}
+}
+
 // TestConcurrentAccess tests thread safety of the operations
 // func TestConcurrentAccess(t *testing.T) {
 // 	// Clear maps before testing
@@ -0,0 +1,37 @@
+package commands
+
+import "sync/atomic"
+
+// KeyspaceHits and KeyspaceMisses count the lookups of keys by read
+// commands that found the key or not, as reported by INFO
+var (
+	KeyspaceHits   atomic.Int64
+	KeyspaceMisses atomic.Int64
+)
+
+// lookedUp counts a lookup of a key
+func lookedUp(found bool) {
+	if found {
+		KeyspaceHits.Add(1)
+	} else {
+		KeyspaceMisses.Add(1)
+	}
+}
+
+// KeyCount returns the number of keys. Strings and hashes live apart, so a
+// name used by both counts once.
+func KeyCount() int {
+	SETsMu.RLock()
+	defer SETsMu.RUnlock()
+	HSETsMu.RLock()
+	defer HSETsMu.RUnlock()
+
+	n := len(SETs)
+	for key := range HSETs {
+		if _, ok := SETs[key]; !ok {
+			n++
+		}
+	}
+
+	return n
+}
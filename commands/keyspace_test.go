@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyspaceStats(t *testing.T) {
+	s := NewSession(1, "127.0.0.1:50000")
+	hits, misses := KeyspaceHits.Load(), KeyspaceMisses.Load()
+
+	Commands.Call(s, command("SET", bulks("stats:string", "v")...))
+	Commands.Call(s, command("HSET", bulks("stats:hash", "f", "v")...))
+
+	Commands.Call(s, command("GET", bulks("stats:string")...))
+	Commands.Call(s, command("GET", bulks("stats:nope")...))
+	Commands.Call(s, command("HGET", bulks("stats:hash", "nope")...))
+	Commands.Call(s, command("HGETALL", bulks("stats:nope")...))
+
+	// a missing field in an existing hash is still a hit on the key
+	assert.Equal(t, hits+2, KeyspaceHits.Load())
+	assert.Equal(t, misses+2, KeyspaceMisses.Load())
+}
+
+func TestKeyCount(t *testing.T) {
+	s := NewSession(1, "127.0.0.1:50000")
+
+	// start without the keys from a previous run
+	for _, key := range []string{"count:string", "count:hash"} {
+		delete(SETs, key)
+		delete(HSETs, key)
+	}
+	n := KeyCount()
+
+	Commands.Call(s, command("SET", bulks("count:string", "v")...))
+	Commands.Call(s, command("HSET", bulks("count:hash", "f", "v")...))
+	Commands.Call(s, command("HSET", bulks("count:string", "f", "v")...))
+	assert.Equal(t, n+2, KeyCount())
+
+	Commands.Call(s, command("DEL", bulks("count:string")...))
+	assert.Equal(t, n+2, KeyCount())
+}
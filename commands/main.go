@@ -2,14 +2,6 @@ package commands
 
 import "github.com/helewud/redis-clone/resp"
 
-type RespHandler = func([]resp.Value) resp.Value
-
-var Handlers = map[string]RespHandler{
-	"PING":    ping,
-	"SET":     set,
-	"GET":     get,
-	"DEL":     del,
-	"HSET":    hset,
-	"HGET":    hget,
-	"HGETALL": hgetall,
-}
+// RespHandler runs a command for the client of session s with the arguments
+// following the command name. The arity is checked before it runs.
+type RespHandler = func(s *Session, args []resp.Value) resp.Value
@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"errors"
+
+	"github.com/helewud/redis-clone/resp"
+)
+
+// ErrNoPush is returned when pushing to a session that is not attached to a
+// connection, such as the one used for AOF replay
+var ErrNoPush = errors.New("session can't receive pushes")
+
+// ErrNoAuth is returned when authenticating a session that has no
+// authenticator attached
+var ErrNoAuth = errors.New("session can't authenticate")
+
+// Session is the state of one client connection. The server creates one per
+// connection and hands it to every handler along with the arguments.
+// Handlers run on the connection's goroutine, so they may change the session
+// without locking.
+type Session struct {
+	// ID is unique for the lifetime of the server
+	ID int64
+
+	// Addr is the remote address of the client
+	Addr string
+
+	// Name is set by the client with CLIENT SETNAME
+	Name string
+
+	// DB is the selected database
+	DB int
+
+	// User is the user the connection is authenticated as
+	User string
+
+	// Authenticated is false until the client passes AUTH when the server
+	// requires it
+	Authenticated bool
+
+	// Proto is the protocol replies are encoded with, set by HELLO
+	Proto resp.Protocol
+
+	push func(resp.Value) error
+	auth func(user, password string) error
+}
+
+// NewSession returns the session of a new connection, authenticated as the
+// default user and speaking RESP2
+func NewSession(id int64, addr string) *Session {
+	return &Session{
+		ID:            id,
+		Addr:          addr,
+		User:          "default",
+		Authenticated: true,
+		Proto:         resp.Resp2,
+	}
+}
+
+// SetPusher attaches the function that delivers out-of-band messages to the
+// client. It must be safe to call from any goroutine.
+func (s *Session) SetPusher(push func(resp.Value) error) {
+	s.push = push
+}
+
+// SetAuthenticator attaches the function that checks a username and
+// password, as HELLO AUTH does
+func (s *Session) SetAuthenticator(auth func(user, password string) error) {
+	s.auth = auth
+}
+
+// Authenticate logs the session in as user when password is theirs
+func (s *Session) Authenticate(user, password string) error {
+	if s.auth == nil {
+		return ErrNoAuth
+	}
+	if err := s.auth(user, password); err != nil {
+		return err
+	}
+
+	s.User = user
+	s.Authenticated = true
+
+	return nil
+}
+
+// ValidClientName reports whether name may be set as a client name, which
+// can't contain spaces, newlines or special characters
+func ValidClientName(name string) bool {
+	for _, r := range name {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Push sends an out-of-band message, such as a pub/sub message, to the
+// client. It may be called from any goroutine.
+func (s *Session) Push(v resp.Value) error {
+	if s.push == nil {
+		return ErrNoPush
+	}
+
+	return s.push(v)
+}
@@ -6,7 +6,7 @@ import (
 	"github.com/helewud/redis-clone/resp"
 )
 
-func ping(args []resp.Value) resp.Value {
+func ping(s *Session, args []resp.Value) resp.Value {
 	if len(args) == 0 {
 		return resp.Value{
 			T:      resp.RespTString,
@@ -16,23 +16,16 @@ func ping(args []resp.Value) resp.Value {
 
 	return resp.Value{
 		T:      resp.RespTString,
-		String: args[0].Bulk,
+		String: string(args[0].Bulk),
 	}
 }
 
 var SETs = map[string]string{}
 var SETsMu = sync.RWMutex{}
 
-func set(args []resp.Value) resp.Value {
-	if len(args) != 2 {
-		return resp.Value{
-			T:      resp.RespTError,
-			String: "ERR wrong number of arguments for 'SET' command",
-		}
-	}
-
-	key := args[0].Bulk
-	value := args[1].Bulk
+func set(s *Session, args []resp.Value) resp.Value {
+	key := string(args[0].Bulk)
+	value := string(args[1].Bulk)
 
 	SETsMu.Lock()
 	SETs[key] = value
@@ -44,45 +37,33 @@ func set(args []resp.Value) resp.Value {
 	}
 }
 
-func get(args []resp.Value) resp.Value {
-	if len(args) != 1 {
-		return resp.Value{
-			T:      resp.RespTError,
-			String: "ERR wrong number of arguments for 'GET' command",
-		}
-	}
-
-	key := args[0].Bulk
+func get(s *Session, args []resp.Value) resp.Value {
+	key := string(args[0].Bulk)
 	res := resp.Value{T: resp.RespTNull}
 
 	SETsMu.RLock()
-	if value, ok := SETs[key]; ok {
+	value, ok := SETs[key]
+	if ok {
 		res.T = resp.RespTBulk
-		res.Bulk = value
+		res.Bulk = []byte(value)
 	}
 	SETsMu.RUnlock()
+	lookedUp(ok)
 
 	return res
 }
 
-func del(args []resp.Value) resp.Value {
-	if len(args) != 1 {
-		return resp.Value{
-			T:      resp.RespTError,
-			String: "ERR wrong number of arguments for 'DEL' command",
-		}
-	}
-
-	key := args[0].Bulk
+func del(s *Session, args []resp.Value) resp.Value {
+	key := string(args[0].Bulk)
 	res := resp.Value{T: resp.RespTNull}
 
-	SETsMu.RLock()
+	SETsMu.Lock()
 	if _, ok := SETs[key]; ok {
 		delete(SETs, key)
 		res.T = resp.RespTString
 		res.String = "OK"
 	}
-	SETsMu.RUnlock()
+	SETsMu.Unlock()
 
 	return res
 }
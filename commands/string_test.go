@@ -8,6 +8,8 @@ import (
 )
 
 func TestPing(t *testing.T) {
+	s := NewSession(1, "127.0.0.1:50000")
+
 	tests := []struct {
 		name string
 		args []resp.Value
@@ -20,14 +22,14 @@ func TestPing(t *testing.T) {
 		},
 		{
 			name: "ping with argument",
-			args: []resp.Value{{T: resp.RespTBulk, Bulk: "hello"}},
+			args: []resp.Value{{T: resp.RespTBulk, Bulk: []byte("hello")}},
 			want: resp.Value{T: resp.RespTString, String: "hello"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := ping(tt.args)
+			got := ping(s, tt.args)
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("ping() = %v, want %v", got, tt.want)
 			}
@@ -36,6 +38,8 @@ func TestPing(t *testing.T) {
 }
 
 func TestSetAndGet(t *testing.T) {
+	s := NewSession(1, "127.0.0.1:50000")
+
 	// Clear the map before testing
 	SETs = map[string]string{}
 
@@ -50,20 +54,20 @@ func TestSetAndGet(t *testing.T) {
 		{
 			name: "simple set and get",
 			setArgs: []resp.Value{
-				{T: resp.RespTBulk, Bulk: "key1"},
-				{T: resp.RespTBulk, Bulk: "resp.value1"},
+				{T: resp.RespTBulk, Bulk: []byte("key1")},
+				{T: resp.RespTBulk, Bulk: []byte("resp.value1")},
 			},
 			getArgs: []resp.Value{
-				{T: resp.RespTBulk, Bulk: "key1"},
+				{T: resp.RespTBulk, Bulk: []byte("key1")},
 			},
 			wantSet:  resp.Value{T: resp.RespTString, String: "OK"},
-			wantGet:  resp.Value{T: resp.RespTBulk, Bulk: "resp.value1"},
+			wantGet:  resp.Value{T: resp.RespTBulk, Bulk: []byte("resp.value1")},
 			scenario: "success",
 		},
 		{
 			name: "get non-existent key",
 			getArgs: []resp.Value{
-				{T: resp.RespTBulk, Bulk: "nonexistent"},
+				{T: resp.RespTBulk, Bulk: []byte("nonexistent")},
 			},
 			wantGet:  resp.Value{T: resp.RespTNull},
 			scenario: "get_only",
@@ -71,23 +75,23 @@ func TestSetAndGet(t *testing.T) {
 		{
 			name: "set with wrong args",
 			setArgs: []resp.Value{
-				{T: resp.RespTBulk, Bulk: "key1"},
+				{T: resp.RespTBulk, Bulk: []byte("key1")},
 			},
 			wantSet: resp.Value{
 				T:      resp.RespTError,
-				String: "ERR wrong number of arguments for 'SET' command",
+				String: "ERR wrong number of arguments for 'set' command",
 			},
 			scenario: "set_error",
 		},
 		{
 			name: "get with wrong args",
 			getArgs: []resp.Value{
-				{T: resp.RespTBulk, Bulk: "key1"},
-				{T: resp.RespTBulk, Bulk: "extra"},
+				{T: resp.RespTBulk, Bulk: []byte("key1")},
+				{T: resp.RespTBulk, Bulk: []byte("extra")},
 			},
 			wantGet: resp.Value{
 				T:      resp.RespTError,
-				String: "ERR wrong number of arguments for 'GET' command",
+				String: "ERR wrong number of arguments for 'get' command",
 			},
 			scenario: "get_error",
 		},
@@ -97,26 +101,28 @@ func TestSetAndGet(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			switch tt.scenario {
 			case "success":
-				got := set(tt.setArgs)
+				got := set(s, tt.setArgs)
 				if !reflect.DeepEqual(got, tt.wantSet) {
 					t.Errorf("set() = %v, want %v", got, tt.wantSet)
 				}
-				got = get(tt.getArgs)
+				got = get(s, tt.getArgs)
 				if !reflect.DeepEqual(got, tt.wantGet) {
 					t.Errorf("get() = %v, want %v", got, tt.wantGet)
 				}
 			case "get_only":
-				got := get(tt.getArgs)
+				got := get(s, tt.getArgs)
 				if !reflect.DeepEqual(got, tt.wantGet) {
 					t.Errorf("get() = %v, want %v", got, tt.wantGet)
 				}
 			case "set_error":
-				got := set(tt.setArgs)
+				// arity is checked before the handler runs
+				got := Commands.Call(s, command("SET", tt.setArgs...))
 				if !reflect.DeepEqual(got, tt.wantSet) {
 					t.Errorf("set() = %v, want %v", got, tt.wantSet)
 				}
 			case "get_error":
-				got := get(tt.getArgs)
+				// arity is checked before the handler runs
+				got := Commands.Call(s, command("GET", tt.getArgs...))
 				if !reflect.DeepEqual(got, tt.wantGet) {
 					t.Errorf("get() = %v, want %v", got, tt.wantGet)
 				}
@@ -126,6 +132,7 @@ func TestSetAndGet(t *testing.T) {
 }
 
 func TestSetAndDel(t *testing.T) {
+	s := NewSession(1, "127.0.0.1:50000")
 
 	tests := []struct {
 		name     string
@@ -145,13 +152,13 @@ func TestSetAndDel(t *testing.T) {
 			scenario: "success",
 
 			setArgs: []resp.Value{
-				{T: resp.RespTBulk, Bulk: "key1"},
-				{T: resp.RespTBulk, Bulk: "resp.value1"},
+				{T: resp.RespTBulk, Bulk: []byte("key1")},
+				{T: resp.RespTBulk, Bulk: []byte("resp.value1")},
 			},
 			wantSet: resp.Value{T: resp.RespTString, String: "OK"},
 
 			delArgs: []resp.Value{
-				{T: resp.RespTBulk, Bulk: "key1"},
+				{T: resp.RespTBulk, Bulk: []byte("key1")},
 			},
 			wantDel: resp.Value{T: resp.RespTString, String: "OK"},
 		},
@@ -160,18 +167,18 @@ func TestSetAndDel(t *testing.T) {
 			scenario: "del_and_get",
 
 			setArgs: []resp.Value{
-				{T: resp.RespTBulk, Bulk: "key1"},
-				{T: resp.RespTBulk, Bulk: "resp.value1"},
+				{T: resp.RespTBulk, Bulk: []byte("key1")},
+				{T: resp.RespTBulk, Bulk: []byte("resp.value1")},
 			},
 			wantSet: resp.Value{T: resp.RespTString, String: "OK"},
 
 			delArgs: []resp.Value{
-				{T: resp.RespTBulk, Bulk: "key1"},
+				{T: resp.RespTBulk, Bulk: []byte("key1")},
 			},
 			wantDel: resp.Value{T: resp.RespTString, String: "OK"},
 
 			getArgs: []resp.Value{
-				{T: resp.RespTBulk, Bulk: "key1"},
+				{T: resp.RespTBulk, Bulk: []byte("key1")},
 			},
 			wantGet: resp.Value{T: resp.RespTNull},
 		},
@@ -180,7 +187,7 @@ func TestSetAndDel(t *testing.T) {
 			scenario: "del_only",
 
 			delArgs: []resp.Value{
-				{T: resp.RespTBulk, Bulk: "nonexistent"},
+				{T: resp.RespTBulk, Bulk: []byte("nonexistent")},
 			},
 			wantDel: resp.Value{T: resp.RespTNull},
 		},
@@ -189,13 +196,13 @@ func TestSetAndDel(t *testing.T) {
 			scenario: "del_error",
 
 			delArgs: []resp.Value{
-				{T: resp.RespTBulk, Bulk: "key1"},
-				{T: resp.RespTBulk, Bulk: "extra"},
+				{T: resp.RespTBulk, Bulk: []byte("key1")},
+				{T: resp.RespTBulk, Bulk: []byte("extra")},
 			},
 
 			wantDel: resp.Value{
 				T:      resp.RespTError,
-				String: "ERR wrong number of arguments for 'DEL' command",
+				String: "ERR wrong number of arguments for 'del' command",
 			},
 		},
 	}
@@ -207,39 +214,41 @@ func TestSetAndDel(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			switch tt.scenario {
 			case "success":
-				got := set(tt.setArgs)
+				got := set(s, tt.setArgs)
 				if !reflect.DeepEqual(got, tt.wantSet) {
 					t.Errorf("set() = %v, want %v", got, tt.wantSet)
 				}
-				got = del(tt.delArgs)
+				got = del(s, tt.delArgs)
 				if !reflect.DeepEqual(got, tt.wantDel) {
 					t.Errorf("del() = %v, want %v", got, tt.wantDel)
 				}
 			case "del_only":
-				got := del(tt.delArgs)
+				got := del(s, tt.delArgs)
 				if !reflect.DeepEqual(got, tt.wantDel) {
 					t.Errorf("del() = %v, want %v", got, tt.wantDel)
 				}
 			case "del_and_get":
-				got := set(tt.setArgs)
+				got := set(s, tt.setArgs)
 				if !reflect.DeepEqual(got, tt.wantSet) {
 					t.Errorf("set() = %v, want %v", got, tt.wantSet)
 				}
-				got = del(tt.delArgs)
+				got = del(s, tt.delArgs)
 				if !reflect.DeepEqual(got, tt.wantDel) {
 					t.Errorf("del() = %v, want %v", got, tt.wantDel)
 				}
-				got = get(tt.delArgs)
+				got = get(s, tt.delArgs)
 				if !reflect.DeepEqual(got, tt.wantGet) {
 					t.Errorf("del() = %v, want %v", got, tt.wantGet)
 				}
 			case "set_error":
-				got := set(tt.setArgs)
+				// arity is checked before the handler runs
+				got := Commands.Call(s, command("SET", tt.setArgs...))
 				if !reflect.DeepEqual(got, tt.wantSet) {
 					t.Errorf("set() = %v, want %v", got, tt.wantSet)
 				}
 			case "del_error":
-				got := del(tt.delArgs)
+				// arity is checked before the handler runs
+				got := Commands.Call(s, command("DEL", tt.delArgs...))
 				if !reflect.DeepEqual(got, tt.wantDel) {
 					t.Errorf("del() = %v, want %v", got, tt.wantDel)
 				}
@@ -0,0 +1,350 @@
+package commands
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/helewud/redis-clone/resp"
+)
+
+// Command flags, as reported by COMMAND
+const (
+	FlagWrite    = "write"
+	FlagReadOnly = "readonly"
+	FlagDenyOOM  = "denyoom"
+	FlagAdmin    = "admin"
+	FlagNoScript = "noscript"
+	FlagLoading  = "loading"
+	FlagStale    = "stale"
+	FlagFast     = "fast"
+	FlagNoAuth   = "no_auth"
+)
+
+// KeySpec tells which arguments are keys. Positions count the command name
+// as 0, a negative Last counts from the end, so -1 is the last argument.
+// First is 0 for commands without keys.
+type KeySpec struct {
+	First, Last, Step int
+
+	// Flags describe the access, such as RO, RW, OW or RM, and access,
+	// insert, update or delete
+	Flags []string
+}
+
+// Arg documents an argument for COMMAND DOCS
+type Arg struct {
+	Name     string
+	Type     string
+	Optional bool
+	Multiple bool
+}
+
+// Command is a command along with what the server and clients need to know
+// about it
+type Command struct {
+	// Name is the lower case name, container|subcommand for subcommands
+	Name    string
+	Handler RespHandler
+
+	// Arity is the number of arguments including the name, or minus the
+	// minimum number when it takes more
+	Arity int
+
+	Flags      []string
+	Categories []string
+	Keys       KeySpec
+
+	Summary    string
+	Since      string
+	Group      string
+	Complexity string
+	Args       []Arg
+
+	// Subcommands are set for containers such as CONFIG, whose handler
+	// runs them all
+	Subcommands map[string]*Command
+}
+
+// HasFlag reports whether the command has flag
+func (c *Command) HasFlag(flag string) bool {
+	return slices.Contains(c.Flags, flag)
+}
+
+// CheckArity reports whether args, the arguments after the name, fit the
+// arity
+func (c *Command) CheckArity(args []resp.Value) bool {
+	n := len(args) + 1
+	if c.Arity < 0 {
+		return n >= -c.Arity
+	}
+
+	return n == c.Arity
+}
+
+// WrongArity is the error reply when CheckArity fails
+func (c *Command) WrongArity() resp.Value {
+	return resp.Value{
+		T:      resp.RespTError,
+		String: fmt.Sprintf("ERR wrong number of arguments for '%s' command", c.Name),
+	}
+}
+
+// Subcommand returns the subcommand args start with, if the command has one
+func (c *Command) Subcommand(args []resp.Value) (*Command, bool) {
+	if c.Subcommands == nil || len(args) == 0 {
+		return nil, false
+	}
+
+	sub, ok := c.Subcommands[strings.ToLower(string(args[0].Bulk))]
+	return sub, ok
+}
+
+// KeyArgs returns the keys among args, the arguments after the name
+func (c *Command) KeyArgs(args []resp.Value) []string {
+	spec := c.Keys
+	if spec.First == 0 || spec.First > len(args) {
+		return nil
+	}
+
+	last := spec.Last
+	if last < 0 {
+		last = len(args) + 1 + last
+	}
+	last = min(last, len(args))
+	step := max(spec.Step, 1)
+
+	var keys []string
+	for i := spec.First; i <= last; i += step {
+		keys = append(keys, string(args[i-1].Bulk))
+	}
+
+	return keys
+}
+
+// UnknownCommand is the error reply to a command that does not exist. Like
+// Redis it quotes the name and the start of the arguments, both cut to 128
+// bytes.
+func UnknownCommand(name string, args []resp.Value) resp.Value {
+	var quoted strings.Builder
+	for _, arg := range args {
+		if quoted.Len() >= 128 {
+			break
+		}
+		fmt.Fprintf(&quoted, "'%s' ", truncate(string(arg.Bulk), 128-quoted.Len()))
+	}
+
+	return ErrorReply(fmt.Sprintf("ERR unknown command '%s', with args beginning with: %s", truncate(name, 128), quoted.String()))
+}
+
+// lineBreaks turns the line breaks of an error into spaces
+var lineBreaks = strings.NewReplacer("\r", " ", "\n", " ")
+
+// ErrorReply is the error reply with msg, which may quote client input. A
+// line break would end the error early and desync the client, so they are
+// replaced with spaces.
+func ErrorReply(msg string) resp.Value {
+	return resp.Value{T: resp.RespTError, String: lineBreaks.Replace(msg)}
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+
+	return s
+}
+
+// Table holds the commands by lower case name
+type Table map[string]*Command
+
+// NewTable returns a table of cmds
+func NewTable(cmds ...*Command) Table {
+	t := Table{}
+	t.Add(cmds...)
+
+	return t
+}
+
+// Add adds or replaces commands
+func (t Table) Add(cmds ...*Command) {
+	for _, cmd := range cmds {
+		t[cmd.Name] = cmd
+	}
+}
+
+// Clone returns a copy of the table that commands can be added to
+func (t Table) Clone() Table {
+	c := make(Table, len(t))
+	for name, cmd := range t {
+		c[name] = cmd
+	}
+
+	return c
+}
+
+// Lookup returns the command called name in any case
+func (t Table) Lookup(name string) (*Command, bool) {
+	cmd, ok := t[strings.ToLower(name)]
+	return cmd, ok
+}
+
+// Exists reports whether a command, or a command|subcommand, exists
+func (t Table) Exists(name string) bool {
+	name, sub, hasSub := strings.Cut(name, "|")
+	cmd, ok := t.Lookup(name)
+	if !ok || !hasSub {
+		return ok
+	}
+
+	_, ok = cmd.Subcommands[strings.ToLower(sub)]
+	return ok
+}
+
+// Names returns the sorted names of the commands
+func (t Table) Names() []string {
+	names := make([]string, 0, len(t))
+	for name := range t {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Call checks the arity of the command named by value's first element and
+// runs it, the way the AOF is replayed
+func (t Table) Call(s *Session, value resp.Value) resp.Value {
+	if value.T != resp.RespTArray || len(value.Array) == 0 {
+		return resp.Value{T: resp.RespTError, String: "ERR Protocol error: expected a command"}
+	}
+
+	name := string(value.Array[0].Bulk)
+	args := value.Array[1:]
+
+	cmd, ok := t.Lookup(name)
+	if !ok {
+		return UnknownCommand(name, args)
+	}
+	if !cmd.CheckArity(args) {
+		return cmd.WrongArity()
+	}
+
+	return cmd.Handler(s, args)
+}
+
+// Commands are the commands acting on the data and the connection. The
+// server adds the ones acting on itself.
+var Commands = NewTable(
+	&Command{
+		Name:       "ping",
+		Handler:    ping,
+		Arity:      -1,
+		Flags:      []string{FlagFast},
+		Categories: []string{"fast", "connection"},
+		Summary:    "Returns the server's liveliness response.",
+		Since:      "1.0.0",
+		Group:      "connection",
+		Complexity: "O(1)",
+		Args:       []Arg{{Name: "message", Type: "string", Optional: true}},
+	},
+	&Command{
+		Name:       "set",
+		Handler:    set,
+		Arity:      3,
+		Flags:      []string{FlagWrite, FlagDenyOOM},
+		Categories: []string{"write", "string", "slow"},
+		Keys:       KeySpec{First: 1, Last: 1, Step: 1, Flags: []string{"OW", "update"}},
+		Summary:    "Sets the string value of a key.",
+		Since:      "1.0.0",
+		Group:      "string",
+		Complexity: "O(1)",
+		Args:       []Arg{{Name: "key", Type: "key"}, {Name: "value", Type: "string"}},
+	},
+	&Command{
+		Name:       "get",
+		Handler:    get,
+		Arity:      2,
+		Flags:      []string{FlagReadOnly, FlagFast},
+		Categories: []string{"read", "string", "fast"},
+		Keys:       KeySpec{First: 1, Last: 1, Step: 1, Flags: []string{"RO", "access"}},
+		Summary:    "Returns the string value of a key.",
+		Since:      "1.0.0",
+		Group:      "string",
+		Complexity: "O(1)",
+		Args:       []Arg{{Name: "key", Type: "key"}},
+	},
+	&Command{
+		Name:       "del",
+		Handler:    del,
+		Arity:      2,
+		Flags:      []string{FlagWrite},
+		Categories: []string{"keyspace", "write", "slow"},
+		Keys:       KeySpec{First: 1, Last: 1, Step: 1, Flags: []string{"RM", "delete"}},
+		Summary:    "Deletes a key.",
+		Since:      "1.0.0",
+		Group:      "generic",
+		Complexity: "O(1)",
+		Args:       []Arg{{Name: "key", Type: "key"}},
+	},
+	&Command{
+		Name:       "hset",
+		Handler:    hset,
+		Arity:      -4,
+		Flags:      []string{FlagWrite, FlagDenyOOM, FlagFast},
+		Categories: []string{"write", "hash", "fast"},
+		Keys:       KeySpec{First: 1, Last: 1, Step: 1, Flags: []string{"RW", "update", "insert"}},
+		Summary:    "Creates or modifies the value of a field in a hash.",
+		Since:      "2.0.0",
+		Group:      "hash",
+		Complexity: "O(1) for each field/value pair added, so O(N) to add N field/value pairs when the command is called with multiple field/value pairs.",
+		Args: []Arg{
+			{Name: "key", Type: "key"},
+			{Name: "field", Type: "string", Multiple: true},
+			{Name: "value", Type: "string", Multiple: true},
+		},
+	},
+	&Command{
+		Name:       "hget",
+		Handler:    hget,
+		Arity:      3,
+		Flags:      []string{FlagReadOnly, FlagFast},
+		Categories: []string{"read", "hash", "fast"},
+		Keys:       KeySpec{First: 1, Last: 1, Step: 1, Flags: []string{"RO", "access"}},
+		Summary:    "Returns the value of a field in a hash.",
+		Since:      "2.0.0",
+		Group:      "hash",
+		Complexity: "O(1)",
+		Args:       []Arg{{Name: "key", Type: "key"}, {Name: "field", Type: "string"}},
+	},
+	&Command{
+		Name:       "hgetall",
+		Handler:    hgetall,
+		Arity:      2,
+		Flags:      []string{FlagReadOnly},
+		Categories: []string{"read", "hash", "slow"},
+		Keys:       KeySpec{First: 1, Last: 1, Step: 1, Flags: []string{"RO", "access"}},
+		Summary:    "Returns all fields and values in a hash.",
+		Since:      "2.0.0",
+		Group:      "hash",
+		Complexity: "O(N) where N is the size of the hash.",
+		Args:       []Arg{{Name: "key", Type: "key"}},
+	},
+	&Command{
+		Name:       "hello",
+		Handler:    hello,
+		Arity:      -1,
+		Flags:      []string{FlagNoScript, FlagLoading, FlagStale, FlagFast, FlagNoAuth},
+		Categories: []string{"fast", "connection"},
+		Summary:    "Handshakes with the Redis server.",
+		Since:      "6.0.0",
+		Group:      "connection",
+		Complexity: "O(1)",
+		Args: []Arg{
+			{Name: "protover", Type: "integer", Optional: true},
+			{Name: "auth", Type: "block", Optional: true},
+			{Name: "clientname", Type: "string", Optional: true},
+		},
+	},
+)
@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/helewud/redis-clone/resp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// command builds a command array from a name and its arguments
+func command(name string, args ...resp.Value) resp.Value {
+	return resp.Value{
+		T:     resp.RespTArray,
+		Array: append([]resp.Value{{T: resp.RespTBulk, Bulk: []byte(name)}}, args...),
+	}
+}
+
+func bulks(strs ...string) []resp.Value {
+	values := make([]resp.Value, len(strs))
+	for i, str := range strs {
+		values[i] = resp.Value{T: resp.RespTBulk, Bulk: []byte(str)}
+	}
+
+	return values
+}
+
+func TestCheckArity(t *testing.T) {
+	tests := []struct {
+		name  string
+		nargs int
+		want  bool
+	}{
+		{name: "get", nargs: 1, want: true},
+		{name: "get", nargs: 0, want: false},
+		{name: "get", nargs: 2, want: false},
+		{name: "ping", nargs: 0, want: true},
+		{name: "ping", nargs: 1, want: true},
+		{name: "hset", nargs: 2, want: false},
+		{name: "hset", nargs: 3, want: true},
+		{name: "hset", nargs: 5, want: true},
+	}
+
+	for _, tt := range tests {
+		cmd, ok := Commands.Lookup(tt.name)
+		require.True(t, ok)
+		assert.Equal(t, tt.want, cmd.CheckArity(make([]resp.Value, tt.nargs)), "%s with %d args", tt.name, tt.nargs)
+	}
+}
+
+func TestKeyArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		keys KeySpec
+		args []string
+		want []string
+	}{
+		{name: "no keys", args: []string{"a"}},
+		{name: "first", keys: KeySpec{First: 1, Last: 1, Step: 1}, args: []string{"k", "v"}, want: []string{"k"}},
+		{name: "all", keys: KeySpec{First: 1, Last: -1, Step: 1}, args: []string{"a", "b", "c"}, want: []string{"a", "b", "c"}},
+		{name: "pairs", keys: KeySpec{First: 1, Last: -1, Step: 2}, args: []string{"a", "1", "b", "2"}, want: []string{"a", "b"}},
+		{name: "missing", keys: KeySpec{First: 2, Last: 2, Step: 1}, args: []string{"a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &Command{Keys: tt.keys}
+			assert.Equal(t, tt.want, cmd.KeyArgs(bulks(tt.args...)))
+		})
+	}
+}
+
+func TestTableLookup(t *testing.T) {
+	table := Commands.Clone()
+	table.Add(&Command{
+		Name:        "config",
+		Subcommands: map[string]*Command{"get": {Name: "config|get", Arity: -3}},
+	})
+
+	cmd, ok := table.Lookup("HGETALL")
+	require.True(t, ok)
+	assert.Equal(t, "hgetall", cmd.Name)
+	assert.True(t, cmd.HasFlag(FlagReadOnly))
+
+	assert.True(t, table.Exists("CONFIG|get"))
+	assert.False(t, table.Exists("config|nope"))
+	assert.False(t, table.Exists("get|sub"))
+	assert.False(t, Commands.Exists("config"))
+
+	sub, ok := table["config"].Subcommand(bulks("GET", "port"))
+	require.True(t, ok)
+	assert.Equal(t, "config|get", sub.Name)
+	assert.Equal(t, "ERR wrong number of arguments for 'config|get' command", sub.WrongArity().String)
+}
+
+func TestCall(t *testing.T) {
+	s := NewSession(1, "127.0.0.1:50000")
+
+	assert.Equal(t, "OK", Commands.Call(s, command("SET", bulks("call", "v")...)).String)
+	assert.Equal(t, "v", string(Commands.Call(s, command("get", bulks("call")...)).Bulk))
+	assert.Equal(t, "ERR wrong number of arguments for 'hget' command", Commands.Call(s, command("HGET", bulks("h")...)).String)
+	assert.Equal(t, resp.RespTError, Commands.Call(s, command("NOPE")).T)
+
+	// a corrupt AOF may hold anything
+	assert.Equal(t, resp.RespTError, Commands.Call(s, resp.Value{T: resp.RespTArray}).T)
+	assert.Equal(t, resp.RespTError, Commands.Call(s, resp.Value{T: resp.RespTBulk, Bulk: []byte("SET")}).T)
+}
+
+func TestUnknownCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		args []resp.Value
+		want string
+	}{
+		{name: "nope", want: "ERR unknown command 'nope', with args beginning with: "},
+		{name: "NOPE", args: bulks("a", "b"), want: "ERR unknown command 'NOPE', with args beginning with: 'a' 'b' "},
+		{name: "no\r\npe", args: bulks("a\nb"), want: "ERR unknown command 'no  pe', with args beginning with: 'a b' "},
+		{
+			name: strings.Repeat("n", 200),
+			args: bulks(strings.Repeat("a", 100), strings.Repeat("b", 100), "c"),
+			want: "ERR unknown command '" + strings.Repeat("n", 128) + "', with args beginning with: '" +
+				strings.Repeat("a", 100) + "' '" + strings.Repeat("b", 25) + "' ",
+		},
+	}
+
+	for _, tt := range tests {
+		reply := UnknownCommand(tt.name, tt.args)
+		assert.Equal(t, resp.RespTError, reply.T)
+		assert.Equal(t, tt.want, reply.String)
+	}
+}
+
+func TestErrorReply(t *testing.T) {
+	assert.Equal(t, resp.Value{T: resp.RespTError, String: "ERR bad 'a  b c'"}, ErrorReply("ERR bad 'a\r\nb\nc'"))
+}
@@ -0,0 +1,606 @@
+// Package config holds the server settings. They are read from a
+// redis.conf style file and command-line flags, and can be inspected and
+// changed at runtime through CONFIG GET, CONFIG SET and CONFIG REWRITE.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/helewud/redis-clone/resp"
+	"github.com/helewud/redis-clone/storage"
+)
+
+var (
+	// ErrUnknownParam is returned for a parameter that doesn't exist
+	ErrUnknownParam = errors.New("unknown parameter")
+
+	// ErrImmutable is returned when setting a parameter that can't change
+	// while the server runs
+	ErrImmutable = errors.New("can't set immutable config")
+
+	// ErrNoConfigFile is returned by Rewrite when no file was loaded
+	ErrNoConfigFile = errors.New("the server is running without a config file")
+)
+
+// ParamError reports a parameter that could not be set
+type ParamError struct {
+	Param string
+	Err   error
+}
+
+func (e *ParamError) Error() string {
+	return e.Param + ": " + e.Err.Error()
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// Config are the server settings
+type Config struct {
+	// Bind are the interfaces to listen on, all of them when empty
+	Bind []string
+
+	// Port is the TCP port, 0 disables TCP
+	Port int
+
+	// UnixSocket is the path of a Unix socket to listen on, if any
+	UnixSocket string
+
+	// UnixSocketPerm are the permissions of the socket file, 0 leaves them
+	// to the umask
+	UnixSocketPerm fs.FileMode
+
+	// TLSPort is the TLS port, 0 disables TLS
+	TLSPort int
+
+	// TLSCertFile and TLSKeyFile are the PEM encoded certificate and key of
+	// the server
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// TLSCACertFile is the PEM bundle client certificates are verified
+	// against
+	TLSCACertFile string
+
+	// TLSAuthClients is whether clients need a certificate: yes, no or
+	// optional, which verifies one only when given
+	TLSAuthClients string
+
+	// TLSAuthClientsUser is CN to authenticate clients as the user named by
+	// the common name of their certificate, or off
+	TLSAuthClientsUser string
+
+	// Dir is the working directory the AOF lives in
+	Dir string
+
+	// AppendFilename is the name of the AOF within Dir
+	AppendFilename string
+
+	// AppendFsync is when the AOF is flushed to disk
+	AppendFsync storage.FsyncPolicy
+
+	// Limits bound what clients may send
+	Limits resp.Limits
+
+	// MaxClients is how many clients may be connected at once
+	MaxClients int
+
+	// Timeout closes clients idle for longer, 0 never does
+	Timeout time.Duration
+
+	// SlowlogLogSlowerThan is the duration from which commands are logged
+	// to the slow log, a negative one disables it
+	SlowlogLogSlowerThan time.Duration
+
+	// SlowlogMaxLen is how many entries the slow log keeps
+	SlowlogMaxLen int
+
+	// LogLevel is one of debug, verbose, notice, warning or nothing
+	LogLevel string
+
+	// LogFile is the file the log is written to, stdout when empty
+	LogFile string
+
+	// LogFormat is text or json
+	LogFormat string
+
+	// RequirePass is the password of the default user, none when empty.
+	// It is ignored when users come from an ACL file.
+	RequirePass string
+
+	// ACLFile is the file users are loaded from and saved to
+	ACLFile string
+}
+
+// Default returns the settings used when nothing else is configured
+func Default() Config {
+	return Config{
+		Port:                 6379,
+		TLSAuthClients:       "no",
+		TLSAuthClientsUser:   "off",
+		Dir:                  ".",
+		AppendFilename:       "storage.store",
+		AppendFsync:          storage.FsyncEverySec,
+		Limits:               resp.DefaultLimits,
+		MaxClients:           10000,
+		SlowlogLogSlowerThan: 10 * time.Millisecond,
+		SlowlogMaxLen:        128,
+		LogLevel:             "notice",
+		LogFormat:            "text",
+	}
+}
+
+// AOFPath returns the path of the append-only file
+func (c Config) AOFPath() string {
+	return filepath.Join(c.Dir, c.AppendFilename)
+}
+
+// Addrs returns the TCP addresses to listen on
+func (c Config) Addrs() []string {
+	return c.addrs(c.Port)
+}
+
+// TLSAddrs returns the TLS addresses to listen on
+func (c Config) TLSAddrs() []string {
+	return c.addrs(c.TLSPort)
+}
+
+func (c Config) addrs(p int) []string {
+	if p == 0 {
+		return nil
+	}
+
+	port := strconv.Itoa(p)
+	if len(c.Bind) == 0 {
+		return []string{":" + port}
+	}
+
+	addrs := make([]string, len(c.Bind))
+	for i, host := range c.Bind {
+		addrs[i] = net.JoinHostPort(strings.TrimPrefix(host, "-"), port)
+	}
+
+	return addrs
+}
+
+// param describes a setting by its redis.conf name
+type param struct {
+	name string
+
+	// mutable parameters can be changed with CONFIG SET
+	mutable bool
+
+	// multi parameters take several words, written unquoted
+	multi bool
+
+	get func(c *Config) string
+	set func(c *Config, value string) error
+}
+
+var params = []param{
+	{
+		name:  "bind",
+		multi: true,
+		get:   func(c *Config) string { return strings.Join(c.Bind, " ") },
+		set: func(c *Config, value string) error {
+			c.Bind = strings.Fields(value)
+			return nil
+		},
+	},
+	{
+		name: "port",
+		get:  func(c *Config) string { return strconv.Itoa(c.Port) },
+		set: func(c *Config, value string) error {
+			return setInt(&c.Port, value, 0, 65535)
+		},
+	},
+	{
+		name: "tls-port",
+		get:  func(c *Config) string { return strconv.Itoa(c.TLSPort) },
+		set: func(c *Config, value string) error {
+			return setInt(&c.TLSPort, value, 0, 65535)
+		},
+	},
+	{
+		name: "tls-cert-file",
+		get:  func(c *Config) string { return c.TLSCertFile },
+		set: func(c *Config, value string) error {
+			c.TLSCertFile = value
+			return nil
+		},
+	},
+	{
+		name: "tls-key-file",
+		get:  func(c *Config) string { return c.TLSKeyFile },
+		set: func(c *Config, value string) error {
+			c.TLSKeyFile = value
+			return nil
+		},
+	},
+	{
+		name: "tls-ca-cert-file",
+		get:  func(c *Config) string { return c.TLSCACertFile },
+		set: func(c *Config, value string) error {
+			c.TLSCACertFile = value
+			return nil
+		},
+	},
+	{
+		name: "tls-auth-clients",
+		get:  func(c *Config) string { return c.TLSAuthClients },
+		set: func(c *Config, value string) error {
+			return setEnum(&c.TLSAuthClients, value, "yes", "no", "optional")
+		},
+	},
+	{
+		name: "tls-auth-clients-user",
+		get:  func(c *Config) string { return c.TLSAuthClientsUser },
+		set: func(c *Config, value string) error {
+			switch strings.ToLower(value) {
+			case "cn":
+				c.TLSAuthClientsUser = "CN"
+			case "off":
+				c.TLSAuthClientsUser = "off"
+			default:
+				return errors.New("argument(s) must be one of the following: CN, off")
+			}
+			return nil
+		},
+	},
+	{
+		name: "unixsocket",
+		get:  func(c *Config) string { return c.UnixSocket },
+		set: func(c *Config, value string) error {
+			c.UnixSocket = value
+			return nil
+		},
+	},
+	{
+		name: "unixsocketperm",
+		get:  func(c *Config) string { return strconv.FormatUint(uint64(c.UnixSocketPerm), 8) },
+		set: func(c *Config, value string) error {
+			perm, err := strconv.ParseUint(value, 8, 32)
+			if err != nil || perm > 0777 {
+				return errors.New("argument must be an octal number between 0 and 777")
+			}
+			c.UnixSocketPerm = fs.FileMode(perm)
+			return nil
+		},
+	},
+	{
+		name: "dir",
+		get:  func(c *Config) string { return c.Dir },
+		set: func(c *Config, value string) error {
+			if value == "" {
+				return errors.New("argument must not be empty")
+			}
+			c.Dir = value
+			return nil
+		},
+	},
+	{
+		name: "appendfilename",
+		get:  func(c *Config) string { return c.AppendFilename },
+		set: func(c *Config, value string) error {
+			if value == "" || strings.ContainsRune(value, filepath.Separator) {
+				return errors.New("appendfilename can't be a path, just a filename")
+			}
+			c.AppendFilename = value
+			return nil
+		},
+	},
+	{
+		name:    "appendfsync",
+		mutable: true,
+		get:     func(c *Config) string { return string(c.AppendFsync) },
+		set: func(c *Config, value string) error {
+			policy := string(c.AppendFsync)
+			err := setEnum(&policy, value, string(storage.FsyncAlways), string(storage.FsyncEverySec), string(storage.FsyncNo))
+			c.AppendFsync = storage.FsyncPolicy(policy)
+			return err
+		},
+	},
+	{
+		name:    "proto-max-bulk-len",
+		mutable: true,
+		get:     func(c *Config) string { return strconv.Itoa(c.Limits.MaxBulkLen) },
+		set: func(c *Config, value string) error {
+			return setMemory(&c.Limits.MaxBulkLen, value, 1024*1024)
+		},
+	},
+	{
+		name:    "proto-max-multibulk-len",
+		mutable: true,
+		get:     func(c *Config) string { return strconv.Itoa(c.Limits.MaxArrayLen) },
+		set: func(c *Config, value string) error {
+			return setInt(&c.Limits.MaxArrayLen, value, 1, 1<<31-1)
+		},
+	},
+	{
+		name:    "proto-max-nesting-depth",
+		mutable: true,
+		get:     func(c *Config) string { return strconv.Itoa(c.Limits.MaxDepth) },
+		set: func(c *Config, value string) error {
+			return setInt(&c.Limits.MaxDepth, value, 1, 1024)
+		},
+	},
+	{
+		name:    "proto-inline-max-size",
+		mutable: true,
+		get:     func(c *Config) string { return strconv.Itoa(c.Limits.MaxLineLen) },
+		set: func(c *Config, value string) error {
+			return setMemory(&c.Limits.MaxLineLen, value, 1024)
+		},
+	},
+	{
+		name:    "maxclients",
+		mutable: true,
+		get:     func(c *Config) string { return strconv.Itoa(c.MaxClients) },
+		set: func(c *Config, value string) error {
+			return setInt(&c.MaxClients, value, 1, 1<<31-1)
+		},
+	},
+	{
+		name:    "timeout",
+		mutable: true,
+		get:     func(c *Config) string { return strconv.Itoa(int(c.Timeout / time.Second)) },
+		set: func(c *Config, value string) error {
+			var seconds int
+			if err := setInt(&seconds, value, 0, 1<<31-1); err != nil {
+				return err
+			}
+			c.Timeout = time.Duration(seconds) * time.Second
+			return nil
+		},
+	},
+	{
+		name:    "slowlog-log-slower-than",
+		mutable: true,
+		get:     func(c *Config) string { return strconv.Itoa(int(c.SlowlogLogSlowerThan / time.Microsecond)) },
+		set: func(c *Config, value string) error {
+			var micros int
+			if err := setInt(&micros, value, -1, 1<<31-1); err != nil {
+				return err
+			}
+			c.SlowlogLogSlowerThan = time.Duration(micros) * time.Microsecond
+			return nil
+		},
+	},
+	{
+		name:    "slowlog-max-len",
+		mutable: true,
+		get:     func(c *Config) string { return strconv.Itoa(c.SlowlogMaxLen) },
+		set: func(c *Config, value string) error {
+			return setInt(&c.SlowlogMaxLen, value, 0, 1<<31-1)
+		},
+	},
+	{
+		name:    "loglevel",
+		mutable: true,
+		get:     func(c *Config) string { return c.LogLevel },
+		set: func(c *Config, value string) error {
+			return setEnum(&c.LogLevel, value, "debug", "verbose", "notice", "warning", "nothing")
+		},
+	},
+	{
+		name: "logfile",
+		get:  func(c *Config) string { return c.LogFile },
+		set: func(c *Config, value string) error {
+			c.LogFile = value
+			return nil
+		},
+	},
+	{
+		name: "log-format",
+		get:  func(c *Config) string { return c.LogFormat },
+		set: func(c *Config, value string) error {
+			return setEnum(&c.LogFormat, value, "text", "json")
+		},
+	},
+	{
+		name:    "requirepass",
+		mutable: true,
+		get:     func(c *Config) string { return c.RequirePass },
+		set: func(c *Config, value string) error {
+			c.RequirePass = value
+			return nil
+		},
+	},
+	{
+		name: "aclfile",
+		get:  func(c *Config) string { return c.ACLFile },
+		set: func(c *Config, value string) error {
+			c.ACLFile = value
+			return nil
+		},
+	},
+}
+
+func lookup(name string) (param, bool) {
+	name = strings.ToLower(name)
+	for _, p := range params {
+		if p.name == name {
+			return p, true
+		}
+	}
+
+	return param{}, false
+}
+
+func setInt(dst *int, value string, lo, hi int) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < lo || n > hi {
+		return fmt.Errorf("argument must be between %d and %d inclusive", lo, hi)
+	}
+	*dst = n
+
+	return nil
+}
+
+func setEnum(dst *string, value string, allowed ...string) error {
+	value = strings.ToLower(value)
+	if !slices.Contains(allowed, value) {
+		return fmt.Errorf("argument(s) must be one of the following: %s", strings.Join(allowed, ", "))
+	}
+	*dst = value
+
+	return nil
+}
+
+// setMemory parses a size like 512mb, where k, m and g are powers of 1000
+// and kb, mb and gb powers of 1024
+func setMemory(dst *int, value string, lo int) error {
+	n, err := ParseMemory(value)
+	if err != nil {
+		return err
+	}
+	if n < lo {
+		return fmt.Errorf("argument must be a memory value of at least %d", lo)
+	}
+	*dst = n
+
+	return nil
+}
+
+// ParseMemory parses a redis.conf memory size such as 1gb or 100k
+func ParseMemory(value string) (int, error) {
+	units := []struct {
+		suffix string
+		mul    int
+	}{
+		{"kb", 1024}, {"mb", 1024 * 1024}, {"gb", 1024 * 1024 * 1024},
+		{"k", 1000}, {"m", 1000 * 1000}, {"g", 1000 * 1000 * 1000},
+		{"b", 1},
+	}
+
+	value = strings.ToLower(value)
+	mul := 1
+	for _, u := range units {
+		if strings.HasSuffix(value, u.suffix) {
+			value = strings.TrimSuffix(value, u.suffix)
+			mul = u.mul
+			break
+		}
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, errors.New("argument must be a memory value")
+	}
+
+	return n * mul, nil
+}
+
+// Manager guards the settings of a running server and remembers the file
+// they were loaded from, which CONFIG REWRITE updates
+type Manager struct {
+	mu   sync.RWMutex
+	cfg  Config
+	path string
+}
+
+// NewManager manages cfg, path is the config file or empty if there is none
+func NewManager(cfg Config, path string) *Manager {
+	return &Manager{cfg: cfg, path: path}
+}
+
+// Config returns a copy of the current settings
+func (m *Manager) Config() Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cfg := m.cfg
+	cfg.Bind = slices.Clone(cfg.Bind)
+
+	return cfg
+}
+
+// The accessors below read single settings without copying all of them,
+// for the ones consulted on every command
+
+// Timeout returns the current idle timeout
+func (m *Manager) Timeout() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.cfg.Timeout
+}
+
+// Limits returns the current client limits
+func (m *Manager) Limits() resp.Limits {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.cfg.Limits
+}
+
+// Slowlog returns the current slow log threshold and length
+func (m *Manager) Slowlog() (slowerThan time.Duration, maxLen int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.cfg.SlowlogLogSlowerThan, m.cfg.SlowlogMaxLen
+}
+
+// Path returns the config file, empty if there is none
+func (m *Manager) Path() string {
+	return m.path
+}
+
+// Get returns the names and values of the parameters matching any of the
+// glob patterns, in the order of alternating names and values
+func (m *Manager) Get(patterns ...string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var pairs []string
+	for _, p := range params {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(strings.ToLower(pattern), p.name); ok {
+				pairs = append(pairs, p.name, p.get(&m.cfg))
+				break
+			}
+		}
+	}
+
+	return pairs
+}
+
+// Set changes parameters at runtime from alternating names and values.
+// Either all of them are set or, on error, none.
+func (m *Manager) Set(pairs ...string) error {
+	if len(pairs)%2 != 0 {
+		return errors.New("config: Set needs name/value pairs")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cfg := m.cfg
+	for i := 0; i < len(pairs); i += 2 {
+		p, ok := lookup(pairs[i])
+		if !ok {
+			return &ParamError{Param: pairs[i], Err: ErrUnknownParam}
+		}
+		if !p.mutable {
+			return &ParamError{Param: p.name, Err: ErrImmutable}
+		}
+		if err := p.set(&cfg, pairs[i+1]); err != nil {
+			return &ParamError{Param: p.name, Err: err}
+		}
+	}
+	m.cfg = cfg
+
+	return nil
+}
@@ -0,0 +1,229 @@
+package config
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/helewud/redis-clone/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "redis.conf")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeFile(t, `# a comment
+port 7000
+bind 127.0.0.1 ::1
+unixsocket /tmp/redis-clone.sock
+unixsocketperm 770
+
+dir "/var/lib/redis clone"
+appendfsync always
+proto-max-bulk-len 1mb
+`)
+
+	cfg := Default()
+	require.NoError(t, Load(&cfg, path))
+
+	assert.Equal(t, 7000, cfg.Port)
+	assert.Equal(t, []string{"127.0.0.1", "::1"}, cfg.Bind)
+	assert.Equal(t, []string{"127.0.0.1:7000", "[::1]:7000"}, cfg.Addrs())
+	assert.Equal(t, "/tmp/redis-clone.sock", cfg.UnixSocket)
+	assert.Equal(t, fs.FileMode(0770), cfg.UnixSocketPerm)
+	assert.Equal(t, "/var/lib/redis clone/storage.store", cfg.AOFPath())
+	assert.Equal(t, storage.FsyncAlways, cfg.AppendFsync)
+	assert.Equal(t, 1024*1024, cfg.Limits.MaxBulkLen)
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr error
+	}{
+		{name: "unknown parameter", content: "port 7000\nmaxmemory 1gb\n", wantErr: ErrUnknownParam},
+		{name: "bad value", content: "appendfsync sometimes\n"},
+		{name: "bad permissions", content: "unixsocketperm 999\n"},
+		{name: "missing value", content: "port\n"},
+		{name: "unbalanced quotes", content: "dir \"/tmp\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			err := Load(&cfg, writeFile(t, tt.content))
+			require.Error(t, err)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	path := writeFile(t, "port 7000\nappendfsync no\n")
+
+	m, err := Parse("server", []string{"-config", path, "-port", "7001", "--bind", "127.0.0.1"}, io.Discard)
+	require.NoError(t, err)
+
+	cfg := m.Config()
+	assert.Equal(t, 7001, cfg.Port, "flags override the file")
+	assert.Equal(t, storage.FsyncNo, cfg.AppendFsync)
+	assert.Equal(t, []string{"127.0.0.1"}, cfg.Bind)
+	assert.Equal(t, path, m.Path())
+
+	_, err = Parse("server", []string{"-port", "70000"}, io.Discard)
+	assert.ErrorIs(t, err, ErrUsage)
+}
+
+func TestManagerGet(t *testing.T) {
+	m := NewManager(Default(), "")
+
+	assert.Equal(t, []string{"port", "6379"}, m.Get("port"))
+	assert.Equal(t, []string{"appendfilename", "storage.store", "appendfsync", "everysec"}, m.Get("append*"))
+	assert.Equal(t, []string{"port", "6379", "loglevel", "notice"}, m.Get("LOGLEVEL", "port"))
+	assert.Empty(t, m.Get("nothing*"))
+}
+
+func TestManagerSet(t *testing.T) {
+	m := NewManager(Default(), "")
+
+	require.NoError(t, m.Set("appendfsync", "ALWAYS", "proto-max-bulk-len", "2mb"))
+	assert.Equal(t, storage.FsyncAlways, m.Config().AppendFsync)
+	assert.Equal(t, 2*1024*1024, m.Config().Limits.MaxBulkLen)
+
+	require.NoError(t, m.Set("slowlog-log-slower-than", "-1", "slowlog-max-len", "0"))
+	assert.Equal(t, -time.Microsecond, m.Config().SlowlogLogSlowerThan)
+	slowerThan, maxLen := m.Slowlog()
+	assert.Equal(t, -time.Microsecond, slowerThan)
+	assert.Zero(t, maxLen)
+	assert.Equal(t, []string{"slowlog-log-slower-than", "-1"}, m.Get("slowlog-log-slower-than"))
+	assert.Error(t, m.Set("slowlog-log-slower-than", "-2"))
+
+	require.NoError(t, m.Set("timeout", "30"))
+	assert.Equal(t, 30*time.Second, m.Timeout())
+
+	var perr *ParamError
+
+	err := m.Set("port", "7000")
+	assert.ErrorIs(t, err, ErrImmutable)
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, "port", perr.Param)
+
+	assert.ErrorIs(t, m.Set("maxmemory", "1gb"), ErrUnknownParam)
+
+	// nothing changes when one of the parameters is bad
+	err = m.Set("loglevel", "debug", "appendfsync", "sometimes")
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, "appendfsync", perr.Param)
+	assert.Equal(t, "notice", m.Config().LogLevel)
+}
+
+func TestManagerRewrite(t *testing.T) {
+	path := writeFile(t, `# keep me
+port 7000
+appendfsync everysec
+appendfsync always
+`)
+
+	cfg := Default()
+	require.NoError(t, Load(&cfg, path))
+	m := NewManager(cfg, path)
+
+	// lines the server doesn't know, e.g. from a newer version, are kept
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data = []byte(strings.Replace(string(data), "port 7000\n", "port 7000\nunknown-directive yes\n", 1))
+	require.NoError(t, os.WriteFile(path, data, 0644))
+
+	require.NoError(t, m.Set("appendfsync", "no", "loglevel", "warning"))
+	require.NoError(t, m.Rewrite())
+
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `# keep me
+port 7000
+unknown-directive yes
+appendfsync no
+# Generated by CONFIG REWRITE
+loglevel warning
+`, string(data))
+
+	// rewriting again is stable
+	require.NoError(t, m.Rewrite())
+	again, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(again))
+
+	assert.ErrorIs(t, NewManager(Default(), "").Rewrite(), ErrNoConfigFile)
+}
+
+func TestFormatLine(t *testing.T) {
+	p, ok := lookup("unixsocket")
+	require.True(t, ok)
+
+	// every value reads back from its line unchanged
+	for _, value := range []string{
+		"/tmp/redis.sock", "", "a b", `C:\sock "x"`, "it's", "tab\there",
+		"new\nline", "\x00\xff", "é",
+	} {
+		cfg := Default()
+		cfg.UnixSocket = value
+
+		line := formatLine(p, &cfg)
+		assert.NotContains(t, line, "\n")
+		name, got, ok, err := parseLine(line)
+		require.NoError(t, err, line)
+		assert.True(t, ok)
+		assert.Equal(t, "unixsocket", name)
+		assert.Equal(t, value, got, line)
+	}
+
+	cfg := Default()
+	cfg.UnixSocket = "/tmp/redis.sock"
+	assert.Equal(t, "unixsocket /tmp/redis.sock", formatLine(p, &cfg))
+	cfg.UnixSocket = `a "b"`
+	assert.Equal(t, `unixsocket "a \"b\""`, formatLine(p, &cfg))
+}
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "100", want: 100},
+		{value: "1k", want: 1000},
+		{value: "1kb", want: 1024},
+		{value: "512MB", want: 512 * 1024 * 1024},
+		{value: "2g", want: 2000 * 1000 * 1000},
+		{value: "10b", want: 10},
+		{value: "lots", wantErr: true},
+		{value: "-1mb", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseMemory(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
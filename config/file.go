@@ -0,0 +1,169 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/helewud/redis-clone/resp"
+)
+
+// rewriteMarker starts the parameters CONFIG REWRITE appends to a file
+const rewriteMarker = "# Generated by CONFIG REWRITE"
+
+// apply sets any parameter, mutable or not, as done on startup
+func apply(cfg *Config, name, value string) error {
+	p, ok := lookup(name)
+	if !ok {
+		return &ParamError{Param: name, Err: ErrUnknownParam}
+	}
+	if err := p.set(cfg, value); err != nil {
+		return &ParamError{Param: p.name, Err: err}
+	}
+
+	return nil
+}
+
+// Load reads a redis.conf style file on top of cfg. Each line holds a
+// parameter name followed by its value, blank lines and lines starting
+// with # are ignored.
+func Load(cfg *Config, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		name, value, ok, err := parseLine(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		if !ok {
+			continue
+		}
+
+		if err := apply(cfg, name, value); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// parseLine splits a config line into a parameter name and its value. ok is
+// false for blank lines and comments.
+func parseLine(line string) (name, value string, ok bool, err error) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' {
+		return "", "", false, nil
+	}
+
+	args, err := resp.SplitArgs(line)
+	if err != nil {
+		return "", "", false, err
+	}
+	if len(args) < 2 {
+		return "", "", false, errors.New("wrong number of arguments")
+	}
+
+	return strings.ToLower(args[0]), strings.Join(args[1:], " "), true, nil
+}
+
+// formatLine writes a parameter back as a config line, quoted so that
+// parseLine reads the same value
+func formatLine(p param, cfg *Config) string {
+	value := p.get(cfg)
+	if !p.multi && resp.NeedsQuotes(value) {
+		value = resp.QuoteArg(value)
+	}
+
+	return p.name + " " + value
+}
+
+// Rewrite updates the config file with the current settings. Comments and
+// lines it doesn't know are kept, known parameters are rewritten in place
+// and the others are appended when they differ from their default.
+func (m *Manager) Rewrite() error {
+	if m.path == "" {
+		return ErrNoConfigFile
+	}
+
+	m.mu.RLock()
+	cfg := m.cfg
+	m.mu.RUnlock()
+
+	data, err := os.ReadFile(m.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	var (
+		out       bytes.Buffer
+		seen      = map[string]bool{}
+		hasMarker = false
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == rewriteMarker {
+			hasMarker = true
+		}
+
+		name, _, ok, err := parseLine(line)
+		p, known := lookup(name)
+		if err != nil || !ok || !known {
+			out.WriteString(line + "\n")
+			continue
+		}
+
+		// later occurrences of a parameter are dropped
+		if !seen[p.name] {
+			out.WriteString(formatLine(p, &cfg) + "\n")
+			seen[p.name] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	defaults := Default()
+	for _, p := range params {
+		if seen[p.name] || p.get(&cfg) == p.get(&defaults) {
+			continue
+		}
+
+		if !hasMarker {
+			out.WriteString(rewriteMarker + "\n")
+			hasMarker = true
+		}
+		out.WriteString(formatLine(p, &cfg) + "\n")
+	}
+
+	// replace the file at once so a crash never leaves half of it
+	tmp, err := os.CreateTemp(filepath.Dir(m.path), filepath.Base(m.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(out.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), m.path)
+}
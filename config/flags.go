@@ -0,0 +1,65 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+)
+
+// ErrUsage is returned by Parse for bad flags, after printing the usage
+var ErrUsage = errors.New("invalid command-line arguments")
+
+// Parse builds the settings from command-line arguments. The -config flag
+// names a file that is loaded over the defaults, then every parameter can
+// be overridden with a flag of the same name, e.g. -port 7000.
+func Parse(name string, args []string, output io.Writer) (*Manager, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(output)
+
+	path := fs.String("config", "", "redis.conf style `file` to load")
+
+	// flags are applied after the file, in the order they were given
+	var overrides []func(cfg *Config) error
+	defaults := Default()
+	for _, p := range params {
+		usage := fmt.Sprintf("%s (default %q)", p.name, p.get(&defaults))
+		fs.Func(p.name, usage, func(value string) error {
+			// validate right away so flag reports the bad flag
+			probe := defaults
+			if err := p.set(&probe, value); err != nil {
+				return err
+			}
+
+			overrides = append(overrides, func(cfg *Config) error {
+				return p.set(cfg, value)
+			})
+			return nil
+		})
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrUsage, err)
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	cfg := Default()
+	if *path != "" {
+		if err := Load(&cfg, *path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, override := range overrides {
+		if err := override(&cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewManager(cfg, *path), nil
+}
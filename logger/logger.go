@@ -0,0 +1,150 @@
+// Package logger sets up the structured server log. Lines are written with
+// log/slog as text or JSON, to stdout or to a file that can be reopened
+// after logrotate moved it, at the level set by loglevel.
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/helewud/redis-clone/config"
+)
+
+// The levels of redis.conf that slog has no name for
+const (
+	LevelVerbose = slog.LevelDebug + 2
+	LevelNothing = slog.LevelError + 4
+)
+
+var levels = map[string]slog.Level{
+	"debug":   slog.LevelDebug,
+	"verbose": LevelVerbose,
+	"notice":  slog.LevelInfo,
+	"warning": slog.LevelWarn,
+	"nothing": LevelNothing,
+}
+
+// level is shared by every logger New returns, so loglevel can change at
+// runtime
+var level slog.LevelVar
+
+// ParseLevel returns the slog level of a loglevel setting
+func ParseLevel(name string) (slog.Level, error) {
+	l, ok := levels[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown log level %q", name)
+	}
+
+	return l, nil
+}
+
+// SetLevel changes the level of the loggers
+func SetLevel(name string) error {
+	l, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.Set(l)
+
+	return nil
+}
+
+// New returns a logger writing to w in the given format, text or json
+func New(w io.Writer, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: &level, ReplaceAttr: replaceLevel}
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// replaceLevel names the verbose level, slog would print DEBUG+2
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if l, ok := a.Value.Any().(slog.Level); ok && l == LevelVerbose {
+			a.Value = slog.StringValue("VERBOSE")
+		}
+	}
+
+	return a
+}
+
+// Setup makes the logger configured by cfg the default one. It returns the
+// log file, nil when logging to stdout.
+func Setup(cfg config.Config) (*File, error) {
+	if err := SetLevel(cfg.LogLevel); err != nil {
+		return nil, err
+	}
+
+	var w io.Writer = os.Stdout
+	var file *File
+	if cfg.LogFile != "" {
+		var err error
+		if file, err = OpenFile(cfg.LogFile); err != nil {
+			return nil, err
+		}
+		w = file
+	}
+	slog.SetDefault(New(w, cfg.LogFormat))
+
+	return file, nil
+}
+
+// File is a log file that is reopened by path on Reopen, so lines go to a
+// new file once logrotate moved the old one away
+type File struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenFile opens path for appending, creating it if needed
+func OpenFile(path string) (*File, error) {
+	file, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{path: path, file: file}, nil
+}
+
+func openFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+}
+
+// Write appends a line to the file
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.file.Write(p)
+}
+
+// Reopen opens the path again. The old file stays in use when that fails.
+func (f *File) Reopen() error {
+	file, err := openFile(f.path)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	old := f.file
+	f.file = file
+	f.mu.Unlock()
+
+	return old.Close()
+}
+
+// Close closes the file
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.file.Close()
+}
@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/helewud/redis-clone/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		want slog.Level
+	}{
+		{name: "debug", want: slog.LevelDebug},
+		{name: "verbose", want: LevelVerbose},
+		{name: "NOTICE", want: slog.LevelInfo},
+		{name: "warning", want: slog.LevelWarn},
+		{name: "nothing", want: LevelNothing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.name)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := ParseLevel("loud")
+	assert.Error(t, err)
+}
+
+func TestLevel(t *testing.T) {
+	t.Cleanup(func() { SetLevel("notice") })
+
+	var buf bytes.Buffer
+	log := New(&buf, "text")
+
+	require.NoError(t, SetLevel("warning"))
+	log.Info("hidden")
+	assert.Empty(t, buf.String())
+
+	require.NoError(t, SetLevel("verbose"))
+	log.Log(context.Background(), LevelVerbose, "shown", "client", 7)
+	assert.Contains(t, buf.String(), "level=VERBOSE msg=shown client=7")
+
+	buf.Reset()
+	require.NoError(t, SetLevel("nothing"))
+	log.Error("hidden")
+	assert.Empty(t, buf.String())
+}
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, "json").Warn("rejected client", "client", 3, "cmd", "get")
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "WARN", line["level"])
+	assert.Equal(t, "rejected client", line["msg"])
+	assert.Equal(t, float64(3), line["client"])
+	assert.Equal(t, "get", line["cmd"])
+	assert.Contains(t, line, "time")
+}
+
+func TestFileReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "server.log")
+
+	file, err := OpenFile(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	log := New(file, "text")
+	log.Info("before")
+
+	// logrotate moves the file away, then signals the server
+	rotated := filepath.Join(dir, "server.log.1")
+	require.NoError(t, os.Rename(path, rotated))
+	log.Info("still old")
+	require.NoError(t, file.Reopen())
+	log.Info("after")
+
+	old, err := os.ReadFile(rotated)
+	require.NoError(t, err)
+	assert.Contains(t, string(old), "msg=before")
+	assert.Contains(t, string(old), `msg="still old"`)
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(current), "before")
+	assert.Contains(t, string(current), "msg=after")
+}
+
+func TestSetup(t *testing.T) {
+	defaultLogger := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(defaultLogger)
+		SetLevel("notice")
+	})
+
+	cfg := config.Default()
+	cfg.LogFile = filepath.Join(t.TempDir(), "server.log")
+	cfg.LogFormat = "json"
+	cfg.LogLevel = "warning"
+
+	file, err := Setup(cfg)
+	require.NoError(t, err)
+	defer file.Close()
+
+	slog.Info("hidden")
+	slog.Warn("shown")
+
+	data, err := os.ReadFile(cfg.LogFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "hidden")
+	assert.Contains(t, string(data), `"msg":"shown"`)
+}
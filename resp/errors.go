@@ -0,0 +1,94 @@
+package resp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrProtocol matches every ProtocolError
+	ErrProtocol = errors.New("protocol error")
+
+	// ErrUnexpectedEOF is the cause of a ProtocolError when the stream
+	// ends in the middle of a value. A stream ending between values is a
+	// clean disconnect and reported as io.EOF.
+	ErrUnexpectedEOF = errors.New("unexpected EOF")
+
+	// ErrInvalidLength is the cause of a ProtocolError when a length
+	// header is malformed, negative or over the limits of the reader
+	ErrInvalidLength = errors.New("invalid length")
+)
+
+// ProtocolError reports input that breaks the protocol or one of the
+// reader limits. The stream cannot be trusted after one, so servers should
+// reply and drop the connection.
+type ProtocolError struct {
+	Msg string
+
+	// Offset is the number of bytes consumed from the stream when the
+	// error was detected
+	Offset int64
+
+	// Err is the underlying cause, such as ErrUnexpectedEOF or
+	// ErrInvalidLength, and may be nil
+	Err error
+}
+
+func (e *ProtocolError) Error() string {
+	return "Protocol error: " + e.Msg
+}
+
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrProtocol or a ProtocolError with the same
+// message, so the exported error values can be compared with errors.Is
+func (e *ProtocolError) Is(target error) bool {
+	if target == ErrProtocol {
+		return true
+	}
+
+	t, ok := target.(*ProtocolError)
+	return ok && t.Msg == e.Msg
+}
+
+var (
+	ErrBulkTooLarge = &ProtocolError{Msg: "invalid bulk length", Err: ErrInvalidLength}
+	ErrArrayTooLong = &ProtocolError{Msg: "invalid multibulk length", Err: ErrInvalidLength}
+	ErrTooDeep      = &ProtocolError{Msg: "too many nested aggregates"}
+	ErrLineTooLong  = &ProtocolError{Msg: "too big inline request"}
+
+	errUnbalancedQuotes = &ProtocolError{Msg: "unbalanced quotes in request"}
+)
+
+// fail returns a copy of template stamped with the current offset
+func (r *Reader) fail(template *ProtocolError) error {
+	e := *template
+	e.Offset = r.offset
+	return &e
+}
+
+// failf returns a ProtocolError with the given cause at the current offset
+func (r *Reader) failf(cause error, format string, args ...any) error {
+	return &ProtocolError{
+		Msg:    fmt.Sprintf(format, args...),
+		Offset: r.offset,
+		Err:    cause,
+	}
+}
+
+// ioError classifies an error from the underlying stream. EOF is a clean
+// disconnect only if nothing of the current value has been consumed yet.
+func (r *Reader) ioError(err error) error {
+	if err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+
+	if err == io.EOF && r.offset == r.start {
+		return io.EOF
+	}
+
+	return r.failf(ErrUnexpectedEOF, "unexpected EOF")
+}
@@ -0,0 +1,232 @@
+package resp
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ReadCommand reads a client request. Requests are either RESP arrays or
+// inline commands, plain lines of space separated arguments as typed into
+// telnet or netcat. Inline commands are returned as an array of bulk
+// strings so callers can treat both forms alike.
+func (r *Reader) ReadCommand() (Value, error) {
+	r.start = r.offset
+
+	symbol, err := r.reader.Peek(1)
+	if err != nil {
+		return Value{}, r.ioError(err)
+	}
+
+	if Symbol(symbol) == RespArray {
+		return r.Read()
+	}
+
+	*r.arena = (*r.arena)[:0]
+
+	return r.readInline()
+}
+
+func (r *Reader) readInline() (Value, error) {
+	v := Value{T: RespTArray}
+
+	// inline commands may end with a bare LF
+	line, err := r.readRawLine()
+	if err != nil {
+		return v, err
+	}
+	line = bytes.TrimSuffix(line[:len(line)-1], []byte("\r"))
+
+	args, perr := splitArgs(line)
+	if perr != nil {
+		return v, r.fail(perr)
+	}
+
+	for _, arg := range args {
+		v.Array = append(v.Array, Value{T: RespTBulk, Bulk: arg})
+	}
+
+	return v, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// splitArgs splits an inline command into arguments the same way redis-cli
+// does. Double quoted arguments understand the usual backslash escapes,
+// including \xHH, while single quoted arguments only unescape \'.
+func splitArgs(line []byte) ([][]byte, *ProtocolError) {
+	var args [][]byte
+
+	i := 0
+	for {
+		// skip blanks
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return args, nil
+		}
+
+		var (
+			current  []byte
+			inDouble bool
+			inSingle bool
+			done     bool
+		)
+
+		for !done {
+			switch {
+			case inDouble:
+				if i == len(line) {
+					return nil, errUnbalancedQuotes
+				}
+
+				switch {
+				case line[i] == '\\' && i+3 < len(line) && line[i+1] == 'x' &&
+					isHexDigit(line[i+2]) && isHexDigit(line[i+3]):
+					b, _ := strconv.ParseUint(string(line[i+2:i+4]), 16, 8)
+					current = append(current, byte(b))
+					i += 3
+				case line[i] == '\\' && i+1 < len(line):
+					i++
+					switch line[i] {
+					case 'n':
+						current = append(current, '\n')
+					case 'r':
+						current = append(current, '\r')
+					case 't':
+						current = append(current, '\t')
+					case 'b':
+						current = append(current, '\b')
+					case 'a':
+						current = append(current, '\a')
+					default:
+						current = append(current, line[i])
+					}
+				case line[i] == '"':
+					// closing quote must be followed by a space or nothing
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, errUnbalancedQuotes
+					}
+					done = true
+				default:
+					current = append(current, line[i])
+				}
+			case inSingle:
+				if i == len(line) {
+					return nil, errUnbalancedQuotes
+				}
+
+				switch {
+				case line[i] == '\\' && i+1 < len(line) && line[i+1] == '\'':
+					i++
+					current = append(current, '\'')
+				case line[i] == '\'':
+					// closing quote must be followed by a space or nothing
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, errUnbalancedQuotes
+					}
+					done = true
+				default:
+					current = append(current, line[i])
+				}
+			default:
+				if i == len(line) {
+					done = true
+					continue
+				}
+
+				switch line[i] {
+				case ' ', '\t', '\n', '\r', '\v', '\f':
+					done = true
+				case '"':
+					inDouble = true
+				case '\'':
+					inSingle = true
+				default:
+					current = append(current, line[i])
+				}
+			}
+
+			if i < len(line) {
+				i++
+			}
+		}
+
+		if current == nil {
+			current = []byte{}
+		}
+		args = append(args, current)
+	}
+}
+
+// SplitArgs splits line into arguments with the quoting rules of inline
+// commands, which redis.conf files follow too
+func SplitArgs(line string) ([]string, error) {
+	args, perr := splitArgs([]byte(line))
+	if perr != nil {
+		return nil, perr
+	}
+
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		strs[i] = string(arg)
+	}
+
+	return strs, nil
+}
+
+// NeedsQuotes reports whether s would not read back as is from an
+// unquoted argument
+func NeedsQuotes(s string) bool {
+	if s == "" {
+		return true
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c <= ' ' || c > '~' || c == '"' || c == '\'' || c == '\\' {
+			return true
+		}
+	}
+
+	return false
+}
+
+// QuoteArg quotes s the way Redis prints arguments to monitors, which
+// SplitArgs reads back as a single argument
+func QuoteArg(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '"':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\a':
+			b.WriteString(`\a`)
+		case '\b':
+			b.WriteString(`\b`)
+		default:
+			if c >= ' ' && c <= '~' {
+				b.WriteByte(c)
+			} else {
+				fmt.Fprintf(&b, `\x%02x`, c)
+			}
+		}
+	}
+	b.WriteByte('"')
+
+	return b.String()
+}
@@ -0,0 +1,175 @@
+package resp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "single word",
+			input: "PING",
+			want:  []string{"PING"},
+		},
+		{
+			name:  "extra whitespace",
+			input: "  SET \t key   value ",
+			want:  []string{"SET", "key", "value"},
+		},
+		{
+			name:  "empty line",
+			input: "",
+			want:  nil,
+		},
+		{
+			name:  "double quotes",
+			input: `SET key "hello world"`,
+			want:  []string{"SET", "key", "hello world"},
+		},
+		{
+			name:  "double quote escapes",
+			input: `SET key "a\nb\t\"c\"\\\x41"`,
+			want:  []string{"SET", "key", "a\nb\t\"c\"\\A"},
+		},
+		{
+			name:  "single quotes",
+			input: `SET key 'it\'s "raw"\n'`,
+			want:  []string{"SET", "key", `it's "raw"\n`},
+		},
+		{
+			name:  "empty quoted argument",
+			input: `SET key ""`,
+			want:  []string{"SET", "key", ""},
+		},
+		{
+			name:    "unbalanced double quotes",
+			input:   `SET key "hello`,
+			wantErr: true,
+		},
+		{
+			name:    "unbalanced single quotes",
+			input:   `SET key 'hello`,
+			wantErr: true,
+		},
+		{
+			name:    "closing quote followed by text",
+			input:   `SET key "hello"world`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := splitArgs([]byte(tt.input))
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("splitArgs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			var got []string
+			for _, arg := range args {
+				got = append(got, string(arg))
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuoteArg(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{arg: "SET", want: `"SET"`},
+		{arg: "", want: `""`},
+		{arg: "a b", want: `"a b"`},
+		{arg: `say "hi" \o/`, want: `"say \"hi\" \\o/"`},
+		{arg: "line\r\n\ttab\a\b", want: `"line\r\n\ttab\a\b"`},
+		{arg: "\x00\x7f\xff", want: `"\x00\x7f\xff"`},
+	}
+
+	for _, tt := range tests {
+		got := QuoteArg(tt.arg)
+		if got != tt.want {
+			t.Errorf("QuoteArg(%q) = %s, want %s", tt.arg, got, tt.want)
+		}
+
+		// the quoted argument reads back unchanged
+		args, err := SplitArgs(got)
+		if err != nil || len(args) != 1 || args[0] != tt.arg {
+			t.Errorf("SplitArgs(%s) = %q, %v, want %q", got, args, err, tt.arg)
+		}
+	}
+}
+
+func TestReader_ReadCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Value
+		wantErr bool
+	}{
+		{
+			name:  "resp array",
+			input: "*1\r\n$4\r\nPING\r\n",
+			want: Value{
+				T:     RespTArray,
+				Array: []Value{{T: RespTBulk, Bulk: []byte("PING")}},
+			},
+		},
+		{
+			name:  "inline with CRLF",
+			input: "GET key\r\n",
+			want: Value{
+				T: RespTArray,
+				Array: []Value{
+					{T: RespTBulk, Bulk: []byte("GET")},
+					{T: RespTBulk, Bulk: []byte("key")},
+				},
+			},
+		},
+		{
+			name:  "inline with LF",
+			input: "PING\n",
+			want: Value{
+				T:     RespTArray,
+				Array: []Value{{T: RespTBulk, Bulk: []byte("PING")}},
+			},
+		},
+		{
+			name:    "inline without newline",
+			input:   "PING",
+			wantErr: true,
+		},
+		{
+			name:    "empty input",
+			input:   "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(tt.input))
+			got, err := r.ReadCommand()
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadCommand() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadCommand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
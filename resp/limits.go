@@ -0,0 +1,32 @@
+package resp
+
+// Limits bound what a Reader accepts from a peer, so a single client cannot
+// make it allocate without bound or recurse until the stack overflows
+type Limits struct {
+	// MaxBulkLen is the largest bulk string accepted, like Redis's
+	// proto-max-bulk-len
+	MaxBulkLen int
+
+	// MaxArrayLen is the largest number of elements in an aggregate
+	MaxArrayLen int
+
+	// MaxDepth is how deep aggregates may be nested
+	MaxDepth int
+
+	// MaxLineLen is the longest line accepted, which covers inline
+	// commands, simple strings and length headers
+	MaxLineLen int
+}
+
+// DefaultLimits are the limits of a new Reader
+var DefaultLimits = Limits{
+	MaxBulkLen:  512 * 1024 * 1024,
+	MaxArrayLen: 1024 * 1024,
+	MaxDepth:    64,
+	MaxLineLen:  64 * 1024,
+}
+
+// SetLimits replaces the limits of the reader
+func (r *Reader) SetLimits(limits Limits) {
+	r.limits = limits
+}
@@ -1,61 +1,203 @@
 package resp
 
 import (
-	"bytes"
+	"math"
 	"strconv"
 )
 
-func (v Value) marshalString() []byte {
-	var buffer bytes.Buffer
-	buffer.WriteString(string(RespString))
-	buffer.WriteString(v.String)
-	return appendEndOfLine(buffer.Bytes())
+func (v Value) appendString(dst []byte) []byte {
+	dst = append(dst, RespString...)
+	dst = append(dst, v.String...)
+	return appendEndOfLine(dst)
 }
 
-func (v Value) marshalBulk() []byte {
-	var buffer bytes.Buffer
-	buffer.WriteString(string(RespBulk))
-	buffer.WriteString(strconv.Itoa(len(v.Bulk)))
-	buffer.WriteString("\r\n")
-	buffer.WriteString(v.Bulk)
-	return appendEndOfLine(buffer.Bytes())
+func appendBulk(dst []byte, bulk []byte) []byte {
+	dst = append(dst, RespBulk...)
+	dst = strconv.AppendInt(dst, int64(len(bulk)), 10)
+	dst = appendEndOfLine(dst)
+	dst = append(dst, bulk...)
+	return appendEndOfLine(dst)
 }
 
-func (v Value) marshalArray() []byte {
-	var buffer bytes.Buffer
-	buffer.WriteString(string(RespArray))
-	buffer.WriteString(strconv.Itoa(len(v.Array)))
-	buffer.WriteString("\r\n")
+func appendBulkString(dst []byte, s string) []byte {
+	dst = append(dst, RespBulk...)
+	dst = strconv.AppendInt(dst, int64(len(s)), 10)
+	dst = appendEndOfLine(dst)
+	dst = append(dst, s...)
+	return appendEndOfLine(dst)
+}
+
+func (v Value) appendBulk(dst []byte) []byte {
+	return appendBulk(dst, v.Bulk)
+}
+
+func (v Value) appendAggregate(dst []byte, symbol Symbol, length int, p Protocol) []byte {
+	dst = append(dst, symbol...)
+	dst = strconv.AppendInt(dst, int64(length), 10)
+	dst = appendEndOfLine(dst)
 	for _, item := range v.Array {
-		buffer.Write(item.Marshal())
+		dst = item.AppendProto(dst, p)
+	}
+	for _, str := range v.Strings {
+		dst = appendBulkString(dst, str)
+	}
+	return dst
+}
+
+func (v Value) appendError(dst []byte) []byte {
+	dst = append(dst, RespError...)
+	dst = append(dst, v.String...)
+	return appendEndOfLine(dst)
+}
+
+func (v Value) appendNull(dst []byte) []byte {
+	return append(dst, RespNnull...)
+}
+
+func appendInteger(dst []byte, n int) []byte {
+	dst = append(dst, RespInteger...)
+	dst = strconv.AppendInt(dst, int64(n), 10)
+	return appendEndOfLine(dst)
+}
+
+// appendDouble renders a float the way RESP3 expects it, spelling out
+// infinities and NaN
+func appendDouble(dst []byte, f float64) []byte {
+	switch {
+	case math.IsInf(f, 1):
+		return append(dst, "inf"...)
+	case math.IsInf(f, -1):
+		return append(dst, "-inf"...)
+	case math.IsNaN(f):
+		return append(dst, "nan"...)
+	default:
+		return strconv.AppendFloat(dst, f, 'g', -1, 64)
+	}
+}
+
+func (v Value) appendDouble(dst []byte) []byte {
+	dst = append(dst, RespDouble...)
+	dst = appendDouble(dst, v.Double)
+	return appendEndOfLine(dst)
+}
+
+func (v Value) appendBoolean(dst []byte) []byte {
+	if v.Boolean {
+		return append(dst, "#t\r\n"...)
 	}
-	return buffer.Bytes()
+	return append(dst, "#f\r\n"...)
 }
 
-func (v Value) marshalError() []byte {
-	var buffer bytes.Buffer
-	buffer.WriteString(string(RespError))
-	buffer.WriteString(v.String)
-	return appendEndOfLine(buffer.Bytes())
+func (v Value) appendBigNumber(dst []byte) []byte {
+	dst = append(dst, RespBigNumber...)
+	dst = append(dst, v.String...)
+	return appendEndOfLine(dst)
+}
+
+func (v Value) verbatimFormat() string {
+	if len(v.Format) != 3 {
+		return "txt"
+	}
+	return v.Format
 }
 
-func (v Value) marshalNull() []byte {
-	return []byte(RespNnull)
+func (v Value) appendVerbatim(dst []byte) []byte {
+	dst = append(dst, RespVerbatim...)
+	dst = strconv.AppendInt(dst, int64(len(v.Bulk)+4), 10)
+	dst = appendEndOfLine(dst)
+	dst = append(dst, v.verbatimFormat()...)
+	dst = append(dst, ':')
+	dst = append(dst, v.Bulk...)
+	return appendEndOfLine(dst)
+}
+
+// appendDowngraded encodes a RESP3-only value as the RESP2 type a RESP2
+// client expects in its place, the same way Redis does
+func (v Value) appendDowngraded(dst []byte) []byte {
+	switch v.T {
+	case RespTMap, RespTSet, RespTPush:
+		return v.appendAggregate(dst, RespArray, v.items(), Resp2)
+	case RespTDouble:
+		var scratch [32]byte
+		return appendBulk(dst, appendDouble(scratch[:0], v.Double))
+	case RespTBoolean:
+		if v.Boolean {
+			return appendInteger(dst, 1)
+		}
+		return appendInteger(dst, 0)
+	case RespTBigNumber:
+		dst = append(dst, RespBulk...)
+		dst = strconv.AppendInt(dst, int64(len(v.String)), 10)
+		dst = appendEndOfLine(dst)
+		dst = append(dst, v.String...)
+		return appendEndOfLine(dst)
+	case RespTVerbatim:
+		return v.appendBulk(dst)
+	default:
+		return v.appendNull(dst)
+	}
 }
 
+// Marshal encodes the value using RESP2
 func (v Value) Marshal() []byte {
+	return v.AppendProto(nil, Resp2)
+}
+
+// MarshalProto encodes the value for a client speaking protocol p. RESP3
+// types are downgraded to their RESP2 counterparts when p is Resp2.
+func (v Value) MarshalProto(p Protocol) []byte {
+	return v.AppendProto(nil, p)
+}
+
+// Append appends the RESP2 encoding of the value to dst and returns the
+// extended buffer
+func (v Value) Append(dst []byte) []byte {
+	return v.AppendProto(dst, Resp2)
+}
+
+// AppendProto appends the encoding of the value for protocol p to dst and
+// returns the extended buffer. Reusing dst across calls avoids allocating
+// on every reply.
+func (v Value) AppendProto(dst []byte, p Protocol) []byte {
+	if p != Resp3 {
+		switch v.T {
+		case RespTMap, RespTSet, RespTPush, RespTDouble, RespTBoolean,
+			RespTBigNumber, RespTVerbatim, RespTNull:
+			return v.appendDowngraded(dst)
+		}
+	}
+
 	switch v.T {
 	case RespTArray:
-		return v.marshalArray()
+		return v.appendAggregate(dst, RespArray, v.items(), p)
 	case RespTBulk:
-		return v.marshalBulk()
+		return v.appendBulk(dst)
 	case RespTNull:
-		return v.marshalNull()
+		return append(dst, "_\r\n"...)
 	case RespTError:
-		return v.marshalError()
+		return v.appendError(dst)
 	case RespTString:
-		return v.marshalString()
+		return v.appendString(dst)
+	case RespTInteger:
+		return appendInteger(dst, v.Number)
+	case RespTMap:
+		return v.appendAggregate(dst, RespMap, v.items()/2, p)
+	case RespTSet:
+		return v.appendAggregate(dst, RespSet, v.items(), p)
+	case RespTPush:
+		return v.appendAggregate(dst, RespPush, v.items(), p)
+	case RespTDouble:
+		return v.appendDouble(dst)
+	case RespTBoolean:
+		return v.appendBoolean(dst)
+	case RespTBigNumber:
+		return v.appendBigNumber(dst)
+	case RespTVerbatim:
+		return v.appendVerbatim(dst)
 	default:
-		return []byte{}
+		if dst == nil {
+			return []byte{}
+		}
+		return dst
 	}
 }
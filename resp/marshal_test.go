@@ -2,6 +2,7 @@ package resp
 
 import (
 	"bytes"
+	"math"
 	"testing"
 )
 
@@ -23,7 +24,7 @@ func TestValue_Marshal(t *testing.T) {
 			name: "bulk string",
 			v: Value{
 				T:    RespTBulk,
-				Bulk: "hello",
+				Bulk: []byte("hello"),
 			},
 			want: []byte("$5\r\nhello\r\n"),
 		},
@@ -31,7 +32,7 @@ func TestValue_Marshal(t *testing.T) {
 			name: "empty bulk string",
 			v: Value{
 				T:    RespTBulk,
-				Bulk: "",
+				Bulk: []byte(""),
 			},
 			want: []byte("$0\r\n\r\n"),
 		},
@@ -91,7 +92,7 @@ func TestValue_Marshal(t *testing.T) {
 				T: RespTArray,
 				Array: []Value{
 					{T: RespTString, String: "hello"},
-					{T: RespTBulk, Bulk: "world"},
+					{T: RespTBulk, Bulk: []byte("world")},
 					{T: RespTNull},
 					{T: RespTError, String: "test error"},
 				},
@@ -117,7 +118,7 @@ func TestValue_Marshal(t *testing.T) {
 	}
 }
 
-func TestValue_marshalString(t *testing.T) {
+func TestValue_appendString(t *testing.T) {
 	tests := []struct {
 		name string
 		v    Value
@@ -148,15 +149,15 @@ func TestValue_marshalString(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.v.T = RespTString
-			got := tt.v.marshalString()
+			got := tt.v.appendString(nil)
 			if !bytes.Equal(got, tt.want) {
-				t.Errorf("marshalString() = %q, want %q", got, tt.want)
+				t.Errorf("appendString(nil) = %q, want %q", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestValue_marshalBulk(t *testing.T) {
+func TestValue_appendBulk(t *testing.T) {
 	tests := []struct {
 		name string
 		v    Value
@@ -164,22 +165,22 @@ func TestValue_marshalBulk(t *testing.T) {
 	}{
 		{
 			name: "simple bulk",
-			v:    Value{Bulk: "hello"},
+			v:    Value{Bulk: []byte("hello")},
 			want: []byte("$5\r\nhello\r\n"),
 		},
 		{
 			name: "empty bulk",
-			v:    Value{Bulk: ""},
+			v:    Value{Bulk: []byte("")},
 			want: []byte("$0\r\n\r\n"),
 		},
 		{
 			name: "bulk with spaces",
-			v:    Value{Bulk: "hello world"},
+			v:    Value{Bulk: []byte("hello world")},
 			want: []byte("$11\r\nhello world\r\n"),
 		},
 		{
 			name: "bulk with special chars",
-			v:    Value{Bulk: "hello\nworld"},
+			v:    Value{Bulk: []byte("hello\nworld")},
 			want: []byte("$11\r\nhello\nworld\r\n"),
 		},
 	}
@@ -187,15 +188,15 @@ func TestValue_marshalBulk(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.v.T = RespTBulk
-			got := tt.v.marshalBulk()
+			got := tt.v.appendBulk(nil)
 			if !bytes.Equal(got, tt.want) {
-				t.Errorf("marshalBulk() = %q, want %q", got, tt.want)
+				t.Errorf("appendBulk(nil) = %q, want %q", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestValue_marshalError(t *testing.T) {
+func TestValue_appendError(t *testing.T) {
 	tests := []struct {
 		name string
 		v    Value
@@ -221,20 +222,20 @@ func TestValue_marshalError(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.v.T = RespTError
-			got := tt.v.marshalError()
+			got := tt.v.appendError(nil)
 			if !bytes.Equal(got, tt.want) {
-				t.Errorf("marshalError() = %q, want %q", got, tt.want)
+				t.Errorf("appendError(nil) = %q, want %q", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestValue_marshalNull(t *testing.T) {
+func TestValue_appendNull(t *testing.T) {
 	v := Value{T: RespTNull}
 	want := []byte("$-1\r\n")
-	got := v.marshalNull()
+	got := v.appendNull(nil)
 	if !bytes.Equal(got, want) {
-		t.Errorf("marshalNull() = %q, want %q", got, want)
+		t.Errorf("appendNull(nil) = %q, want %q", got, want)
 	}
 }
 
@@ -244,14 +245,151 @@ func BenchmarkValue_Marshal(b *testing.B) {
 		T: RespTArray,
 		Array: []Value{
 			{T: RespTString, String: "hello"},
-			{T: RespTBulk, Bulk: "world"},
+			{T: RespTBulk, Bulk: []byte("world")},
 			{T: RespTNull},
 			{T: RespTError, String: "test error"},
 		},
 	}
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		v.Marshal()
 	}
 }
+
+func BenchmarkValue_Append(b *testing.B) {
+	v := Value{
+		T: RespTArray,
+		Array: []Value{
+			{T: RespTString, String: "hello"},
+			{T: RespTBulk, Bulk: []byte("world")},
+			{T: RespTNull},
+			{T: RespTError, String: "test error"},
+		},
+	}
+
+	var buf []byte
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf = v.Append(buf[:0])
+	}
+}
+
+func TestValue_MarshalProto(t *testing.T) {
+	tests := []struct {
+		name  string
+		v     Value
+		want2 []byte
+		want3 []byte
+	}{
+		{
+			name:  "integer",
+			v:     Value{T: RespTInteger, Number: -42},
+			want2: []byte(":-42\r\n"),
+			want3: []byte(":-42\r\n"),
+		},
+		{
+			name:  "null",
+			v:     Value{T: RespTNull},
+			want2: []byte("$-1\r\n"),
+			want3: []byte("_\r\n"),
+		},
+		{
+			name: "map",
+			v: Value{
+				T: RespTMap,
+				Array: []Value{
+					{T: RespTBulk, Bulk: []byte("field")},
+					{T: RespTBulk, Bulk: []byte("value")},
+				},
+			},
+			want2: []byte("*2\r\n$5\r\nfield\r\n$5\r\nvalue\r\n"),
+			want3: []byte("%1\r\n$5\r\nfield\r\n$5\r\nvalue\r\n"),
+		},
+		{
+			name: "set",
+			v: Value{
+				T:     RespTSet,
+				Array: []Value{{T: RespTBulk, Bulk: []byte("a")}},
+			},
+			want2: []byte("*1\r\n$1\r\na\r\n"),
+			want3: []byte("~1\r\n$1\r\na\r\n"),
+		},
+		{
+			name: "push",
+			v: Value{
+				T:     RespTPush,
+				Array: []Value{{T: RespTBulk, Bulk: []byte("message")}},
+			},
+			want2: []byte("*1\r\n$7\r\nmessage\r\n"),
+			want3: []byte(">1\r\n$7\r\nmessage\r\n"),
+		},
+		{
+			name:  "double",
+			v:     Value{T: RespTDouble, Double: 3.5},
+			want2: []byte("$3\r\n3.5\r\n"),
+			want3: []byte(",3.5\r\n"),
+		},
+		{
+			name:  "negative infinity",
+			v:     Value{T: RespTDouble, Double: math.Inf(-1)},
+			want2: []byte("$4\r\n-inf\r\n"),
+			want3: []byte(",-inf\r\n"),
+		},
+		{
+			name:  "boolean true",
+			v:     Value{T: RespTBoolean, Boolean: true},
+			want2: []byte(":1\r\n"),
+			want3: []byte("#t\r\n"),
+		},
+		{
+			name:  "boolean false",
+			v:     Value{T: RespTBoolean},
+			want2: []byte(":0\r\n"),
+			want3: []byte("#f\r\n"),
+		},
+		{
+			name:  "big number",
+			v:     Value{T: RespTBigNumber, String: "3492890328409238509324850943850943825024385"},
+			want2: []byte("$43\r\n3492890328409238509324850943850943825024385\r\n"),
+			want3: []byte("(3492890328409238509324850943850943825024385\r\n"),
+		},
+		{
+			name:  "verbatim string",
+			v:     Value{T: RespTVerbatim, Format: "txt", Bulk: []byte("Some string")},
+			want2: []byte("$11\r\nSome string\r\n"),
+			want3: []byte("=15\r\ntxt:Some string\r\n"),
+		},
+		{
+			name: "nested map in array",
+			v: Value{
+				T: RespTArray,
+				Array: []Value{
+					{
+						T: RespTMap,
+						Array: []Value{
+							{T: RespTString, String: "ok"},
+							{T: RespTBoolean, Boolean: true},
+						},
+					},
+				},
+			},
+			want2: []byte("*1\r\n*2\r\n+ok\r\n:1\r\n"),
+			want3: []byte("*1\r\n%1\r\n+ok\r\n#t\r\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.v.MarshalProto(Resp2); !bytes.Equal(got, tt.want2) {
+				t.Errorf("MarshalProto(Resp2) = %q, want %q", got, tt.want2)
+			}
+			if got := tt.v.MarshalProto(Resp3); !bytes.Equal(got, tt.want3) {
+				t.Errorf("MarshalProto(Resp3) = %q, want %q", got, tt.want3)
+			}
+		})
+	}
+}
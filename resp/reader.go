@@ -4,31 +4,168 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"math/big"
+	"slices"
 	"strconv"
+	"sync"
 )
 
+const (
+	// readBufferSize is the size of the pooled buffered readers
+	readBufferSize = 16 * 1024
+
+	// maxPooledArena is the largest payload arena kept for reuse, bigger
+	// ones are left to the garbage collector
+	maxPooledArena = 1024 * 1024
+
+	// payloadChunk is how much of a large payload is read at once. The
+	// arena grows as data arrives instead of trusting the announced length.
+	payloadChunk = 64 * 1024
+)
+
+var readerPool = sync.Pool{
+	New: func() any { return bufio.NewReaderSize(nil, readBufferSize) },
+}
+
+var arenaPool = sync.Pool{
+	New: func() any { return new([]byte) },
+}
+
+// Reader decodes RESP values from a stream.
+//
+// Bulk payloads are not copied out one by one: they are sliced from an arena
+// the reader reuses, so a value is only valid until the next call to Read
+// or ReadCommand. Use Value.Clone to keep one around for longer.
 type Reader struct {
-	reader *bufio.Reader
+	reader  *bufio.Reader
+	arena   *[]byte
+	scratch []byte
+	limits  Limits
+	depth   int
+
+	// offset counts the bytes consumed so far, start is the offset at
+	// which the value being decoded began
+	offset int64
+	start  int64
 }
 
 func NewReader(rd io.Reader) *Reader {
-	return &Reader{reader: bufio.NewReader(rd)}
+	reader := readerPool.Get().(*bufio.Reader)
+	reader.Reset(rd)
+
+	return &Reader{
+		reader: reader,
+		arena:  arenaPool.Get().(*[]byte),
+		limits: DefaultLimits,
+	}
 }
 
-func (r *Reader) readLine() (line []byte, charCount int, err error) {
-	for {
-		char, err := r.reader.ReadByte()
-		if err != nil {
-			return nil, 0, err
+// Release hands the buffers of the reader back to the pool. Neither the
+// reader nor any value it returned may be used afterwards.
+func (r *Reader) Release() {
+	if r.reader == nil {
+		return
+	}
+
+	r.reader.Reset(nil)
+	readerPool.Put(r.reader)
+	r.reader = nil
+
+	if cap(*r.arena) <= maxPooledArena {
+		*r.arena = (*r.arena)[:0]
+		arenaPool.Put(r.arena)
+	}
+	r.arena = nil
+}
+
+// Offset returns the number of bytes consumed from the stream so far. Read
+// at a value boundary, it is the position of the next value.
+func (r *Reader) Offset() int64 {
+	return r.offset
+}
+
+// Buffered returns the number of bytes that have been received but not
+// decoded yet
+func (r *Reader) Buffered() int {
+	return r.reader.Buffered()
+}
+
+// readRawLine returns the next line including its LF terminator. The line
+// aliases the read buffer, so it is only valid until the next read.
+func (r *Reader) readRawLine() ([]byte, error) {
+	line, err := r.reader.ReadSlice('\n')
+	r.offset += int64(len(line))
+	if err == bufio.ErrBufferFull {
+		// line is longer than the read buffer, stitch it together
+		r.scratch = append(r.scratch[:0], line...)
+		for err == bufio.ErrBufferFull {
+			if len(r.scratch) > r.limits.MaxLineLen {
+				return nil, r.fail(ErrLineTooLong)
+			}
+			line, err = r.reader.ReadSlice('\n')
+			r.offset += int64(len(line))
+			r.scratch = append(r.scratch, line...)
 		}
-		charCount++
+		line = r.scratch
+	}
+	if err != nil {
+		return nil, r.ioError(err)
+	}
+
+	if len(line) > r.limits.MaxLineLen {
+		return nil, r.fail(ErrLineTooLong)
+	}
+
+	return line, nil
+}
+
+func (r *Reader) readLine() (line []byte, charCount int, err error) {
+	line, err = r.readRawLine()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !isEndOfLine(line) {
+		return nil, 0, r.failf(nil, "expected CRLF line ending")
+	}
+
+	return trimEndOfLine(line), len(line), nil
+}
+
+// parseInt parses a base 10 integer without going through a string
+func parseInt(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, fmt.Errorf("invalid integer: %q", b)
+	}
+
+	neg := false
+	digits := b
+	switch b[0] {
+	case '-':
+		neg = true
+		digits = b[1:]
+	case '+':
+		digits = b[1:]
+	}
 
-		line = append(line, char)
+	// fall back to strconv for anything that could overflow
+	if len(digits) == 0 || len(digits) > 18 {
+		n, err := strconv.ParseInt(string(b), 10, 64)
+		return int(n), err
+	}
 
-		if isEndOfLine(line) {
-			return trimEndOfLine(line), charCount, nil
+	n := 0
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("invalid integer: %q", b)
 		}
+		n = n*10 + int(c-'0')
+	}
+
+	if neg {
+		return -n, nil
 	}
+	return n, nil
 }
 
 func (r *Reader) parseInteger() (value int, err error) {
@@ -37,26 +174,67 @@ func (r *Reader) parseInteger() (value int, err error) {
 		return 0, err
 	}
 
-	int64Val, err := strconv.ParseInt(string(line), 10, 64)
+	value, err = parseInt(line)
+	if err != nil {
+		return 0, r.failf(nil, "invalid integer")
+	}
+
+	return value, nil
+}
+
+// readLength reads a length header, where -1 announces a null. Anything
+// else that is not a valid length is reported as invalid with msg.
+func (r *Reader) readLength(msg string) (int, error) {
+	line, _, err := r.readLine()
 	if err != nil {
 		return 0, err
 	}
 
-	return int(int64Val), nil
+	n, err := parseInt(line)
+	if err != nil || n < -1 {
+		return 0, r.failf(ErrInvalidLength, msg)
+	}
+
+	return n, nil
 }
 
 func (r *Reader) readArray() (Value, error) {
-	v := Value{T: RespTArray}
+	return r.readAggregate(RespTArray, 1)
+}
+
+// readAggregate reads a length-prefixed aggregate of type t. Each counted
+// element is made of width values, which is 2 for maps and 1 otherwise.
+func (r *Reader) readAggregate(t Type, width int) (Value, error) {
+	v := Value{T: t}
 
-	// get array length
-	len, err := r.parseInteger()
+	// get aggregate length
+	len, err := r.readLength(ErrArrayTooLong.Msg)
 	if err != nil {
 		return v, err
 	}
 
+	// a length of -1 is the RESP2 null array
+	if len == -1 {
+		return Value{T: RespTNull}, nil
+	}
+	if len > r.limits.MaxArrayLen {
+		return v, r.fail(ErrArrayTooLong)
+	}
+
+	r.depth++
+	defer func() { r.depth-- }()
+	if r.depth > r.limits.MaxDepth {
+		return v, r.fail(ErrTooDeep)
+	}
+
+	// the announced length is not trusted for preallocation
+	if len > 0 {
+		v.Array = make([]Value, 0, min(len*width, 1024))
+	}
+
 	// foreach line, parse and read the value
-	for i := 0; i < len; i++ {
-		val, err := r.Read()
+	for i := 0; i < len*width; i++ {
+		val, err := r.read()
 		if err != nil {
 			return v, err
 		}
@@ -74,36 +252,238 @@ func (r *Reader) readBulk() (Value, error) {
 	}
 
 	// get bulk char length
-	len, err := r.parseInteger()
+	len, err := r.readLength(ErrBulkTooLarge.Msg)
 	if err != nil {
 		return v, err
 	}
 
-	bulk := make([]byte, len)
+	// a length of -1 is the RESP2 null bulk string
+	if len == -1 {
+		return Value{T: RespTNull}, nil
+	}
+	if len > r.limits.MaxBulkLen {
+		return v, r.fail(ErrBulkTooLarge)
+	}
+
+	if len == 0 {
+		v.Bulk = []byte{}
+	} else {
+		v.Bulk, err = r.readPayload(len)
+		if err != nil {
+			return v, err
+		}
+	}
 
-	r.reader.Read(bulk)
+	// the payload must be followed by CRLF
+	crlf, err := r.reader.Peek(2)
+	if err != nil {
+		return v, r.ioError(err)
+	}
+	if crlf[0] != '\r' || crlf[1] != '\n' {
+		return v, r.failf(nil, "bulk string not terminated by CRLF")
+	}
+	r.reader.Discard(2)
+	r.offset += 2
 
-	v.Bulk = string(bulk)
+	return v, nil
+}
 
-	// remove CRLF from line
-	r.readLine()
+// readPayload reads n bytes into the arena and returns them. The returned
+// slice is capped so appending to it never clobbers the next payload.
+func (r *Reader) readPayload(n int) ([]byte, error) {
+	arena := *r.arena
+	start := len(arena)
+
+	for read := 0; read < n; {
+		chunk := min(n-read, payloadChunk)
+
+		end := len(arena)
+		arena = slices.Grow(arena, chunk)[:end+chunk]
+		*r.arena = arena
+
+		n, err := io.ReadFull(r.reader, arena[end:])
+		r.offset += int64(n)
+		if err != nil {
+			return nil, r.ioError(err)
+		}
+		read += chunk
+	}
+
+	return arena[start : start+n : start+n], nil
+}
+
+func (r *Reader) readString() (Value, error) {
+	v := Value{T: RespTString}
+
+	line, _, err := r.readLine()
+	if err != nil {
+		return v, err
+	}
+	v.String = string(line)
+
+	return v, nil
+}
+
+func (r *Reader) readError() (Value, error) {
+	v := Value{T: RespTError}
+
+	line, _, err := r.readLine()
+	if err != nil {
+		return v, err
+	}
+	v.String = string(line)
+
+	return v, nil
+}
+
+func (r *Reader) readInteger() (Value, error) {
+	v := Value{T: RespTInteger}
+
+	number, err := r.parseInteger()
+	if err != nil {
+		return v, err
+	}
+	v.Number = number
 
 	return v, nil
 }
 
+func (r *Reader) readDouble() (Value, error) {
+	v := Value{T: RespTDouble}
+
+	line, _, err := r.readLine()
+	if err != nil {
+		return v, err
+	}
+
+	v.Double, err = strconv.ParseFloat(string(line), 64)
+	if err != nil {
+		return v, r.failf(nil, "invalid double")
+	}
+
+	return v, nil
+}
+
+func (r *Reader) readBoolean() (Value, error) {
+	v := Value{T: RespTBoolean}
+
+	line, _, err := r.readLine()
+	if err != nil {
+		return v, err
+	}
+
+	switch string(line) {
+	case "t":
+		v.Boolean = true
+	case "f":
+		v.Boolean = false
+	default:
+		return v, r.failf(nil, "invalid boolean")
+	}
+
+	return v, nil
+}
+
+func (r *Reader) readBigNumber() (Value, error) {
+	v := Value{T: RespTBigNumber}
+
+	line, _, err := r.readLine()
+	if err != nil {
+		return v, err
+	}
+
+	if _, ok := new(big.Int).SetString(string(line), 10); !ok {
+		return v, r.failf(nil, "invalid big number")
+	}
+	v.String = string(line)
+
+	return v, nil
+}
+
+func (r *Reader) readVerbatim() (Value, error) {
+	v, err := r.readBulk()
+	if err != nil {
+		return v, err
+	}
+
+	// payload is prefixed with a three letter encoding and a colon
+	if len(v.Bulk) < 4 || v.Bulk[3] != ':' {
+		return v, r.failf(nil, "invalid verbatim string")
+	}
+
+	return Value{T: RespTVerbatim, Format: string(v.Bulk[:3]), Bulk: v.Bulk[4:]}, nil
+}
+
+func (r *Reader) readBlobError() (Value, error) {
+	v, err := r.readBulk()
+	if err != nil {
+		return v, err
+	}
+
+	return Value{T: RespTError, String: string(v.Bulk)}, nil
+}
+
+func (r *Reader) readNull() (Value, error) {
+	v := Value{T: RespTNull}
+
+	line, _, err := r.readLine()
+	if err != nil {
+		return v, err
+	}
+
+	if len(line) != 0 {
+		return v, r.failf(nil, "invalid null")
+	}
+
+	return v, nil
+}
+
+// Read decodes the next value. Bulk payloads of the previous value are
+// reused, see Reader.
 func (r *Reader) Read() (Value, error) {
+	*r.arena = (*r.arena)[:0]
+	r.depth = 0
+	r.start = r.offset
+	return r.read()
+}
+
+func (r *Reader) read() (Value, error) {
 	symbol, err := r.reader.ReadByte()
 	if err != nil {
-		return Value{}, err
+		return Value{}, r.ioError(err)
 	}
+	r.offset++
 
 	switch Symbol(symbol) {
 	case RespArray:
 		return r.readArray()
 	case RespBulk:
 		return r.readBulk()
+	case RespString:
+		return r.readString()
+	case RespError:
+		return r.readError()
+	case RespInteger:
+		return r.readInteger()
+	case RespMap:
+		return r.readAggregate(RespTMap, 2)
+	case RespSet:
+		return r.readAggregate(RespTSet, 1)
+	case RespPush:
+		return r.readAggregate(RespTPush, 1)
+	case RespDouble:
+		return r.readDouble()
+	case RespBoolean:
+		return r.readBoolean()
+	case RespBigNumber:
+		return r.readBigNumber()
+	case RespVerbatim:
+		return r.readVerbatim()
+	case RespBlobError:
+		return r.readBlobError()
+	case RespNull3:
+		return r.readNull()
 	default:
-		fmt.Printf("unknown resptype symbol: %v", string(symbol))
-		return Value{}, nil
+		return Value{}, r.failf(nil, "unexpected type byte %q", symbol)
 	}
 }
@@ -2,10 +2,13 @@ package resp
 
 import (
 	"bytes"
+	"errors"
 	"io"
+	"math"
 	"reflect"
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestReader_readLine(t *testing.T) {
@@ -116,7 +119,7 @@ func TestReader_readBulk(t *testing.T) {
 			input: "5\r\nhello\r\n",
 			want: Value{
 				T:    RespTBulk,
-				Bulk: "hello",
+				Bulk: []byte("hello"),
 			},
 			wantErr: false,
 		},
@@ -125,7 +128,7 @@ func TestReader_readBulk(t *testing.T) {
 			input: "0\r\n\r\n",
 			want: Value{
 				T:    RespTBulk,
-				Bulk: "",
+				Bulk: []byte(""),
 			},
 			wantErr: false,
 		},
@@ -160,8 +163,8 @@ func TestReader_readArray(t *testing.T) {
 			want: Value{
 				T: RespTArray,
 				Array: []Value{
-					{T: RespTBulk, Bulk: "hello"},
-					{T: RespTBulk, Bulk: "world"},
+					{T: RespTBulk, Bulk: []byte("hello")},
+					{T: RespTBulk, Bulk: []byte("world")},
 				},
 			},
 			wantErr: false,
@@ -180,11 +183,11 @@ func TestReader_readArray(t *testing.T) {
 			want: Value{
 				T: RespTArray,
 				Array: []Value{
-					{T: RespTBulk, Bulk: "hello"},
+					{T: RespTBulk, Bulk: []byte("hello")},
 					{
 						T: RespTArray,
 						Array: []Value{
-							{T: RespTBulk, Bulk: "world"},
+							{T: RespTBulk, Bulk: []byte("world")},
 						},
 					},
 				},
@@ -221,7 +224,7 @@ func TestReader_Read(t *testing.T) {
 			input: string(RespBulk) + "5\r\nhello\r\n",
 			want: Value{
 				T:    RespTBulk,
-				Bulk: "hello",
+				Bulk: []byte("hello"),
 			},
 			wantErr: false,
 		},
@@ -231,17 +234,74 @@ func TestReader_Read(t *testing.T) {
 			want: Value{
 				T: RespTArray,
 				Array: []Value{
-					{T: RespTBulk, Bulk: "hello"},
-					{T: RespTBulk, Bulk: "world"},
+					{T: RespTBulk, Bulk: []byte("hello")},
+					{T: RespTBulk, Bulk: []byte("world")},
 				},
 			},
 			wantErr: false,
 		},
+		{
+			name:  "read simple string",
+			input: string(RespString) + "OK\r\n",
+			want: Value{
+				T:      RespTString,
+				String: "OK",
+			},
+			wantErr: false,
+		},
+		{
+			name:  "read error",
+			input: string(RespError) + "ERR unknown command\r\n",
+			want: Value{
+				T:      RespTError,
+				String: "ERR unknown command",
+			},
+			wantErr: false,
+		},
+		{
+			name:  "read integer",
+			input: string(RespInteger) + "-1000\r\n",
+			want: Value{
+				T:      RespTInteger,
+				Number: -1000,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "read invalid integer",
+			input:   string(RespInteger) + "12x\r\n",
+			want:    Value{},
+			wantErr: true,
+		},
+		{
+			name:    "read null bulk string",
+			input:   string(RespNnull),
+			want:    Value{T: RespTNull},
+			wantErr: false,
+		},
+		{
+			name:    "read null array",
+			input:   string(RespArray) + "-1\r\n",
+			want:    Value{T: RespTNull},
+			wantErr: false,
+		},
+		{
+			name:    "read invalid bulk length",
+			input:   string(RespBulk) + "-2\r\n",
+			want:    Value{},
+			wantErr: true,
+		},
+		{
+			name:    "read invalid array length",
+			input:   string(RespArray) + "-2\r\n",
+			want:    Value{},
+			wantErr: true,
+		},
 		{
 			name:    "read invalid type",
 			input:   "x5\r\nhello\r\n",
 			want:    Value{},
-			wantErr: false, // According to the implementation
+			wantErr: true,
 		},
 		{
 			name:    "read empty input",
@@ -275,3 +335,451 @@ func TestReader_EOF(t *testing.T) {
 		t.Errorf("Expected EOF error, got %v", err)
 	}
 }
+
+func TestReader_ReadResp3(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Value
+		wantErr bool
+	}{
+		{
+			name:  "map",
+			input: "%2\r\n$5\r\nfirst\r\n$1\r\n1\r\n$6\r\nsecond\r\n$1\r\n2\r\n",
+			want: Value{
+				T: RespTMap,
+				Array: []Value{
+					{T: RespTBulk, Bulk: []byte("first")},
+					{T: RespTBulk, Bulk: []byte("1")},
+					{T: RespTBulk, Bulk: []byte("second")},
+					{T: RespTBulk, Bulk: []byte("2")},
+				},
+			},
+		},
+		{
+			name:  "set",
+			input: "~1\r\n$5\r\nhello\r\n",
+			want: Value{
+				T:     RespTSet,
+				Array: []Value{{T: RespTBulk, Bulk: []byte("hello")}},
+			},
+		},
+		{
+			name:  "push",
+			input: ">2\r\n$7\r\nmessage\r\n$5\r\nhello\r\n",
+			want: Value{
+				T: RespTPush,
+				Array: []Value{
+					{T: RespTBulk, Bulk: []byte("message")},
+					{T: RespTBulk, Bulk: []byte("hello")},
+				},
+			},
+		},
+		{
+			name:  "double",
+			input: ",1.23\r\n",
+			want:  Value{T: RespTDouble, Double: 1.23},
+		},
+		{
+			name:  "double infinity",
+			input: ",inf\r\n",
+			want:  Value{T: RespTDouble, Double: math.Inf(1)},
+		},
+		{
+			name:    "invalid double",
+			input:   ",one\r\n",
+			wantErr: true,
+		},
+		{
+			name:  "boolean true",
+			input: "#t\r\n",
+			want:  Value{T: RespTBoolean, Boolean: true},
+		},
+		{
+			name:  "boolean false",
+			input: "#f\r\n",
+			want:  Value{T: RespTBoolean},
+		},
+		{
+			name:    "invalid boolean",
+			input:   "#x\r\n",
+			wantErr: true,
+		},
+		{
+			name:  "big number",
+			input: "(-3492890328409238509324850943850943825024385\r\n",
+			want:  Value{T: RespTBigNumber, String: "-3492890328409238509324850943850943825024385"},
+		},
+		{
+			name:    "invalid big number",
+			input:   "(12a\r\n",
+			wantErr: true,
+		},
+		{
+			name:  "verbatim string",
+			input: "=15\r\ntxt:Some string\r\n",
+			want:  Value{T: RespTVerbatim, Format: "txt", Bulk: []byte("Some string")},
+		},
+		{
+			name:    "verbatim string without format",
+			input:   "=3\r\ntxt\r\n",
+			wantErr: true,
+		},
+		{
+			name:  "blob error",
+			input: "!21\r\nSYNTAX invalid syntax\r\n",
+			want:  Value{T: RespTError, String: "SYNTAX invalid syntax"},
+		},
+		{
+			name:  "null",
+			input: "_\r\n",
+			want:  Value{T: RespTNull},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(tt.input))
+			got, err := r.Read()
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReader_Resp3RoundTrip(t *testing.T) {
+	v := Value{
+		T: RespTMap,
+		Array: []Value{
+			{T: RespTBulk, Bulk: []byte("flags")},
+			{T: RespTSet, Array: []Value{{T: RespTBoolean, Boolean: true}}},
+			{T: RespTBulk, Bulk: []byte("score")},
+			{T: RespTDouble, Double: -0.5},
+			{T: RespTBulk, Bulk: []byte("missing")},
+			{T: RespTNull},
+		},
+	}
+
+	r := NewReader(bytes.NewReader(v.MarshalProto(Resp3)))
+	got, err := r.Read()
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("Read() = %v, want %v", got, v)
+	}
+}
+
+func TestReader_MarshalRoundTrip(t *testing.T) {
+	v := Value{
+		T: RespTArray,
+		Array: []Value{
+			{T: RespTString, String: "OK"},
+			{T: RespTError, String: "ERR failed"},
+			{T: RespTInteger, Number: 7},
+			{T: RespTBulk, Bulk: []byte("hello")},
+			{T: RespTNull},
+		},
+	}
+
+	r := NewReader(bytes.NewReader(v.Marshal()))
+	got, err := r.Read()
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("Read() = %v, want %v", got, v)
+	}
+}
+
+func TestReader_BinarySafeBulk(t *testing.T) {
+	payload := []byte{0x00, '\r', '\n', 0xff, '$', '\n'}
+	input := append([]byte("$6\r\n"), payload...)
+	input = append(input, '\r', '\n')
+
+	r := NewReader(bytes.NewReader(input))
+	defer r.Release()
+
+	got, err := r.Read()
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if !bytes.Equal(got.Bulk, payload) {
+		t.Errorf("Read() bulk = %q, want %q", got.Bulk, payload)
+	}
+}
+
+func TestReader_LargeValues(t *testing.T) {
+	bulk := bytes.Repeat([]byte("b"), 3*readBufferSize)
+	line := strings.Repeat("s", 2*readBufferSize)
+
+	input := Value{T: RespTBulk, Bulk: bulk}.Marshal()
+	input = append(input, Value{T: RespTString, String: line}.Marshal()...)
+
+	r := NewReader(bytes.NewReader(input))
+	defer r.Release()
+
+	got, err := r.Read()
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if !bytes.Equal(got.Bulk, bulk) {
+		t.Errorf("Read() bulk length = %d, want %d", len(got.Bulk), len(bulk))
+	}
+
+	got, err = r.Read()
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if got.String != line {
+		t.Errorf("Read() string length = %d, want %d", len(got.String), len(line))
+	}
+}
+
+func TestValue_Clone(t *testing.T) {
+	r := NewReader(strings.NewReader("*1\r\n$5\r\nhello\r\n*1\r\n$5\r\nworld\r\n"))
+	defer r.Release()
+
+	first, err := r.Read()
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	kept := first.Clone()
+
+	// the next read reuses the payload arena
+	if _, err := r.Read(); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	want := Value{T: RespTArray, Array: []Value{{T: RespTBulk, Bulk: []byte("hello")}}}
+	if !reflect.DeepEqual(kept, want) {
+		t.Errorf("Clone() = %v, want %v", kept, want)
+	}
+}
+
+func BenchmarkReader_Read(b *testing.B) {
+	cmd := Value{
+		T: RespTArray,
+		Array: []Value{
+			{T: RespTBulk, Bulk: []byte("SET")},
+			{T: RespTBulk, Bulk: []byte("key:000001")},
+			{T: RespTBulk, Bulk: bytes.Repeat([]byte("v"), 256)},
+		},
+	}.Marshal()
+
+	input := bytes.Repeat(cmd, 1000)
+	src := bytes.NewReader(input)
+	r := NewReader(src)
+	defer r.Release()
+
+	b.SetBytes(int64(len(cmd)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%1000 == 0 {
+			src.Reset(input)
+		}
+		if _, err := r.Read(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestReader_Limits(t *testing.T) {
+	limits := Limits{
+		MaxBulkLen:  8,
+		MaxArrayLen: 4,
+		MaxDepth:    2,
+		MaxLineLen:  32,
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{
+			name:  "within limits",
+			input: "*2\r\n$8\r\n12345678\r\n*1\r\n:1\r\n",
+		},
+		{
+			name:    "bulk too large",
+			input:   "$9\r\n123456789\r\n",
+			wantErr: ErrBulkTooLarge,
+		},
+		{
+			name:    "huge announced bulk",
+			input:   "$2147483647\r\n",
+			wantErr: ErrBulkTooLarge,
+		},
+		{
+			name:    "array too long",
+			input:   "*5\r\n",
+			wantErr: ErrArrayTooLong,
+		},
+		{
+			name:    "map counts pairs",
+			input:   "%5\r\n",
+			wantErr: ErrArrayTooLong,
+		},
+		{
+			name:    "nested too deep",
+			input:   "*1\r\n*1\r\n*1\r\n:1\r\n",
+			wantErr: ErrTooDeep,
+		},
+		{
+			name:    "line too long",
+			input:   "+" + strings.Repeat("a", 64) + "\r\n",
+			wantErr: ErrLineTooLong,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(tt.input))
+			defer r.Release()
+			r.SetLimits(limits)
+
+			_, err := r.Read()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Read() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReader_LineLimitAcrossBuffer(t *testing.T) {
+	r := NewReader(strings.NewReader(strings.Repeat("a", 2*readBufferSize) + "\r\n"))
+	defer r.Release()
+	r.SetLimits(Limits{MaxBulkLen: 1, MaxArrayLen: 1, MaxDepth: 1, MaxLineLen: readBufferSize})
+
+	if _, err := r.ReadCommand(); !errors.Is(err, ErrLineTooLong) {
+		t.Errorf("ReadCommand() error = %v, want %v", err, ErrLineTooLong)
+	}
+}
+
+func TestReader_ShortReads(t *testing.T) {
+	input := "*2\r\n$5\r\nhello\r\n$11\r\nhello world\r\n"
+	want := Value{
+		T: RespTArray,
+		Array: []Value{
+			{T: RespTBulk, Bulk: []byte("hello")},
+			{T: RespTBulk, Bulk: []byte("hello world")},
+		},
+	}
+
+	// deliver the stream one byte per read, like a slow TCP peer
+	r := NewReader(iotest.OneByteReader(strings.NewReader(input)))
+	defer r.Release()
+
+	got, err := r.Read()
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() = %v, want %v", got, want)
+	}
+	if r.Offset() != int64(len(input)) {
+		t.Errorf("Offset() = %d, want %d", r.Offset(), len(input))
+	}
+}
+
+func TestReader_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantErr    error
+		wantCause  error
+		wantOffset int64
+	}{
+		{
+			name:    "clean EOF between values",
+			input:   "+OK\r\n",
+			wantErr: io.EOF,
+		},
+		{
+			name:       "truncated bulk payload",
+			input:      "+OK\r\n$5\r\nhel",
+			wantErr:    ErrProtocol,
+			wantCause:  ErrUnexpectedEOF,
+			wantOffset: 12,
+		},
+		{
+			name:       "truncated header",
+			input:      "+OK\r\n*2\r",
+			wantErr:    ErrProtocol,
+			wantCause:  ErrUnexpectedEOF,
+			wantOffset: 8,
+		},
+		{
+			name:       "truncated array",
+			input:      "+OK\r\n*2\r\n:1\r\n",
+			wantErr:    ErrProtocol,
+			wantCause:  ErrUnexpectedEOF,
+			wantOffset: 13,
+		},
+		{
+			name:       "bulk without CRLF",
+			input:      "+OK\r\n$3\r\nhello\r\n",
+			wantErr:    ErrProtocol,
+			wantOffset: 12,
+		},
+		{
+			name:       "malformed bulk length",
+			input:      "+OK\r\n$abc\r\n",
+			wantErr:    ErrBulkTooLarge,
+			wantCause:  ErrInvalidLength,
+			wantOffset: 11,
+		},
+		{
+			name:       "negative array length",
+			input:      "+OK\r\n*-5\r\n",
+			wantErr:    ErrArrayTooLong,
+			wantCause:  ErrInvalidLength,
+			wantOffset: 10,
+		},
+		{
+			name:       "unknown type",
+			input:      "+OK\r\n?\r\n",
+			wantErr:    ErrProtocol,
+			wantOffset: 6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(tt.input))
+			defer r.Release()
+
+			// the first value always decodes
+			if _, err := r.Read(); err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+
+			_, err := r.Read()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Read() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == io.EOF {
+				return
+			}
+
+			var protoErr *ProtocolError
+			if !errors.As(err, &protoErr) {
+				t.Fatalf("Read() error = %T, want *ProtocolError", err)
+			}
+			if protoErr.Offset != tt.wantOffset {
+				t.Errorf("Offset = %d, want %d", protoErr.Offset, tt.wantOffset)
+			}
+			if tt.wantCause != nil && !errors.Is(err, tt.wantCause) {
+				t.Errorf("Read() error = %v, want cause %v", err, tt.wantCause)
+			}
+		})
+	}
+}
@@ -9,22 +9,99 @@ const (
 	RespError   Symbol = "-"
 	RespInteger Symbol = ":"
 	RespNnull   Symbol = "$-1\r\n"
+
+	// RESP3 symbols
+	RespMap       Symbol = "%"
+	RespSet       Symbol = "~"
+	RespDouble    Symbol = ","
+	RespBoolean   Symbol = "#"
+	RespBigNumber Symbol = "("
+	RespVerbatim  Symbol = "="
+	RespPush      Symbol = ">"
+	RespBlobError Symbol = "!"
+	RespNull3     Symbol = "_"
 )
 
 type Type string
 
 const (
-	RespTArray  Type = "array"
-	RespTBulk   Type = "bulk"
-	RespTNull   Type = "null"
-	RespTError  Type = "error"
-	RespTString Type = "string"
+	RespTArray   Type = "array"
+	RespTBulk    Type = "bulk"
+	RespTNull    Type = "null"
+	RespTError   Type = "error"
+	RespTString  Type = "string"
+	RespTInteger Type = "integer"
+
+	// RESP3 types
+	RespTMap       Type = "map"
+	RespTSet       Type = "set"
+	RespTDouble    Type = "double"
+	RespTBoolean   Type = "boolean"
+	RespTBigNumber Type = "bignumber"
+	RespTVerbatim  Type = "verbatim"
+	RespTPush      Type = "push"
 )
 
+// Protocol is the RESP version spoken on a connection
+type Protocol int
+
+const (
+	Resp2 Protocol = 2
+	Resp3 Protocol = 3
+)
+
+// Value is a single RESP value.
+//
+// Bulk payloads are binary safe byte slices. Values decoded by a Reader share
+// its buffers, see Reader for how long they stay valid.
+//
+// Aggregates (array, set, push) keep their items in Array. Maps keep their
+// entries in Array as alternating key/value pairs. Big numbers keep their
+// decimal digits in String, and verbatim strings keep their payload in Bulk
+// with the three letter encoding in Format.
+//
+// Server replies made only of bulk strings, such as HGETALL, may keep them
+// in Strings instead of Array. The Writer streams them without building a
+// Value per item.
 type Value struct {
-	T      Type
-	String string
-	Number int
-	Bulk   string
-	Array  []Value
+	T       Type
+	String  string
+	Number  int
+	Bulk    []byte
+	Array   []Value
+	Strings []string
+	Double  float64
+	Boolean bool
+	Format  string
+}
+
+// Clone returns a deep copy of the value that does not share any memory with
+// the buffers it was decoded from
+func (v Value) Clone() Value {
+	if v.Bulk != nil {
+		v.Bulk = append([]byte{}, v.Bulk...)
+	}
+
+	if v.Array != nil {
+		array := make([]Value, len(v.Array))
+		for i, item := range v.Array {
+			array[i] = item.Clone()
+		}
+		v.Array = array
+	}
+
+	if v.Strings != nil {
+		v.Strings = append([]string{}, v.Strings...)
+	}
+
+	return v
+}
+
+// items returns the number of items of an aggregate
+func (v Value) items() int {
+	if v.Strings != nil {
+		return len(v.Strings)
+	}
+
+	return len(v.Array)
 }
@@ -0,0 +1,181 @@
+package resp
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+)
+
+// Writer encodes replies straight into a buffered stream. Nothing reaches
+// the underlying writer until the buffer fills up or Flush is called, which
+// lets the replies to pipelined commands go out in a single write.
+type Writer struct {
+	writer *bufio.Writer
+	proto  Protocol
+}
+
+func NewWriter(wr io.Writer) *Writer {
+	return &Writer{
+		writer: bufio.NewWriterSize(wr, readBufferSize),
+		proto:  Resp2,
+	}
+}
+
+// Protocol returns the protocol replies are encoded with
+func (w *Writer) Protocol() Protocol {
+	return w.proto
+}
+
+// SetProtocol changes the protocol used for the following replies
+func (w *Writer) SetProtocol(p Protocol) {
+	w.proto = p
+}
+
+// Buffered returns the number of bytes waiting to be flushed
+func (w *Writer) Buffered() int {
+	return w.writer.Buffered()
+}
+
+// Flush writes any buffered replies to the underlying writer
+func (w *Writer) Flush() error {
+	return w.writer.Flush()
+}
+
+// write appends an encoding to the free space of the buffer when it fits,
+// so small replies are encoded in place without an intermediate slice
+func (w *Writer) write(encode func(dst []byte) []byte) error {
+	_, err := w.writer.Write(encode(w.writer.AvailableBuffer()))
+	return err
+}
+
+// WriteValue encodes a whole value. Aggregates are written item by item,
+// so a large reply drains through the buffer instead of being encoded into
+// one slice first.
+func (w *Writer) WriteValue(v Value) error {
+	var err error
+	switch v.T {
+	case RespTArray:
+		err = w.WriteArrayHeader(v.items())
+	case RespTMap:
+		err = w.WriteMapHeader(v.items() / 2)
+	case RespTSet:
+		err = w.WriteSetHeader(v.items())
+	case RespTPush:
+		err = w.WritePushHeader(v.items())
+	case RespTBulk:
+		return w.WriteBulk(v.Bulk)
+	default:
+		return w.write(func(dst []byte) []byte {
+			return v.AppendProto(dst, w.proto)
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, item := range v.Array {
+		if err := w.WriteValue(item); err != nil {
+			return err
+		}
+	}
+	for _, str := range v.Strings {
+		if err := w.WriteBulkString(str); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteString writes a simple string
+func (w *Writer) WriteString(s string) error {
+	return w.write(Value{T: RespTString, String: s}.appendString)
+}
+
+// WriteError writes an error, msg should start with an error code such as
+// ERR
+func (w *Writer) WriteError(msg string) error {
+	return w.write(Value{T: RespTError, String: msg}.appendError)
+}
+
+// WriteInteger writes an integer
+func (w *Writer) WriteInteger(n int) error {
+	return w.write(func(dst []byte) []byte {
+		return appendInteger(dst, n)
+	})
+}
+
+// WriteBulk writes a bulk string
+func (w *Writer) WriteBulk(b []byte) error {
+	if err := w.writeHeader(RespBulk, len(b)); err != nil {
+		return err
+	}
+
+	// large payloads are copied by bufio without being buffered twice
+	if _, err := w.writer.Write(b); err != nil {
+		return err
+	}
+
+	_, err := w.writer.WriteString("\r\n")
+	return err
+}
+
+// WriteBulkString writes a bulk string from a Go string
+func (w *Writer) WriteBulkString(s string) error {
+	if err := w.writeHeader(RespBulk, len(s)); err != nil {
+		return err
+	}
+
+	if _, err := w.writer.WriteString(s); err != nil {
+		return err
+	}
+
+	_, err := w.writer.WriteString("\r\n")
+	return err
+}
+
+// WriteNull writes the null reply of the current protocol
+func (w *Writer) WriteNull() error {
+	return w.WriteValue(Value{T: RespTNull})
+}
+
+// WriteArrayHeader starts an array of n elements. The elements are written
+// with further calls, which lets large replies be streamed.
+func (w *Writer) WriteArrayHeader(n int) error {
+	return w.writeHeader(RespArray, n)
+}
+
+// WriteMapHeader starts a map of n key/value pairs. RESP2 clients receive
+// a flat array of 2n elements instead.
+func (w *Writer) WriteMapHeader(n int) error {
+	if w.proto == Resp3 {
+		return w.writeHeader(RespMap, n)
+	}
+	return w.writeHeader(RespArray, 2*n)
+}
+
+// WriteSetHeader starts a set of n elements. RESP2 clients receive an
+// array instead.
+func (w *Writer) WriteSetHeader(n int) error {
+	if w.proto == Resp3 {
+		return w.writeHeader(RespSet, n)
+	}
+	return w.writeHeader(RespArray, n)
+}
+
+// WritePushHeader starts an out of band push message of n elements. RESP2
+// clients receive an array instead.
+func (w *Writer) WritePushHeader(n int) error {
+	if w.proto == Resp3 {
+		return w.writeHeader(RespPush, n)
+	}
+	return w.writeHeader(RespArray, n)
+}
+
+func (w *Writer) writeHeader(symbol Symbol, n int) error {
+	return w.write(func(dst []byte) []byte {
+		dst = append(dst, symbol...)
+		dst = strconv.AppendInt(dst, int64(n), 10)
+		return appendEndOfLine(dst)
+	})
+}
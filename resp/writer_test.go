@@ -0,0 +1,227 @@
+package resp
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		proto Protocol
+		write func(w *Writer) error
+		want  string
+	}{
+		{
+			name:  "simple string",
+			write: func(w *Writer) error { return w.WriteString("OK") },
+			want:  "+OK\r\n",
+		},
+		{
+			name:  "error",
+			write: func(w *Writer) error { return w.WriteError("ERR failed") },
+			want:  "-ERR failed\r\n",
+		},
+		{
+			name:  "integer",
+			write: func(w *Writer) error { return w.WriteInteger(-12) },
+			want:  ":-12\r\n",
+		},
+		{
+			name:  "bulk",
+			write: func(w *Writer) error { return w.WriteBulk([]byte("hello")) },
+			want:  "$5\r\nhello\r\n",
+		},
+		{
+			name:  "bulk string",
+			write: func(w *Writer) error { return w.WriteBulkString("") },
+			want:  "$0\r\n\r\n",
+		},
+		{
+			name:  "resp2 null",
+			write: func(w *Writer) error { return w.WriteNull() },
+			want:  "$-1\r\n",
+		},
+		{
+			name:  "resp3 null",
+			proto: Resp3,
+			write: func(w *Writer) error { return w.WriteNull() },
+			want:  "_\r\n",
+		},
+		{
+			name: "streamed array",
+			write: func(w *Writer) error {
+				w.WriteArrayHeader(2)
+				w.WriteBulkString("a")
+				return w.WriteInteger(1)
+			},
+			want: "*2\r\n$1\r\na\r\n:1\r\n",
+		},
+		{
+			name: "resp2 map header",
+			write: func(w *Writer) error {
+				return w.WriteMapHeader(2)
+			},
+			want: "*4\r\n",
+		},
+		{
+			name:  "resp3 map header",
+			proto: Resp3,
+			write: func(w *Writer) error {
+				return w.WriteMapHeader(2)
+			},
+			want: "%2\r\n",
+		},
+		{
+			name: "resp2 set header",
+			write: func(w *Writer) error {
+				return w.WriteSetHeader(3)
+			},
+			want: "*3\r\n",
+		},
+		{
+			name:  "resp3 set header",
+			proto: Resp3,
+			write: func(w *Writer) error {
+				return w.WriteSetHeader(3)
+			},
+			want: "~3\r\n",
+		},
+		{
+			name: "resp2 push header",
+			write: func(w *Writer) error {
+				return w.WritePushHeader(3)
+			},
+			want: "*3\r\n",
+		},
+		{
+			name:  "resp3 push header",
+			proto: Resp3,
+			write: func(w *Writer) error {
+				return w.WritePushHeader(3)
+			},
+			want: ">3\r\n",
+		},
+		{
+			name: "resp2 map of strings",
+			write: func(w *Writer) error {
+				return w.WriteValue(Value{T: RespTMap, Strings: []string{"f", "v"}})
+			},
+			want: "*2\r\n$1\r\nf\r\n$1\r\nv\r\n",
+		},
+		{
+			name:  "resp3 map of strings",
+			proto: Resp3,
+			write: func(w *Writer) error {
+				return w.WriteValue(Value{T: RespTMap, Strings: []string{"f", "v"}})
+			},
+			want: "%1\r\n$1\r\nf\r\n$1\r\nv\r\n",
+		},
+		{
+			name:  "resp3 value",
+			proto: Resp3,
+			write: func(w *Writer) error {
+				return w.WriteValue(Value{T: RespTBoolean, Boolean: true})
+			},
+			want: "#t\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			w := NewWriter(&out)
+			if tt.proto != 0 {
+				w.SetProtocol(tt.proto)
+			}
+
+			if err := tt.write(w); err != nil {
+				t.Fatalf("write error = %v", err)
+			}
+			if out.Len() != 0 {
+				t.Errorf("wrote %q before Flush", out.Bytes())
+			}
+
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriter_LargeBulk(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+
+	payload := bytes.Repeat([]byte("x"), 4*readBufferSize)
+	if err := w.WriteBulk(payload); err != nil {
+		t.Fatalf("WriteBulk() error = %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	want := Value{T: RespTBulk, Bulk: payload}.Marshal()
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("wrote %d bytes, want %d", out.Len(), len(want))
+	}
+}
+
+func TestWriter_LargeAggregate(t *testing.T) {
+	strs := make([]string, 2*readBufferSize)
+	for i := range strs {
+		strs[i] = strconv.Itoa(i)
+	}
+
+	for _, proto := range []Protocol{Resp2, Resp3} {
+		var out bytes.Buffer
+		w := NewWriter(&out)
+		w.SetProtocol(proto)
+
+		v := Value{T: RespTMap, Strings: strs}
+		if err := w.WriteValue(v); err != nil {
+			t.Fatalf("WriteValue() error = %v", err)
+		}
+
+		// the reply drained through the buffer as it was written
+		if out.Len() == 0 {
+			t.Errorf("protocol %d: nothing written before Flush", proto)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+
+		if want := v.MarshalProto(proto); !bytes.Equal(out.Bytes(), want) {
+			t.Errorf("protocol %d: wrote %d bytes, want %d", proto, out.Len(), len(want))
+		}
+	}
+}
+
+func BenchmarkWriter_WriteValue(b *testing.B) {
+	v := Value{
+		T: RespTArray,
+		Array: []Value{
+			{T: RespTString, String: "hello"},
+			{T: RespTBulk, Bulk: []byte("world")},
+			{T: RespTNull},
+			{T: RespTError, String: "test error"},
+		},
+	}
+
+	var out bytes.Buffer
+	w := NewWriter(&out)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.WriteValue(v)
+		if w.Buffered() > readBufferSize/2 {
+			w.Flush()
+			out.Reset()
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/helewud/redis-clone/acl"
+	"github.com/helewud/redis-clone/commands"
+	"github.com/helewud/redis-clone/resp"
+)
+
+// LoadACL loads the users from the configured ACL file, if any, otherwise
+// it applies requirepass to the default user
+func (s *Server) LoadACL() error {
+	cfg := s.config.Config()
+	if cfg.ACLFile != "" {
+		return s.acl.Load(cfg.ACLFile)
+	}
+
+	return s.applyRequirePass(cfg.RequirePass)
+}
+
+func (s *Server) applyRequirePass(password string) error {
+	if s.config.Config().ACLFile != "" {
+		return nil
+	}
+	if password == "" {
+		return s.acl.SetUser(acl.DefaultUser, "nopass")
+	}
+
+	return s.acl.SetUser(acl.DefaultUser, "resetpass", ">"+password)
+}
+
+// initSession authenticates a new connection as the default user when it
+// has no password, or as the user named by its TLS certificate, and lets
+// HELLO AUTH log it in
+func (s *Server) initSession(session *commands.Session, tlsUser string) {
+	session.SetAuthenticator(func(user, password string) error {
+		return s.authenticate(session, user, password)
+	})
+
+	u, ok := s.acl.User(acl.DefaultUser)
+	session.Authenticated = ok && u.Enabled && u.NoPass
+
+	if tlsUser == "" {
+		return
+	}
+	if u, ok := s.acl.User(tlsUser); ok && u.Enabled {
+		session.User = tlsUser
+		session.Authenticated = true
+	}
+}
+
+// describeClient is the client info of ACL log entries, the client's line
+// in CLIENT LIST
+func (s *Server) describeClient(session *commands.Session) string {
+	if c, ok := s.client(session.ID); ok {
+		return c.describe()
+	}
+
+	return fmt.Sprintf("id=%d addr=%s name=%s user=%s", session.ID, session.Addr, session.Name, session.User)
+}
+
+// authorize checks that the client may run cmd before its handler does. It
+// returns the error reply when it may not.
+func (s *Server) authorize(session *commands.Session, cmd *commands.Command, args []resp.Value) (resp.Value, bool) {
+	// commands such as AUTH and HELLO must work whatever the user may run,
+	// or a restricted default user would lock everybody out
+	if cmd.HasFlag(commands.FlagNoAuth) {
+		return resp.Value{}, true
+	}
+	if !session.Authenticated {
+		return resp.Value{T: resp.RespTError, String: "NOAUTH Authentication required."}, false
+	}
+
+	// subcommands have categories of their own
+	var subcommand string
+	categories := cmd.Categories
+	if cmd.Subcommands != nil && len(args) > 0 {
+		subcommand = string(args[0].Bulk)
+		if sub, ok := cmd.Subcommand(args); ok {
+			categories = sub.Categories
+		}
+	}
+
+	err := s.acl.Check(session.User, cmd.Name, subcommand, categories, cmd.KeyArgs(args))
+
+	var perr *acl.PermError
+	switch {
+	case err == nil:
+		return resp.Value{}, true
+	case errors.Is(err, acl.ErrNoUser):
+		// the user was deleted, the client has to log in again
+		session.User = acl.DefaultUser
+		session.Authenticated = false
+		return resp.Value{T: resp.RespTError, String: "NOAUTH Authentication required."}, false
+	case errors.As(err, &perr):
+		s.acl.Log().Add(perr.Reason, perr.Object, session.User, s.describeClient(session))
+		return commands.ErrorReply("NOPERM " + perr.Error()), false
+	default:
+		return commands.ErrorReply("ERR " + err.Error()), false
+	}
+}
+
+// authCommand answers AUTH [username] password
+func (s *Server) authCommand(session *commands.Session, args []resp.Value) resp.Value {
+	var name, password string
+	switch len(args) {
+	case 1:
+		name, password = acl.DefaultUser, string(args[0].Bulk)
+
+		if u, ok := s.acl.User(acl.DefaultUser); ok && u.NoPass {
+			return resp.Value{
+				T:      resp.RespTError,
+				String: "ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?",
+			}
+		}
+	case 2:
+		name, password = string(args[0].Bulk), string(args[1].Bulk)
+	default:
+		return resp.Value{T: resp.RespTError, String: "ERR wrong number of arguments for 'auth' command"}
+	}
+
+	if err := s.authenticate(session, name, password); err != nil {
+		return resp.Value{T: resp.RespTError, String: "WRONGPASS invalid username-password pair or user is disabled."}
+	}
+
+	session.User = name
+	session.Authenticated = true
+
+	return resp.Value{T: resp.RespTString, String: "OK"}
+}
+
+// authenticate checks the password of user for the client of session and
+// logs failed attempts
+func (s *Server) authenticate(session *commands.Session, user, password string) error {
+	err := s.acl.Authenticate(user, password)
+	if err != nil {
+		s.acl.Log().Add("auth", "AUTH", user, s.describeClient(session))
+	}
+
+	return err
+}
+
+func bulk(s string) resp.Value {
+	return resp.Value{T: resp.RespTBulk, Bulk: []byte(s)}
+}
+
+func bulkArray(strs []string) resp.Value {
+	v := resp.Value{T: resp.RespTArray, Array: make([]resp.Value, len(strs))}
+	for i, s := range strs {
+		v.Array[i] = bulk(s)
+	}
+
+	return v
+}
+
+const noACLFile = "ERR This Redis instance is not configured to use an ACL file. " +
+	"You may want to specify users via the ACL SETUSER command and then issue a CONFIG REWRITE " +
+	"(assuming you have a Redis configuration file set) in order to store users in the Redis configuration."
+
+// aclCommand answers the ACL subcommands
+func (s *Server) aclCommand(session *commands.Session, args []resp.Value) resp.Value {
+	if len(args) == 0 {
+		return resp.Value{T: resp.RespTError, String: "ERR wrong number of arguments for 'acl' command"}
+	}
+
+	name := string(args[0].Bulk)
+	subcommand := strings.ToUpper(name)
+	args = args[1:]
+
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		strs[i] = string(arg.Bulk)
+	}
+
+	wrongArgs := resp.Value{
+		T:      resp.RespTError,
+		String: fmt.Sprintf("ERR wrong number of arguments for 'acl|%s' command", strings.ToLower(subcommand)),
+	}
+
+	switch subcommand {
+	case "SETUSER":
+		if len(strs) == 0 {
+			return wrongArgs
+		}
+		if err := s.acl.SetUser(strs[0], strs[1:]...); err != nil {
+			return commands.ErrorReply("ERR " + err.Error())
+		}
+		return resp.Value{T: resp.RespTString, String: "OK"}
+	case "GETUSER":
+		if len(strs) != 1 {
+			return wrongArgs
+		}
+		u, ok := s.acl.User(strs[0])
+		if !ok {
+			return resp.Value{T: resp.RespTNull}
+		}
+		return describeUser(u)
+	case "DELUSER":
+		if len(strs) == 0 {
+			return wrongArgs
+		}
+		n, err := s.acl.DelUser(strs...)
+		if err != nil {
+			return commands.ErrorReply("ERR " + err.Error())
+		}
+		return resp.Value{T: resp.RespTInteger, Number: n}
+	case "LIST":
+		if len(strs) != 0 {
+			return wrongArgs
+		}
+		return bulkArray(s.acl.List())
+	case "USERS":
+		if len(strs) != 0 {
+			return wrongArgs
+		}
+		return bulkArray(s.acl.Users())
+	case "WHOAMI":
+		if len(strs) != 0 {
+			return wrongArgs
+		}
+		return bulk(session.User)
+	case "CAT":
+		switch len(strs) {
+		case 0:
+			return bulkArray(acl.Categories)
+		case 1:
+			category := strings.ToLower(strs[0])
+			if !slices.Contains(acl.Categories, category) {
+				return commands.ErrorReply("ERR Unknown category '" + strs[0] + "'")
+			}
+			// containers are listed by their subcommands
+			var names []string
+			for _, cmd := range s.commands {
+				cmds := []*commands.Command{cmd}
+				if cmd.Subcommands != nil {
+					cmds = slices.Collect(maps.Values(cmd.Subcommands))
+				}
+				for _, cmd := range cmds {
+					if slices.Contains(cmd.Categories, category) {
+						names = append(names, cmd.Name)
+					}
+				}
+			}
+			slices.Sort(names)
+			return bulkArray(names)
+		default:
+			return wrongArgs
+		}
+	case "LOG":
+		return s.aclLog(strs, wrongArgs)
+	case "SAVE", "LOAD":
+		if len(strs) != 0 {
+			return wrongArgs
+		}
+		path := s.config.Config().ACLFile
+		if path == "" {
+			return resp.Value{T: resp.RespTError, String: noACLFile}
+		}
+
+		var err error
+		if subcommand == "SAVE" {
+			err = s.acl.Save(path)
+		} else {
+			err = s.acl.Load(path)
+		}
+		if err != nil {
+			return commands.ErrorReply("ERR " + err.Error())
+		}
+		return resp.Value{T: resp.RespTString, String: "OK"}
+	case "HELP":
+		return helpReply(
+			"ACL <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+			"CAT [<category>]",
+			"    List all commands that belong to <category>, or all command categories",
+			"    when no category is specified.",
+			"DELUSER <username> [<username> ...]",
+			"    Delete a list of users.",
+			"GETUSER <username>",
+			"    Get the user's details.",
+			"LIST",
+			"    Show users details in config file format.",
+			"LOAD",
+			"    Reload users from the ACL file.",
+			"LOG [<count> | RESET]",
+			"    Show the ACL log entries.",
+			"SAVE",
+			"    Save the current config to the ACL file.",
+			"SETUSER <username> <attribute> [<attribute> ...]",
+			"    Create or modify a user with the specified attributes.",
+			"USERS",
+			"    List all the registered usernames.",
+			"WHOAMI",
+			"    Return the current connection username.",
+			"HELP",
+			"    Print this help.",
+		)
+	default:
+		return commands.ErrorReply(fmt.Sprintf("ERR unknown subcommand '%s'. Try ACL HELP.", name))
+	}
+}
+
+// describeUser is the reply to ACL GETUSER
+func describeUser(u *acl.User) resp.Value {
+	flags := []string{"off"}
+	if u.Enabled {
+		flags[0] = "on"
+	}
+	if u.NoPass {
+		flags = append(flags, "nopass")
+	}
+
+	rules := make([]string, len(u.Commands))
+	for i, r := range u.Commands {
+		rules[i] = r.String()
+	}
+	if len(rules) == 0 {
+		rules = []string{"-@all"}
+	}
+
+	keys := make([]string, len(u.Keys))
+	for i, pattern := range u.Keys {
+		keys[i] = "~" + pattern
+	}
+
+	return resp.Value{
+		T: resp.RespTMap,
+		Array: []resp.Value{
+			bulk("flags"), bulkArray(flags),
+			bulk("passwords"), bulkArray(u.Password),
+			bulk("commands"), bulk(strings.Join(rules, " ")),
+			bulk("keys"), bulk(strings.Join(keys, " ")),
+			bulk("channels"), bulk(""),
+			bulk("selectors"), {T: resp.RespTArray, Array: []resp.Value{}},
+		},
+	}
+}
+
+// aclLog answers ACL LOG [count | RESET]
+func (s *Server) aclLog(args []string, wrongArgs resp.Value) resp.Value {
+	count := 10
+	switch {
+	case len(args) > 1:
+		return wrongArgs
+	case len(args) == 1 && strings.EqualFold(args[0], "RESET"):
+		s.acl.Log().Reset()
+		return resp.Value{T: resp.RespTString, String: "OK"}
+	case len(args) == 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			return resp.Value{T: resp.RespTError, String: "ERR value is out of range, must be positive"}
+		}
+		count = n
+	}
+
+	now := time.Now()
+	entries := s.acl.Log().Entries(count)
+	res := resp.Value{T: resp.RespTArray, Array: make([]resp.Value, len(entries))}
+	for i, e := range entries {
+		res.Array[i] = resp.Value{
+			T: resp.RespTMap,
+			Array: []resp.Value{
+				bulk("count"), {T: resp.RespTInteger, Number: e.Count},
+				bulk("reason"), bulk(e.Reason),
+				bulk("context"), bulk(e.Context),
+				bulk("object"), bulk(e.Object),
+				bulk("username"), bulk(e.Username),
+				bulk("age-seconds"), {T: resp.RespTDouble, Double: now.Sub(e.Created).Seconds()},
+				bulk("client-info"), bulk(e.ClientInfo),
+				bulk("entry-id"), {T: resp.RespTInteger, Number: int(e.ID)},
+				bulk("timestamp-created"), {T: resp.RespTInteger, Number: int(e.Created.UnixMilli())},
+				bulk("timestamp-last-updated"), {T: resp.RespTInteger, Number: int(e.Updated.UnixMilli())},
+			},
+		}
+	}
+
+	return res
+}
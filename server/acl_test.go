@@ -0,0 +1,198 @@
+package server
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/helewud/redis-clone/commands"
+	"github.com/helewud/redis-clone/config"
+	"github.com/helewud/redis-clone/resp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// client sends commands to addr and reads their replies
+type client struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *resp.Reader
+}
+
+func dial(t *testing.T, addr string) *client {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	reader := resp.NewReader(conn)
+	t.Cleanup(func() {
+		reader.Release()
+		conn.Close()
+	})
+
+	return &client{t: t, conn: conn, reader: reader}
+}
+
+func (c *client) do(args ...string) resp.Value {
+	c.t.Helper()
+
+	_, err := c.conn.Write(resp.Value{T: resp.RespTArray, Array: bulks(args...)}.Marshal())
+	require.NoError(c.t, err)
+	reply, err := c.reader.Read()
+	require.NoError(c.t, err)
+
+	return reply
+}
+
+func TestAuth(t *testing.T) {
+	s, _, addr, _ := serve(t)
+	session := commands.NewSession(1, "127.0.0.1:50000")
+	require.Equal(t, "OK", s.configCommand(session, bulks("SET", "requirepass", "secret")).String)
+
+	c := dial(t, addr)
+	assert.Equal(t, "NOAUTH Authentication required.", c.do("GET", "k").String)
+	assert.Equal(t, "WRONGPASS invalid username-password pair or user is disabled.", c.do("AUTH", "wrong").String)
+	assert.Equal(t, "OK", c.do("AUTH", "secret").String)
+	assert.Equal(t, "OK", c.do("SET", "k", "v").String)
+	assert.Equal(t, "default", string(c.do("ACL", "WHOAMI").Bulk))
+
+	// the failed attempt is in the log
+	entries := c.do("ACL", "LOG")
+	require.Len(t, entries.Array, 1)
+	assert.Equal(t, "auth", string(entries.Array[0].Array[3].Bulk))
+	assert.Equal(t, "AUTH", string(entries.Array[0].Array[7].Bulk))
+
+	// HELLO needs its AUTH option to log in
+	h := dial(t, addr)
+	assert.Contains(t, h.do("HELLO", "3").String, "NOAUTH HELLO must be called with the client already authenticated")
+	assert.Equal(t, resp.RespTMap, h.do("HELLO", "3", "AUTH", "default", "secret", "SETNAME", "app").T)
+	assert.Equal(t, "app", string(h.do("CLIENT", "GETNAME").Bulk))
+
+	assert.Equal(t, "OK", c.do("CONFIG", "SET", "requirepass", "").String)
+	assert.Contains(t, dial(t, addr).do("AUTH", "secret").String, "without any password configured")
+}
+
+func TestConfigSetKeepsDefaultPassword(t *testing.T) {
+	_, _, addr, _ := serve(t)
+
+	c := dial(t, addr)
+	assert.Equal(t, "OK", c.do("ACL", "SETUSER", "default", "resetpass", ">pw").String)
+	assert.Equal(t, "OK", c.do("CONFIG", "SET", "timeout", "5").String)
+
+	// other settings leave the password set with ACL SETUSER alone
+	assert.Equal(t, "NOAUTH Authentication required.", dial(t, addr).do("GET", "k").String)
+}
+
+func TestAuthRestrictedDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.acl")
+	require.NoError(t, os.WriteFile(path, []byte("user default on nopass resetkeys -@all\nuser alice on >pw ~* +@all\n"), 0644))
+
+	cfg := config.Default()
+	cfg.ACLFile = path
+	s, _, addr, _ := serveConfig(t, cfg)
+	require.NoError(t, s.LoadACL())
+
+	// the default user may run nothing but still log in as someone else
+	c := dial(t, addr)
+	assert.Equal(t, "NOPERM User default has no permissions to run the 'get' command", c.do("GET", "k").String)
+	assert.Equal(t, resp.RespTMap, c.do("HELLO", "3").T)
+	assert.Equal(t, "OK", c.do("AUTH", "alice", "pw").String)
+	assert.Equal(t, resp.RespTNull, c.do("GET", "restricted:nope").T)
+
+	// and so can clients of a disabled default user
+	assert.Equal(t, "OK", c.do("ACL", "SETUSER", "default", "off").String)
+	other := dial(t, addr)
+	assert.Equal(t, "NOAUTH Authentication required.", other.do("GET", "k").String)
+	assert.Equal(t, "OK", other.do("AUTH", "alice", "pw").String)
+}
+
+func TestACLPermissions(t *testing.T) {
+	_, _, addr, _ := serve(t)
+
+	admin := dial(t, addr)
+	assert.Equal(t, "OK", admin.do("ACL", "SETUSER", "reader", "on", ">pw", "~cache:*", "+@read", "+ping").String)
+	assert.Equal(t, "OK", admin.do("SET", "cache:1", "v").String)
+
+	c := dial(t, addr)
+	assert.Equal(t, "OK", c.do("AUTH", "reader", "pw").String)
+	assert.Equal(t, "PONG", c.do("PING").String)
+	assert.Equal(t, "v", string(c.do("GET", "cache:1").Bulk))
+	assert.Equal(t, "NOPERM User reader has no permissions to run the 'set' command", c.do("SET", "cache:1", "w").String)
+	assert.Equal(t, "NOPERM No permissions to access a key", c.do("GET", "other").String)
+	assert.Equal(t, "v", string(admin.do("GET", "cache:1").Bulk))
+
+	entries := admin.do("ACL", "LOG")
+	require.Len(t, entries.Array, 2)
+	assert.Equal(t, "key", string(entries.Array[0].Array[3].Bulk))
+	assert.Equal(t, "other", string(entries.Array[0].Array[7].Bulk))
+	assert.Equal(t, "command", string(entries.Array[1].Array[3].Bulk))
+	assert.Equal(t, "set", string(entries.Array[1].Array[7].Bulk))
+	assert.Equal(t, "OK", admin.do("ACL", "LOG", "RESET").String)
+	assert.Empty(t, admin.do("ACL", "LOG").Array)
+
+	// a deleted user has to log in again
+	assert.Equal(t, 1, admin.do("ACL", "DELUSER", "reader").Number)
+	assert.Equal(t, "NOAUTH Authentication required.", c.do("PING").String)
+}
+
+func TestACLCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.acl")
+	cfg := config.Default()
+	cfg.ACLFile = path
+	s := New(nil, config.NewManager(cfg, ""))
+	session := commands.NewSession(1, "127.0.0.1:50000")
+
+	acl := func(args ...string) resp.Value {
+		return s.aclCommand(session, bulks(args...))
+	}
+
+	assert.Equal(t, "OK", acl("SETUSER", "alice", "on", "nopass", "~app:*", "+@all", "-@dangerous").String)
+	assert.Equal(t, "ERR Error in ACL SETUSER modifier '+nosuch': Unknown command or category name in ACL",
+		acl("SETUSER", "alice", "+nosuch").String)
+	assert.Equal(t, "ERR Error in ACL SETUSER modifier 'x  +OK': Syntax error",
+		acl("SETUSER", "alice", "x\r\n+OK").String)
+	assert.Equal(t, []string{"alice", "default"}, strs(acl("USERS")))
+	assert.Equal(t, []string{
+		"user alice on nopass ~app:* +@all -@dangerous",
+		"user default on nopass ~* +@all",
+	}, strs(acl("LIST")))
+
+	user := acl("GETUSER", "alice")
+	require.Equal(t, resp.RespTMap, user.T)
+	assert.Equal(t, []string{"on", "nopass"}, strs(user.Array[1]))
+	assert.Equal(t, "+@all -@dangerous", string(user.Array[5].Bulk))
+	assert.Equal(t, "~app:*", string(user.Array[7].Bulk))
+	assert.Equal(t, resp.RespTNull, acl("GETUSER", "nobody").T)
+
+	assert.Contains(t, strs(acl("CAT")), "dangerous")
+	assert.Equal(t, []string{"client|kill", "client|list", "client|pause", "client|unpause",
+		"config|get", "config|rewrite", "config|set", "info", "monitor", "shutdown", "slowlog|get", "slowlog|len", "slowlog|reset"},
+		strs(acl("CAT", "dangerous"))[8:])
+	assert.Equal(t, []string{"hget", "hgetall", "hset"}, strs(acl("CAT", "hash")))
+	assert.Equal(t, "ERR Unknown category 'no  pe'", acl("CAT", "no\r\npe").String)
+
+	// SAVE and LOAD round trip through the ACL file
+	assert.Equal(t, "OK", acl("SAVE").String)
+	assert.Equal(t, 1, acl("DELUSER", "alice").Number)
+	assert.Equal(t, "OK", acl("LOAD").String)
+	assert.Equal(t, []string{"alice", "default"}, strs(acl("USERS")))
+
+	require.NoError(t, os.WriteFile(path, []byte("user bob on +nosuch\n"), 0600))
+	assert.Contains(t, acl("LOAD").String, "users.acl:1")
+	assert.Equal(t, []string{"alice", "default"}, strs(acl("USERS")))
+
+	assert.Equal(t, "ERR The 'default' user cannot be removed", acl("DELUSER", "default").String)
+	assert.Equal(t, "ERR unknown subcommand 'nope'. Try ACL HELP.", acl("nope").String)
+	assert.Equal(t, "ERR unknown subcommand 'no  pe'. Try ACL HELP.", acl("no\r\npe").String)
+	assert.Equal(t, "ERR wrong number of arguments for 'acl|getuser' command", acl("GETUSER").String)
+}
+
+func strs(v resp.Value) []string {
+	res := make([]string, len(v.Array))
+	for i, item := range v.Array {
+		res[i] = string(item.Bulk)
+	}
+
+	return res
+}
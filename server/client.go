@@ -0,0 +1,471 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/helewud/redis-clone/commands"
+	"github.com/helewud/redis-clone/resp"
+)
+
+// clientInfo is what CLIENT LIST shows about a connection. The connection
+// goroutine keeps it current, other goroutines read it under conn.infoMu.
+type clientInfo struct {
+	name  string
+	user  string
+	db    int
+	proto resp.Protocol
+
+	// cmd is the last command run, active is when it arrived
+	cmd    string
+	active time.Time
+
+	// qbuf is how much of the input is buffered and not yet run
+	qbuf int
+}
+
+// begin records the command the connection is about to run
+func (c *conn) begin(cmd *commands.Command, args []resp.Value) {
+	name := cmd.Name
+	if sub, ok := cmd.Subcommand(args); ok {
+		name = sub.Name
+	}
+
+	c.infoMu.Lock()
+	c.info.cmd = name
+	c.info.active = time.Now()
+	c.info.qbuf = c.reader.Buffered()
+	c.infoMu.Unlock()
+}
+
+// lastCmd is the last command run, which log lines outside of a command
+// carry
+func (c *conn) lastCmd() string {
+	c.infoMu.Lock()
+	defer c.infoMu.Unlock()
+
+	return c.info.cmd
+}
+
+// syncSession publishes the session fields commands may have changed
+func (c *conn) syncSession() {
+	c.infoMu.Lock()
+	c.info.name = c.session.Name
+	c.info.user = c.session.User
+	c.info.db = c.session.DB
+	c.info.proto = c.session.Proto
+	c.infoMu.Unlock()
+}
+
+// describe is the line of the connection in CLIENT LIST
+func (c *conn) describe() string {
+	c.mu.Lock()
+	obl := c.writer.Buffered()
+	c.mu.Unlock()
+
+	c.infoMu.Lock()
+	defer c.infoMu.Unlock()
+
+	// monitors are flagged O, other clients N
+	flags := "N"
+	if c.monitoring.Load() {
+		flags = "O"
+	}
+
+	now := time.Now()
+	return fmt.Sprintf(
+		"id=%d addr=%s laddr=%s name=%s age=%d idle=%d flags=%s db=%d qbuf=%d obl=%d cmd=%s user=%s resp=%d",
+		c.session.ID, c.session.Addr, c.netConn.LocalAddr(), c.info.name,
+		int(now.Sub(c.created).Seconds()), int(now.Sub(c.info.active).Seconds()),
+		flags, c.info.db, c.info.qbuf, obl, c.info.cmd, c.info.user, c.info.proto,
+	)
+}
+
+// kill disconnects the client. A client killing itself gets its reply
+// first, the connection goroutine closes it afterwards.
+func (c *conn) kill(self bool) {
+	c.log.Info("client killed")
+	c.killed.Store(true)
+	if !self {
+		c.netConn.Close()
+	}
+}
+
+// client returns the connection with the given ID
+func (s *Server) client(id int64) (*conn, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.conns[id]
+	return c, ok
+}
+
+// clients returns the connections sorted by ID
+func (s *Server) clients() []*conn {
+	s.mu.Lock()
+	conns := make([]*conn, 0, len(s.conns))
+	for _, c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(conns, func(a, b *conn) int {
+		return int(a.session.ID - b.session.ID)
+	})
+
+	return conns
+}
+
+// pause holds clients back during CLIENT PAUSE
+type pause struct {
+	mu     sync.Mutex
+	until  time.Time
+	all    bool
+	lifted chan struct{}
+}
+
+// start pauses the clients until the given time, only writes unless all is
+// set. Pausing while paused keeps the longer and stricter of both.
+func (p *pause) start(until time.Time, all bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.lifted == nil || time.Now().After(p.until) {
+		p.until, p.all = until, all
+		if p.lifted == nil {
+			p.lifted = make(chan struct{})
+		}
+		return
+	}
+
+	if until.After(p.until) {
+		p.until = until
+	}
+	p.all = p.all || all
+}
+
+// lift releases the paused clients
+func (p *pause) lift() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.lifted != nil {
+		close(p.lifted)
+		p.lifted = nil
+	}
+}
+
+// wait blocks while commands like this one are paused
+func (p *pause) wait(write bool) {
+	for {
+		p.mu.Lock()
+		if p.lifted == nil || !(p.all || write) {
+			p.mu.Unlock()
+			return
+		}
+		remaining := time.Until(p.until)
+		lifted := p.lifted
+		p.mu.Unlock()
+
+		if remaining <= 0 {
+			return
+		}
+
+		timer := time.NewTimer(remaining)
+		select {
+		case <-timer.C:
+		case <-lifted:
+			timer.Stop()
+		}
+	}
+}
+
+// clientFilter selects the clients CLIENT KILL disconnects
+type clientFilter struct {
+	id     int64
+	addr   string
+	laddr  string
+	user   string
+	maxAge time.Duration
+	skipMe bool
+
+	// none is set by a TYPE other than normal, no client has it
+	none bool
+}
+
+func (f clientFilter) match(c *conn) bool {
+	if f.none {
+		return false
+	}
+	if f.id != 0 && c.session.ID != f.id {
+		return false
+	}
+	if f.addr != "" && c.session.Addr != f.addr {
+		return false
+	}
+	if f.laddr != "" && c.netConn.LocalAddr().String() != f.laddr {
+		return false
+	}
+	if f.maxAge != 0 && time.Since(c.created) < f.maxAge {
+		return false
+	}
+	if f.user != "" {
+		c.infoMu.Lock()
+		user := c.info.user
+		c.infoMu.Unlock()
+		if user != f.user {
+			return false
+		}
+	}
+
+	return true
+}
+
+func errorValue(msg string) resp.Value {
+	return resp.Value{T: resp.RespTError, String: msg}
+}
+
+var okValue = resp.Value{T: resp.RespTString, String: "OK"}
+
+// clientCommand answers the CLIENT subcommands
+func (s *Server) clientCommand(session *commands.Session, args []resp.Value) resp.Value {
+	if len(args) == 0 {
+		return errorValue("ERR wrong number of arguments for 'client' command")
+	}
+
+	name := string(args[0].Bulk)
+	subcommand := strings.ToUpper(name)
+	strs := make([]string, len(args)-1)
+	for i, arg := range args[1:] {
+		strs[i] = string(arg.Bulk)
+	}
+
+	wrongArgs := errorValue(fmt.Sprintf("ERR wrong number of arguments for 'client|%s' command", strings.ToLower(subcommand)))
+
+	switch subcommand {
+	case "ID":
+		if len(strs) != 0 {
+			return wrongArgs
+		}
+		return resp.Value{T: resp.RespTInteger, Number: int(session.ID)}
+	case "SETNAME":
+		if len(strs) != 1 {
+			return wrongArgs
+		}
+		if !commands.ValidClientName(strs[0]) {
+			return errorValue("ERR Client names cannot contain spaces, newlines or special characters.")
+		}
+		session.Name = strs[0]
+		return okValue
+	case "GETNAME":
+		if len(strs) != 0 {
+			return wrongArgs
+		}
+		if session.Name == "" {
+			return resp.Value{T: resp.RespTNull}
+		}
+		return bulk(session.Name)
+	case "INFO":
+		if len(strs) != 0 {
+			return wrongArgs
+		}
+		c, ok := s.client(session.ID)
+		if !ok {
+			return resp.Value{T: resp.RespTNull}
+		}
+		return bulk(c.describe() + "\n")
+	case "LIST":
+		return s.clientList(strs)
+	case "KILL":
+		return s.clientKill(session, strs)
+	case "PAUSE":
+		if len(strs) < 1 || len(strs) > 2 {
+			return wrongArgs
+		}
+		ms, err := strconv.Atoi(strs[0])
+		if err != nil || ms < 0 {
+			return errorValue("ERR timeout is not an integer or out of range")
+		}
+		all := true
+		if len(strs) == 2 {
+			switch strings.ToUpper(strs[1]) {
+			case "ALL":
+			case "WRITE":
+				all = false
+			default:
+				return errorValue("ERR syntax error")
+			}
+		}
+		s.pause.start(time.Now().Add(time.Duration(ms)*time.Millisecond), all)
+		return okValue
+	case "UNPAUSE":
+		if len(strs) != 0 {
+			return wrongArgs
+		}
+		s.pause.lift()
+		return okValue
+	case "HELP":
+		return helpReply(
+			"CLIENT <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+			"GETNAME",
+			"    Return the name of the current connection.",
+			"ID",
+			"    Return the ID of the current connection.",
+			"INFO",
+			"    Return information about the current client connection.",
+			"KILL <ip:port>",
+			"    Kill connection made from <ip:port>.",
+			"KILL <option> <value> [<option> <value> [...]]",
+			"    Kill connections. Options are:",
+			"    * ADDR (<ip:port>|<unixsocket>:0)",
+			"      Kill connections made from the specified address",
+			"    * LADDR (<ip:port>|<unixsocket>:0)",
+			"      Kill connections made to specified local address",
+			"    * ID <client-id>",
+			"      Kill connections by client id.",
+			"    * USER <username>",
+			"      Kill connections authenticated by <username>.",
+			"    * MAXAGE <maxage>",
+			"      Kill connections older than the specified age.",
+			"    * SKIPME (YES|NO)",
+			"      Skip killing current connection (default: yes).",
+			"LIST [TYPE (NORMAL|MASTER|REPLICA|PUBSUB)] [ID <id> [<id> ...]]",
+			"    Return information about client connections.",
+			"PAUSE <timeout> [WRITE|ALL]",
+			"    Suspend all, or just write, clients for <timeout> milliseconds.",
+			"UNPAUSE",
+			"    Stop the current client pause, resuming traffic.",
+			"SETNAME <name>",
+			"    Assign the name <name> to the current connection.",
+			"HELP",
+			"    Print this help.",
+		)
+	default:
+		return commands.ErrorReply(fmt.Sprintf("ERR unknown subcommand '%s'. Try CLIENT HELP.", name))
+	}
+}
+
+// clientList answers CLIENT LIST [TYPE type] [ID id ...]
+func (s *Server) clientList(args []string) resp.Value {
+	var ids []int64
+	normal := true
+	for i := 0; i < len(args); i++ {
+		switch {
+		case strings.EqualFold(args[i], "TYPE") && i+1 < len(args):
+			i++
+			switch strings.ToLower(args[i]) {
+			case "normal":
+			case "master", "replica", "slave", "pubsub":
+				normal = false
+			default:
+				return commands.ErrorReply(fmt.Sprintf("ERR Unknown client type '%s'", args[i]))
+			}
+		case strings.EqualFold(args[i], "ID") && i+1 < len(args):
+			for i++; i < len(args); i++ {
+				id, err := strconv.ParseInt(args[i], 10, 64)
+				if err != nil || id <= 0 {
+					return errorValue("ERR Invalid client ID")
+				}
+				ids = append(ids, id)
+			}
+		default:
+			return errorValue("ERR syntax error")
+		}
+	}
+
+	var b strings.Builder
+	if normal {
+		for _, c := range s.clients() {
+			if ids == nil || slices.Contains(ids, c.session.ID) {
+				b.WriteString(c.describe())
+				b.WriteByte('\n')
+			}
+		}
+	}
+
+	return bulk(b.String())
+}
+
+// clientKill answers CLIENT KILL addr and CLIENT KILL filter value ...
+func (s *Server) clientKill(session *commands.Session, args []string) resp.Value {
+	switch len(args) {
+	case 0:
+		return errorValue("ERR wrong number of arguments for 'client|kill' command")
+	case 1:
+		// the old form kills one client by address, itself included
+		for _, c := range s.clients() {
+			if c.session.Addr == args[0] {
+				c.kill(c.session == session)
+				return okValue
+			}
+		}
+		return errorValue("ERR No such client")
+	}
+
+	if len(args)%2 != 0 {
+		return errorValue("ERR syntax error")
+	}
+
+	filter := clientFilter{skipMe: true}
+	for i := 0; i < len(args); i += 2 {
+		value := args[i+1]
+		switch strings.ToUpper(args[i]) {
+		case "ID":
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || id <= 0 {
+				return errorValue("ERR client-id should be greater than 0")
+			}
+			filter.id = id
+		case "ADDR":
+			filter.addr = value
+		case "LADDR":
+			filter.laddr = value
+		case "USER":
+			if _, ok := s.acl.User(value); !ok {
+				return commands.ErrorReply(fmt.Sprintf("ERR No such user '%s'", value))
+			}
+			filter.user = value
+		case "MAXAGE":
+			age, err := strconv.Atoi(value)
+			if err != nil || age <= 0 {
+				return errorValue("ERR syntax error")
+			}
+			filter.maxAge = time.Duration(age) * time.Second
+		case "SKIPME":
+			switch strings.ToLower(value) {
+			case "yes":
+				filter.skipMe = true
+			case "no":
+				filter.skipMe = false
+			default:
+				return errorValue("ERR syntax error")
+			}
+		case "TYPE":
+			switch strings.ToLower(value) {
+			case "normal":
+			case "master", "replica", "slave", "pubsub":
+				filter.none = true
+			default:
+				return commands.ErrorReply(fmt.Sprintf("ERR Unknown client type '%s'", value))
+			}
+		default:
+			return errorValue("ERR syntax error")
+		}
+	}
+
+	killed := 0
+	for _, c := range s.clients() {
+		self := c.session == session
+		if (self && filter.skipMe) || !filter.match(c) {
+			continue
+		}
+		c.kill(self)
+		killed++
+	}
+
+	return resp.Value{T: resp.RespTInteger, Number: killed}
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/helewud/redis-clone/commands"
+	"github.com/helewud/redis-clone/config"
+	"github.com/helewud/redis-clone/resp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fields parses a CLIENT LIST line
+func fields(line string) map[string]string {
+	res := map[string]string{}
+	for _, field := range strings.Fields(line) {
+		k, v, _ := strings.Cut(field, "=")
+		res[k] = v
+	}
+
+	return res
+}
+
+func TestClientNameAndInfo(t *testing.T) {
+	_, _, addr, _ := serve(t)
+	c := dial(t, addr)
+
+	id := c.do("CLIENT", "ID").Number
+	assert.Positive(t, id)
+	assert.Equal(t, resp.RespTNull, c.do("CLIENT", "GETNAME").T)
+	assert.Equal(t, "OK", c.do("CLIENT", "SETNAME", "worker-1").String)
+	assert.Equal(t, "worker-1", string(c.do("CLIENT", "GETNAME").Bulk))
+	assert.Equal(t, "ERR Client names cannot contain spaces, newlines or special characters.",
+		c.do("CLIENT", "SETNAME", "bad name").String)
+
+	info := fields(string(c.do("CLIENT", "INFO").Bulk))
+	assert.Equal(t, fmt.Sprint(id), info["id"])
+	assert.Equal(t, c.conn.LocalAddr().String(), info["addr"])
+	assert.Equal(t, addr, info["laddr"])
+	assert.Equal(t, "worker-1", info["name"])
+	assert.Equal(t, "client|info", info["cmd"])
+	assert.Equal(t, "default", info["user"])
+	assert.Equal(t, "2", info["resp"])
+
+	other := dial(t, addr)
+	other.do("PING")
+	list := strings.Split(strings.TrimSuffix(string(c.do("CLIENT", "LIST").Bulk), "\n"), "\n")
+	require.Len(t, list, 2)
+	assert.Equal(t, "worker-1", fields(list[0])["name"])
+	assert.Equal(t, "ping", fields(list[1])["cmd"])
+
+	list = strings.Split(strings.TrimSuffix(string(c.do("CLIENT", "LIST", "ID", fmt.Sprint(id)).Bulk), "\n"), "\n")
+	require.Len(t, list, 1)
+	assert.Empty(t, string(c.do("CLIENT", "LIST", "TYPE", "pubsub").Bulk))
+	assert.Equal(t, "ERR Unknown client type 'no  pe'", c.do("CLIENT", "LIST", "TYPE", "no\r\npe").String)
+	assert.Equal(t, "ERR unknown subcommand 'no pe'. Try CLIENT HELP.", c.do("CLIENT", "no\npe").String)
+}
+
+func TestClientKill(t *testing.T) {
+	s, _, addr, _ := serve(t)
+	require.NoError(t, s.acl.SetUser("bob", "on", ">pw", "+@all", "~*"))
+
+	admin := dial(t, addr)
+	victim := dial(t, addr)
+	bob := dial(t, addr)
+	assert.Equal(t, "OK", bob.do("AUTH", "bob", "pw").String)
+
+	closed := func(c *client) {
+		t.Helper()
+		c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, err := c.reader.Read()
+		assert.Equal(t, io.EOF, err)
+	}
+
+	assert.Equal(t, "OK", admin.do("CLIENT", "KILL", victim.conn.LocalAddr().String()).String)
+	closed(victim)
+	assert.Equal(t, "ERR No such client", admin.do("CLIENT", "KILL", "127.0.0.1:1").String)
+
+	assert.Equal(t, 1, admin.do("CLIENT", "KILL", "USER", "bob").Number)
+	closed(bob)
+	assert.Equal(t, "ERR No such user 'nobody'", admin.do("CLIENT", "KILL", "USER", "nobody").String)
+	assert.Equal(t, "ERR No such user 'no  body'", admin.do("CLIENT", "KILL", "USER", "no\r\nbody").String)
+
+	// a client skips itself unless asked not to, and gets the reply first
+	id := fmt.Sprint(admin.do("CLIENT", "ID").Number)
+	assert.Equal(t, 0, admin.do("CLIENT", "KILL", "ID", id).Number)
+	assert.Equal(t, 1, admin.do("CLIENT", "KILL", "ID", id, "SKIPME", "no").Number)
+	closed(admin)
+}
+
+func TestClientPause(t *testing.T) {
+	_, _, addr, _ := serve(t)
+
+	admin := dial(t, addr)
+	writer := dial(t, addr)
+	admin.do("DEL", "paused")
+	assert.Equal(t, "OK", admin.do("CLIENT", "PAUSE", "10000", "WRITE").String)
+
+	_, err := writer.conn.Write(resp.Value{T: resp.RespTArray, Array: bulks("SET", "paused", "v")}.Marshal())
+	require.NoError(t, err)
+	replied := make(chan resp.Value, 1)
+	go func() {
+		reply, _ := writer.reader.Read()
+		replied <- reply
+	}()
+
+	// reads go on during a write pause
+	assert.Equal(t, resp.RespTNull, admin.do("GET", "paused").T)
+	select {
+	case reply := <-replied:
+		t.Fatalf("SET ran during the pause: %v", reply)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	assert.Equal(t, "OK", admin.do("CLIENT", "UNPAUSE").String)
+	select {
+	case reply := <-replied:
+		assert.Equal(t, "OK", reply.String)
+	case <-time.After(5 * time.Second):
+		t.Fatal("SET still paused")
+	}
+
+	// a pause ends on its own
+	assert.Equal(t, "OK", admin.do("CLIENT", "PAUSE", "50").String)
+	start := time.Now()
+	assert.Equal(t, "PONG", writer.do("PING").String)
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	assert.Equal(t, "ERR timeout is not an integer or out of range", admin.do("CLIENT", "PAUSE", "soon").String)
+}
+
+func TestMaxClients(t *testing.T) {
+	cfg := config.Default()
+	cfg.MaxClients = 1
+	s, _, addr, _ := serveConfig(t, cfg)
+
+	first := dial(t, addr)
+	assert.Equal(t, "PONG", first.do("PING").String)
+
+	second := dial(t, addr)
+	reply, err := second.reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "ERR max number of clients reached", reply.String)
+	_, err = second.reader.Read()
+	assert.Equal(t, io.EOF, err)
+
+	session := commands.NewSession(0, "test")
+	assert.Equal(t, "OK", s.configCommand(session, bulks("SET", "maxclients", "2")).String)
+	assert.Equal(t, "PONG", dial(t, addr).do("PING").String)
+}
+
+func TestIdleTimeout(t *testing.T) {
+	cfg := config.Default()
+	cfg.Timeout = 200 * time.Millisecond
+	_, _, addr, _ := serveConfig(t, cfg)
+
+	c := dial(t, addr)
+	assert.Equal(t, "PONG", c.do("PING").String)
+
+	// every command restarts the clock
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "PONG", c.do("PING").String)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "PONG", c.do("PING").String)
+
+	start := time.Now()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err := c.reader.Read()
+	assert.Equal(t, io.EOF, err)
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
@@ -0,0 +1,287 @@
+package server
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/helewud/redis-clone/acl"
+	"github.com/helewud/redis-clone/commands"
+	"github.com/helewud/redis-clone/resp"
+)
+
+// lookupCommand finds a command, or a container|subcommand
+func (s *Server) lookupCommand(name string) (*commands.Command, bool) {
+	name, sub, hasSub := strings.Cut(name, "|")
+	cmd, ok := s.commands.Lookup(name)
+	if !ok || !hasSub {
+		return cmd, ok
+	}
+
+	cmd, ok = cmd.Subcommands[strings.ToLower(sub)]
+	return cmd, ok
+}
+
+// sortedSubcommands returns the subcommands of cmd by name
+func sortedSubcommands(cmd *commands.Command) []*commands.Command {
+	subs := slices.Collect(maps.Values(cmd.Subcommands))
+	slices.SortFunc(subs, func(a, b *commands.Command) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return subs
+}
+
+func simpleSet(strs []string) resp.Value {
+	v := resp.Value{T: resp.RespTSet, Array: make([]resp.Value, len(strs))}
+	for i, s := range strs {
+		v.Array[i] = resp.Value{T: resp.RespTString, String: s}
+	}
+
+	return v
+}
+
+func integer(n int) resp.Value {
+	return resp.Value{T: resp.RespTInteger, Number: n}
+}
+
+// commandInfo is the reply of COMMAND INFO for cmd
+func commandInfo(cmd *commands.Command) resp.Value {
+	categories := make([]string, len(cmd.Categories))
+	for i, category := range cmd.Categories {
+		categories[i] = "@" + category
+	}
+
+	keySpecs := resp.Value{T: resp.RespTArray, Array: []resp.Value{}}
+	if spec := cmd.Keys; spec.First != 0 {
+		lastKey := spec.Last
+		if lastKey >= 0 {
+			lastKey -= spec.First
+		}
+		keySpecs.Array = append(keySpecs.Array, resp.Value{
+			T: resp.RespTMap,
+			Array: []resp.Value{
+				bulk("flags"), simpleSet(spec.Flags),
+				bulk("begin_search"), {T: resp.RespTMap, Array: []resp.Value{
+					bulk("type"), bulk("index"),
+					bulk("spec"), {T: resp.RespTMap, Array: []resp.Value{bulk("index"), integer(spec.First)}},
+				}},
+				bulk("find_keys"), {T: resp.RespTMap, Array: []resp.Value{
+					bulk("type"), bulk("range"),
+					bulk("spec"), {T: resp.RespTMap, Array: []resp.Value{
+						bulk("lastkey"), integer(lastKey),
+						bulk("keystep"), integer(spec.Step),
+						bulk("limit"), integer(0),
+					}},
+				}},
+			},
+		})
+	}
+
+	subs := resp.Value{T: resp.RespTArray, Array: []resp.Value{}}
+	for _, sub := range sortedSubcommands(cmd) {
+		subs.Array = append(subs.Array, commandInfo(sub))
+	}
+
+	flags := cmd.Flags
+	if flags == nil {
+		flags = []string{}
+	}
+
+	return resp.Value{
+		T: resp.RespTArray,
+		Array: []resp.Value{
+			bulk(cmd.Name),
+			integer(cmd.Arity),
+			simpleSet(flags),
+			integer(cmd.Keys.First),
+			integer(cmd.Keys.Last),
+			integer(cmd.Keys.Step),
+			simpleSet(categories),
+			{T: resp.RespTArray, Array: []resp.Value{}},
+			keySpecs,
+			subs,
+		},
+	}
+}
+
+// commandDocs is the reply of COMMAND DOCS for cmd
+func commandDocs(cmd *commands.Command) resp.Value {
+	docs := resp.Value{
+		T: resp.RespTMap,
+		Array: []resp.Value{
+			bulk("summary"), bulk(cmd.Summary),
+			bulk("since"), bulk(cmd.Since),
+			bulk("group"), bulk(cmd.Group),
+			bulk("complexity"), bulk(cmd.Complexity),
+		},
+	}
+
+	if len(cmd.Args) > 0 {
+		args := resp.Value{T: resp.RespTArray, Array: make([]resp.Value, len(cmd.Args))}
+		for i, arg := range cmd.Args {
+			v := resp.Value{
+				T:     resp.RespTMap,
+				Array: []resp.Value{bulk("name"), bulk(arg.Name), bulk("type"), bulk(arg.Type)},
+			}
+			if arg.Type == "key" {
+				v.Array = append(v.Array, bulk("key_spec_index"), integer(0))
+			}
+
+			var flags []string
+			if arg.Optional {
+				flags = append(flags, "optional")
+			}
+			if arg.Multiple {
+				flags = append(flags, "multiple")
+			}
+			if flags != nil {
+				v.Array = append(v.Array, bulk("flags"), simpleSet(flags))
+			}
+			args.Array[i] = v
+		}
+		docs.Array = append(docs.Array, bulk("arguments"), args)
+	}
+
+	if cmd.Subcommands != nil {
+		subs := resp.Value{T: resp.RespTMap}
+		for _, sub := range sortedSubcommands(cmd) {
+			subs.Array = append(subs.Array, bulk(sub.Name), commandDocs(sub))
+		}
+		docs.Array = append(docs.Array, bulk("subcommands"), subs)
+	}
+
+	return docs
+}
+
+// commandCommand answers COMMAND and its subcommands
+func (s *Server) commandCommand(session *commands.Session, args []resp.Value) resp.Value {
+	if len(args) == 0 {
+		return s.commandInfos(s.commands.Names())
+	}
+
+	name := string(args[0].Bulk)
+	strs := make([]string, len(args)-1)
+	for i, arg := range args[1:] {
+		strs[i] = string(arg.Bulk)
+	}
+
+	switch strings.ToUpper(name) {
+	case "COUNT":
+		return integer(len(s.commands))
+	case "INFO":
+		if len(strs) == 0 {
+			strs = s.commands.Names()
+		}
+		return s.commandInfos(strs)
+	case "DOCS":
+		if len(strs) == 0 {
+			strs = s.commands.Names()
+		}
+		res := resp.Value{T: resp.RespTMap, Array: []resp.Value{}}
+		for _, name := range strs {
+			if cmd, ok := s.lookupCommand(name); ok {
+				res.Array = append(res.Array, bulk(cmd.Name), commandDocs(cmd))
+			}
+		}
+		return res
+	case "LIST":
+		return s.commandList(strs)
+	case "GETKEYS":
+		if len(strs) == 0 {
+			return errorValue("ERR wrong number of arguments for 'command|getkeys' command")
+		}
+		cmd, ok := s.commands.Lookup(strs[0])
+		if !ok {
+			return errorValue("ERR Invalid command specified")
+		}
+		cmdArgs := args[2:]
+		if !cmd.CheckArity(cmdArgs) {
+			return errorValue("ERR Invalid number of arguments specified for command")
+		}
+		keys := cmd.KeyArgs(cmdArgs)
+		if len(keys) == 0 {
+			return errorValue("ERR The command has no key arguments")
+		}
+		return bulkArray(keys)
+	case "HELP":
+		return helpReply(
+			"COMMAND <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+			"(no subcommand)",
+			"    Return details about all Redis commands.",
+			"COUNT",
+			"    Return the total number of commands in this Redis server.",
+			"LIST",
+			"    Return a list of all commands in this Redis server.",
+			"INFO [<command-name> ...]",
+			"    Return details about multiple Redis commands.",
+			"    If no command names are given, documentation details for all",
+			"    commands are returned.",
+			"DOCS [<command-name> ...]",
+			"    Return documentation details about multiple Redis commands.",
+			"    If no command names are given, documentation details for all",
+			"    commands are returned.",
+			"GETKEYS <full-command>",
+			"    Return the keys from a full Redis command.",
+			"HELP",
+			"    Print this help.",
+		)
+	default:
+		return commands.ErrorReply(fmt.Sprintf("ERR unknown subcommand '%s'. Try COMMAND HELP.", name))
+	}
+}
+
+// commandInfos is the COMMAND INFO reply for names, null for unknown ones
+func (s *Server) commandInfos(names []string) resp.Value {
+	res := resp.Value{T: resp.RespTArray, Array: make([]resp.Value, len(names))}
+	for i, name := range names {
+		if cmd, ok := s.lookupCommand(name); ok {
+			res.Array[i] = commandInfo(cmd)
+		} else {
+			res.Array[i] = resp.Value{T: resp.RespTNull}
+		}
+	}
+
+	return res
+}
+
+// commandList answers COMMAND LIST [FILTERBY MODULE name|ACLCAT category|PATTERN pattern]
+func (s *Server) commandList(args []string) resp.Value {
+	match := func(*commands.Command) bool { return true }
+
+	switch {
+	case len(args) == 0:
+	case len(args) == 3 && strings.EqualFold(args[0], "FILTERBY"):
+		value := args[2]
+		switch strings.ToUpper(args[1]) {
+		case "MODULE":
+			// there are no modules
+			match = func(*commands.Command) bool { return false }
+		case "ACLCAT":
+			match = func(cmd *commands.Command) bool { return slices.Contains(cmd.Categories, strings.ToLower(value)) }
+		case "PATTERN":
+			match = func(cmd *commands.Command) bool { return acl.Match(strings.ToLower(value), cmd.Name) }
+		default:
+			return errorValue("ERR syntax error")
+		}
+	default:
+		return errorValue("ERR syntax error")
+	}
+
+	// subcommands are listed along with their containers
+	var names []string
+	for _, name := range s.commands.Names() {
+		cmd := s.commands[name]
+		if match(cmd) {
+			names = append(names, cmd.Name)
+		}
+		for _, sub := range sortedSubcommands(cmd) {
+			if match(sub) {
+				names = append(names, sub.Name)
+			}
+		}
+	}
+
+	return bulkArray(names)
+}
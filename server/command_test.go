@@ -0,0 +1,115 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/helewud/redis-clone/resp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandInfo(t *testing.T) {
+	_, _, addr, _ := serve(t)
+	c := dial(t, addr)
+
+	count := c.do("COMMAND", "COUNT").Number
+	assert.Len(t, c.do("COMMAND").Array, count)
+
+	infos := c.do("COMMAND", "INFO", "get", "nope", "config|set")
+	require.Len(t, infos.Array, 3)
+
+	get := infos.Array[0].Array
+	assert.Equal(t, "get", string(get[0].Bulk))
+	assert.Equal(t, 2, get[1].Number)
+	assert.Equal(t, "readonly", get[2].Array[0].String)
+	assert.Equal(t, []int{1, 1, 1}, []int{get[3].Number, get[4].Number, get[5].Number})
+	assert.Equal(t, "@read", get[6].Array[0].String)
+	require.Len(t, get[8].Array, 1)
+	assert.Equal(t, resp.RespTNull, infos.Array[1].T)
+	assert.Equal(t, "config|set", string(infos.Array[2].Array[0].Bulk))
+	assert.Equal(t, -4, infos.Array[2].Array[1].Number)
+
+	config := c.do("COMMAND", "INFO", "config").Array[0].Array
+	assert.Len(t, config[9].Array, 4)
+
+	docs := c.do("COMMAND", "DOCS", "hset")
+	require.Len(t, docs.Array, 2)
+	assert.Equal(t, "hset", string(docs.Array[0].Bulk))
+	assert.Equal(t, "summary", string(docs.Array[1].Array[0].Bulk))
+	assert.Equal(t, "Creates or modifies the value of a field in a hash.", string(docs.Array[1].Array[1].Bulk))
+
+	assert.Equal(t, []string{"hget", "hgetall", "hset"}, strs(c.do("COMMAND", "LIST", "FILTERBY", "ACLCAT", "hash")))
+	assert.Equal(t, []string{"client|kill"}, strs(c.do("COMMAND", "LIST", "FILTERBY", "PATTERN", "client|k*")))
+	assert.Equal(t, "ERR unknown subcommand 'no  pe'. Try COMMAND HELP.", c.do("COMMAND", "no\r\npe").String)
+}
+
+func TestCommandGetKeys(t *testing.T) {
+	_, _, addr, _ := serve(t)
+	c := dial(t, addr)
+
+	tests := []struct {
+		args []string
+		want resp.Value
+	}{
+		{
+			args: []string{"SET", "k", "v"},
+			want: resp.Value{T: resp.RespTArray, Array: bulks("k")},
+		},
+		{
+			args: []string{"HSET", "h", "f", "v"},
+			want: resp.Value{T: resp.RespTArray, Array: bulks("h")},
+		},
+		{
+			args: []string{"PING"},
+			want: resp.Value{T: resp.RespTError, String: "ERR The command has no key arguments"},
+		},
+		{
+			args: []string{"GET"},
+			want: resp.Value{T: resp.RespTError, String: "ERR Invalid number of arguments specified for command"},
+		},
+		{
+			args: []string{"NOPE", "k"},
+			want: resp.Value{T: resp.RespTError, String: "ERR Invalid command specified"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args[0], func(t *testing.T) {
+			assert.Equal(t, tt.want, c.do(append([]string{"COMMAND", "GETKEYS"}, tt.args...)...))
+		})
+	}
+}
+
+func TestDispatchArity(t *testing.T) {
+	_, _, addr, _ := serve(t)
+	c := dial(t, addr)
+
+	assert.Equal(t, "ERR wrong number of arguments for 'get' command", c.do("GET").String)
+	assert.Equal(t, "ERR wrong number of arguments for 'hset' command", c.do("hset", "h", "f").String)
+	assert.Equal(t, "ERR wrong number of arguments for 'config|get' command", c.do("CONFIG", "GET").String)
+	assert.Equal(t, "ERR wrong number of arguments for 'client|setname' command", c.do("CLIENT", "SETNAME").String)
+	assert.Equal(t, "ERR unknown subcommand 'nope'. Try CLIENT HELP.", c.do("CLIENT", "nope").String)
+}
+
+func TestWritesPersisted(t *testing.T) {
+	_, store, addr, _ := serve(t)
+	c := dial(t, addr)
+
+	c.do("SET", "persisted", "v")
+	c.do("GET", "persisted")
+	c.do("HSET", "persisted:h", "f", "v")
+	c.do("DEL", "persisted")
+	c.do("SET", "persisted")
+	c.do("CONFIG", "SET", "timeout", "0")
+
+	var logged [][]string
+	require.NoError(t, store.Read(func(value resp.Value) error {
+		logged = append(logged, strs(value))
+		return nil
+	}))
+	assert.Equal(t, [][]string{
+		{"SET", "persisted", "v"},
+		{"HSET", "persisted:h", "f", "v"},
+		{"DEL", "persisted"},
+	}, logged)
+}
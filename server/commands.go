@@ -0,0 +1,281 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/helewud/redis-clone/commands"
+)
+
+var (
+	adminCategories       = []string{"admin", "slow", "dangerous"}
+	connectionCategories  = []string{"slow", "connection"}
+	clientAdminCategories = []string{"admin", "slow", "dangerous", "connection"}
+
+	adminFlags  = []string{commands.FlagAdmin, commands.FlagNoScript, commands.FlagLoading, commands.FlagStale}
+	scriptFlags = []string{commands.FlagNoScript, commands.FlagLoading, commands.FlagStale}
+	staleFlags  = []string{commands.FlagLoading, commands.FlagStale}
+)
+
+// subcommands indexes the subcommands of a container by the name after |
+func subcommands(cmds ...*commands.Command) map[string]*commands.Command {
+	m := make(map[string]*commands.Command, len(cmds))
+	for _, cmd := range cmds {
+		_, name, _ := strings.Cut(cmd.Name, "|")
+		m[name] = cmd
+	}
+
+	return m
+}
+
+// help is the HELP subcommand of container
+func help(container string) *commands.Command {
+	return &commands.Command{
+		Name:       container + "|help",
+		Arity:      2,
+		Flags:      staleFlags,
+		Categories: []string{"slow"},
+		Summary:    "Returns helpful text about the different subcommands.",
+		Group:      "server",
+		Complexity: "O(1)",
+	}
+}
+
+// serverCommands are the commands acting on the server itself
+func (s *Server) serverCommands() []*commands.Command {
+	return []*commands.Command{
+		{
+			Name:       "shutdown",
+			Handler:    s.shutdownCommand,
+			Arity:      -1,
+			Flags:      adminFlags,
+			Categories: adminCategories,
+			Summary:    "Synchronously saves the database(s) to disk and shuts down the Redis server.",
+			Since:      "1.0.0",
+			Group:      "server",
+			Complexity: "O(N) when saving, where N is the total number of keys in all databases when saving data, otherwise O(1)",
+			Args:       []commands.Arg{{Name: "save-selector", Type: "oneof", Optional: true}},
+		},
+		{
+			Name:       "auth",
+			Handler:    s.authCommand,
+			Arity:      -2,
+			Flags:      []string{commands.FlagNoScript, commands.FlagLoading, commands.FlagStale, commands.FlagFast, commands.FlagNoAuth},
+			Categories: []string{"fast", "connection"},
+			Summary:    "Authenticates the connection.",
+			Since:      "1.0.0",
+			Group:      "connection",
+			Complexity: "O(N) where N is the number of passwords defined for the user",
+			Args: []commands.Arg{
+				{Name: "username", Type: "string", Optional: true},
+				{Name: "password", Type: "string"},
+			},
+		},
+		{
+			Name:       "config",
+			Handler:    s.configCommand,
+			Arity:      -2,
+			Categories: []string{"slow"},
+			Summary:    "A container for server configuration commands.",
+			Since:      "2.0.0",
+			Group:      "server",
+			Complexity: "Depends on subcommand.",
+			Subcommands: subcommands(
+				&commands.Command{Name: "config|get", Arity: -3, Flags: adminFlags, Categories: adminCategories,
+					Summary: "Returns the effective values of configuration parameters.", Since: "2.0.0", Group: "server",
+					Complexity: "O(N) when N is the number of configuration parameters provided",
+					Args:       []commands.Arg{{Name: "parameter", Type: "string", Multiple: true}}},
+				&commands.Command{Name: "config|set", Arity: -4, Flags: adminFlags, Categories: adminCategories,
+					Summary: "Sets configuration parameters in-flight.", Since: "2.0.0", Group: "server",
+					Complexity: "O(N) when N is the number of configuration parameters provided",
+					Args: []commands.Arg{
+						{Name: "parameter", Type: "string", Multiple: true},
+						{Name: "value", Type: "string", Multiple: true},
+					}},
+				&commands.Command{Name: "config|rewrite", Arity: 2, Flags: adminFlags, Categories: adminCategories,
+					Summary: "Persists the effective configuration to file.", Since: "2.8.0", Group: "server",
+					Complexity: "O(1)"},
+				help("config"),
+			),
+		},
+		{
+			Name:       "acl",
+			Handler:    s.aclCommand,
+			Arity:      -2,
+			Categories: []string{"slow"},
+			Summary:    "A container for Access List Control commands.",
+			Since:      "6.0.0",
+			Group:      "server",
+			Complexity: "Depends on subcommand.",
+			Subcommands: subcommands(
+				&commands.Command{Name: "acl|cat", Arity: -2, Flags: scriptFlags, Categories: []string{"slow"},
+					Summary: "Lists the ACL categories, or the commands inside a category.", Since: "6.0.0", Group: "server",
+					Complexity: "O(1) since the categories and commands are a fixed set.",
+					Args:       []commands.Arg{{Name: "category", Type: "string", Optional: true}}},
+				&commands.Command{Name: "acl|deluser", Arity: -3, Flags: adminFlags, Categories: adminCategories,
+					Summary: "Deletes ACL users, and terminates their connections.", Since: "6.0.0", Group: "server",
+					Complexity: "O(1) amortized time considering the typical user.",
+					Args:       []commands.Arg{{Name: "username", Type: "string", Multiple: true}}},
+				&commands.Command{Name: "acl|getuser", Arity: 3, Flags: adminFlags, Categories: adminCategories,
+					Summary: "Lists the ACL rules of a user.", Since: "6.0.0", Group: "server",
+					Complexity: "O(N). Where N is the number of password, command and pattern rules that the user has.",
+					Args:       []commands.Arg{{Name: "username", Type: "string"}}},
+				&commands.Command{Name: "acl|list", Arity: 2, Flags: adminFlags, Categories: adminCategories,
+					Summary: "Dumps the effective rules in ACL file format.", Since: "6.0.0", Group: "server",
+					Complexity: "O(N). Where N is the number of configured users."},
+				&commands.Command{Name: "acl|load", Arity: 2, Flags: adminFlags, Categories: adminCategories,
+					Summary: "Reloads the rules from the configured ACL file.", Since: "6.0.0", Group: "server",
+					Complexity: "O(N). Where N is the number of configured users."},
+				&commands.Command{Name: "acl|log", Arity: -2, Flags: adminFlags, Categories: adminCategories,
+					Summary: "Lists recent security events generated due to ACL rules.", Since: "6.0.0", Group: "server",
+					Complexity: "O(N) with N being the number of entries shown.",
+					Args:       []commands.Arg{{Name: "operation", Type: "oneof", Optional: true}}},
+				&commands.Command{Name: "acl|save", Arity: 2, Flags: adminFlags, Categories: adminCategories,
+					Summary: "Saves the effective ACL rules in the configured ACL file.", Since: "6.0.0", Group: "server",
+					Complexity: "O(N). Where N is the number of configured users."},
+				&commands.Command{Name: "acl|setuser", Arity: -3, Flags: adminFlags, Categories: adminCategories,
+					Summary: "Creates and modifies an ACL user and its rules.", Since: "6.0.0", Group: "server",
+					Complexity: "O(N). Where N is the number of rules provided.",
+					Args: []commands.Arg{
+						{Name: "username", Type: "string"},
+						{Name: "rule", Type: "string", Optional: true, Multiple: true},
+					}},
+				&commands.Command{Name: "acl|users", Arity: 2, Flags: adminFlags, Categories: adminCategories,
+					Summary: "Lists all ACL users.", Since: "6.0.0", Group: "server",
+					Complexity: "O(N). Where N is the number of configured users."},
+				&commands.Command{Name: "acl|whoami", Arity: 2, Flags: scriptFlags, Categories: []string{"slow"},
+					Summary: "Returns the authenticated username of the current connection.", Since: "6.0.0", Group: "server",
+					Complexity: "O(1)"},
+				help("acl"),
+			),
+		},
+		{
+			Name:       "client",
+			Handler:    s.clientCommand,
+			Arity:      -2,
+			Categories: connectionCategories,
+			Summary:    "A container for client connection commands.",
+			Since:      "2.4.0",
+			Group:      "connection",
+			Complexity: "Depends on subcommand.",
+			Subcommands: subcommands(
+				&commands.Command{Name: "client|id", Arity: 2, Flags: scriptFlags, Categories: connectionCategories,
+					Summary: "Returns the unique client ID of the connection.", Since: "5.0.0", Group: "connection",
+					Complexity: "O(1)"},
+				&commands.Command{Name: "client|info", Arity: 2, Flags: scriptFlags, Categories: connectionCategories,
+					Summary: "Returns information about the connection.", Since: "6.2.0", Group: "connection",
+					Complexity: "O(1)"},
+				&commands.Command{Name: "client|getname", Arity: 2, Flags: scriptFlags, Categories: connectionCategories,
+					Summary: "Returns the name of the connection.", Since: "2.6.9", Group: "connection",
+					Complexity: "O(1)"},
+				&commands.Command{Name: "client|setname", Arity: 3, Flags: scriptFlags, Categories: connectionCategories,
+					Summary: "Sets the connection name.", Since: "2.6.9", Group: "connection",
+					Complexity: "O(1)",
+					Args:       []commands.Arg{{Name: "connection-name", Type: "string"}}},
+				&commands.Command{Name: "client|list", Arity: -2, Flags: adminFlags, Categories: clientAdminCategories,
+					Summary: "Lists open connections.", Since: "2.4.0", Group: "connection",
+					Complexity: "O(N) where N is the number of client connections",
+					Args: []commands.Arg{
+						{Name: "client-type", Type: "oneof", Optional: true},
+						{Name: "client-id", Type: "integer", Optional: true, Multiple: true},
+					}},
+				&commands.Command{Name: "client|kill", Arity: -3, Flags: adminFlags, Categories: clientAdminCategories,
+					Summary: "Terminates open connections.", Since: "2.4.0", Group: "connection",
+					Complexity: "O(N) where N is the number of client connections",
+					Args:       []commands.Arg{{Name: "filter", Type: "oneof", Multiple: true}}},
+				&commands.Command{Name: "client|pause", Arity: -3, Flags: adminFlags, Categories: clientAdminCategories,
+					Summary: "Suspends commands processing.", Since: "3.0.0", Group: "connection",
+					Complexity: "O(1)",
+					Args: []commands.Arg{
+						{Name: "timeout", Type: "integer"},
+						{Name: "mode", Type: "oneof", Optional: true},
+					}},
+				&commands.Command{Name: "client|unpause", Arity: 2, Flags: adminFlags, Categories: clientAdminCategories,
+					Summary: "Resumes processing commands from paused clients.", Since: "6.2.0", Group: "connection",
+					Complexity: "O(N) Where N is the number of paused clients"},
+				help("client"),
+			),
+		},
+		{
+			Name:       "command",
+			Handler:    s.commandCommand,
+			Arity:      -1,
+			Flags:      staleFlags,
+			Categories: connectionCategories,
+			Summary:    "Returns detailed information about all commands.",
+			Since:      "2.8.13",
+			Group:      "server",
+			Complexity: "O(N) where N is the total number of Redis commands",
+			Subcommands: subcommands(
+				&commands.Command{Name: "command|count", Arity: 2, Flags: staleFlags, Categories: connectionCategories,
+					Summary: "Returns a count of commands.", Since: "2.8.13", Group: "server",
+					Complexity: "O(1)"},
+				&commands.Command{Name: "command|docs", Arity: -2, Flags: staleFlags, Categories: connectionCategories,
+					Summary: "Returns documentary information about one, multiple or all commands.", Since: "7.0.0", Group: "server",
+					Complexity: "O(N) where N is the number of commands to look up",
+					Args:       []commands.Arg{{Name: "command-name", Type: "string", Optional: true, Multiple: true}}},
+				&commands.Command{Name: "command|getkeys", Arity: -3, Flags: staleFlags, Categories: connectionCategories,
+					Summary: "Extracts the key names from an arbitrary command.", Since: "2.8.13", Group: "server",
+					Complexity: "O(N) where N is the number of arguments to the command",
+					Args: []commands.Arg{
+						{Name: "command", Type: "string"},
+						{Name: "arg", Type: "string", Optional: true, Multiple: true},
+					}},
+				&commands.Command{Name: "command|info", Arity: -2, Flags: staleFlags, Categories: connectionCategories,
+					Summary: "Returns information about one, multiple or all commands.", Since: "2.8.13", Group: "server",
+					Complexity: "O(N) where N is the number of commands to look up",
+					Args:       []commands.Arg{{Name: "command-name", Type: "string", Optional: true, Multiple: true}}},
+				&commands.Command{Name: "command|list", Arity: -2, Flags: staleFlags, Categories: connectionCategories,
+					Summary: "Returns a list of command names.", Since: "7.0.0", Group: "server",
+					Complexity: "O(N) where N is the total number of Redis commands",
+					Args:       []commands.Arg{{Name: "filterby", Type: "oneof", Optional: true}}},
+				help("command"),
+			),
+		},
+		{
+			Name:       "slowlog",
+			Handler:    s.slowlogCommand,
+			Arity:      -2,
+			Categories: []string{"slow"},
+			Summary:    "A container for slow log commands.",
+			Since:      "2.2.12",
+			Group:      "server",
+			Complexity: "Depends on subcommand.",
+			Subcommands: subcommands(
+				&commands.Command{Name: "slowlog|get", Arity: -2, Flags: adminFlags, Categories: adminCategories,
+					Summary: "Returns the slow log's entries.", Since: "2.2.12", Group: "server",
+					Complexity: "O(N) where N is the number of entries returned",
+					Args:       []commands.Arg{{Name: "count", Type: "integer", Optional: true}}},
+				&commands.Command{Name: "slowlog|len", Arity: 2, Flags: adminFlags, Categories: adminCategories,
+					Summary: "Returns the number of entries in the slow log.", Since: "2.2.12", Group: "server",
+					Complexity: "O(1)"},
+				&commands.Command{Name: "slowlog|reset", Arity: 2, Flags: adminFlags, Categories: adminCategories,
+					Summary: "Clears all entries from the slow log.", Since: "2.2.12", Group: "server",
+					Complexity: "O(N) where N is the number of entries in the slowlog"},
+				help("slowlog"),
+			),
+		},
+		{
+			Name:       "monitor",
+			Handler:    s.monitorCommand,
+			Arity:      1,
+			Flags:      adminFlags,
+			Categories: adminCategories,
+			Summary:    "Listens for all requests received by the server in real-time.",
+			Since:      "1.0.0",
+			Group:      "server",
+			Complexity: "O(1)",
+		},
+		{
+			Name:       "info",
+			Handler:    s.infoCommand,
+			Arity:      -1,
+			Flags:      staleFlags,
+			Categories: []string{"slow", "dangerous"},
+			Summary:    "Returns information and statistics about the server.",
+			Since:      "1.0.0",
+			Group:      "server",
+			Complexity: "O(1)",
+			Args:       []commands.Arg{{Name: "section", Type: "string", Optional: true, Multiple: true}},
+		},
+	}
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/helewud/redis-clone/commands"
+	"github.com/helewud/redis-clone/config"
+	"github.com/helewud/redis-clone/logger"
+	"github.com/helewud/redis-clone/resp"
+)
+
+// configCommand answers CONFIG GET, CONFIG SET and CONFIG REWRITE
+func (s *Server) configCommand(session *commands.Session, args []resp.Value) resp.Value {
+	if len(args) == 0 {
+		return resp.Value{T: resp.RespTError, String: "ERR wrong number of arguments for 'config' command"}
+	}
+
+	name := string(args[0].Bulk)
+	subcommand := strings.ToUpper(name)
+	args = args[1:]
+
+	switch {
+	case subcommand == "HELP" && len(args) == 0:
+		return helpReply(
+			"CONFIG <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+			"GET <pattern> [<pattern> ...]",
+			"    Return parameters matching the glob-like <pattern> and their values.",
+			"SET <directive> <value> [<directive> <value> ...]",
+			"    Set the configuration <directive> to <value>.",
+			"REWRITE",
+			"    Rewrite the configuration file.",
+			"HELP",
+			"    Print this help.",
+		)
+	case subcommand == "GET" && len(args) > 0:
+		patterns := make([]string, len(args))
+		for i, arg := range args {
+			patterns[i] = string(arg.Bulk)
+		}
+
+		pairs := s.config.Get(patterns...)
+		res := resp.Value{T: resp.RespTMap, Array: make([]resp.Value, len(pairs))}
+		for i, str := range pairs {
+			res.Array[i] = resp.Value{T: resp.RespTBulk, Bulk: []byte(str)}
+		}
+
+		return res
+	case subcommand == "SET" && len(args) > 0 && len(args)%2 == 0:
+		pairs := make([]string, len(args))
+		for i, arg := range args {
+			pairs[i] = string(arg.Bulk)
+		}
+
+		if err := s.config.Set(pairs...); err != nil {
+			return configSetError(err)
+		}
+		s.applyConfig(pairs)
+
+		return resp.Value{T: resp.RespTString, String: "OK"}
+	case subcommand == "REWRITE" && len(args) == 0:
+		if err := s.config.Rewrite(); err != nil {
+			if errors.Is(err, config.ErrNoConfigFile) {
+				return resp.Value{T: resp.RespTError, String: "ERR The server is running without a config file"}
+			}
+			return resp.Value{T: resp.RespTError, String: "ERR Rewriting config file: " + err.Error()}
+		}
+
+		return resp.Value{T: resp.RespTString, String: "OK"}
+	case subcommand == "GET" || subcommand == "SET" || subcommand == "REWRITE":
+		return resp.Value{
+			T:      resp.RespTError,
+			String: fmt.Sprintf("ERR wrong number of arguments for 'config|%s' command", strings.ToLower(subcommand)),
+		}
+	default:
+		return commands.ErrorReply(fmt.Sprintf("ERR unknown subcommand '%s'. Try CONFIG HELP.", name))
+	}
+}
+
+func configSetError(err error) resp.Value {
+	var perr *config.ParamError
+	if !errors.As(err, &perr) {
+		return commands.ErrorReply("ERR " + err.Error())
+	}
+
+	if errors.Is(err, config.ErrUnknownParam) {
+		return commands.ErrorReply(fmt.Sprintf("ERR Unknown option or number of arguments for CONFIG SET - '%s'", perr.Param))
+	}
+
+	return commands.ErrorReply(fmt.Sprintf("ERR CONFIG SET failed (possibly related to argument '%s') - %s", perr.Param, perr.Err))
+}
+
+// applyConfig puts the settings that can change live into effect after
+// CONFIG SET changed the given name/value pairs. Limits and timeouts are
+// read by the connections before every command.
+func (s *Server) applyConfig(pairs []string) {
+	cfg := s.config.Config()
+
+	if s.store != nil {
+		s.store.SetFsync(cfg.AppendFsync)
+	}
+	// requirepass resets the default user, so it is only applied when set
+	// and leaves passwords added with ACL SETUSER alone otherwise
+	for i := 0; i < len(pairs); i += 2 {
+		if strings.EqualFold(pairs[i], "requirepass") {
+			if err := s.applyRequirePass(cfg.RequirePass); err != nil {
+				s.log.Error("applying requirepass", "err", err)
+			}
+			break
+		}
+	}
+	if err := logger.SetLevel(cfg.LogLevel); err != nil {
+		s.log.Error("applying loglevel", "err", err)
+	}
+	s.slowlog.setMaxLen(cfg.SlowlogMaxLen)
+}
+
+// helpReply answers the HELP subcommand of a command family
+func helpReply(lines ...string) resp.Value {
+	res := resp.Value{T: resp.RespTArray, Array: make([]resp.Value, len(lines))}
+	for i, line := range lines {
+		res.Array[i] = resp.Value{T: resp.RespTString, String: line}
+	}
+
+	return res
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/helewud/redis-clone/commands"
+	"github.com/helewud/redis-clone/config"
+	"github.com/helewud/redis-clone/resp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bulks(strs ...string) []resp.Value {
+	values := make([]resp.Value, len(strs))
+	for i, str := range strs {
+		values[i] = resp.Value{T: resp.RespTBulk, Bulk: []byte(str)}
+	}
+
+	return values
+}
+
+func TestConfigCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redis.conf")
+	require.NoError(t, os.WriteFile(path, []byte("port 7000\n"), 0644))
+
+	cfg := config.Default()
+	require.NoError(t, config.Load(&cfg, path))
+	s := New(nil, config.NewManager(cfg, path))
+	session := commands.NewSession(1, "127.0.0.1:50000")
+
+	tests := []struct {
+		name string
+		args []string
+		want resp.Value
+	}{
+		{
+			name: "get",
+			args: []string{"GET", "port"},
+			want: resp.Value{T: resp.RespTMap, Array: bulks("port", "7000")},
+		},
+		{
+			name: "get no match",
+			args: []string{"get", "nothing"},
+			want: resp.Value{T: resp.RespTMap, Array: []resp.Value{}},
+		},
+		{
+			name: "set",
+			args: []string{"SET", "appendfsync", "always", "loglevel", "debug"},
+			want: resp.Value{T: resp.RespTString, String: "OK"},
+		},
+		{
+			name: "get after set",
+			args: []string{"GET", "appendfsync", "loglevel"},
+			want: resp.Value{T: resp.RespTMap, Array: bulks("appendfsync", "always", "loglevel", "debug")},
+		},
+		{
+			name: "set immutable",
+			args: []string{"SET", "port", "7001"},
+			want: resp.Value{
+				T:      resp.RespTError,
+				String: "ERR CONFIG SET failed (possibly related to argument 'port') - can't set immutable config",
+			},
+		},
+		{
+			name: "set bad value",
+			args: []string{"SET", "appendfsync", "sometimes"},
+			want: resp.Value{
+				T:      resp.RespTError,
+				String: "ERR CONFIG SET failed (possibly related to argument 'appendfsync') - argument(s) must be one of the following: always, everysec, no",
+			},
+		},
+		{
+			name: "set unknown",
+			args: []string{"SET", "maxmemory", "1gb"},
+			want: resp.Value{
+				T:      resp.RespTError,
+				String: "ERR Unknown option or number of arguments for CONFIG SET - 'maxmemory'",
+			},
+		},
+		{
+			name: "set unknown with line breaks",
+			args: []string{"SET", "max\r\nmemory", "1gb"},
+			want: resp.Value{
+				T:      resp.RespTError,
+				String: "ERR Unknown option or number of arguments for CONFIG SET - 'max  memory'",
+			},
+		},
+		{
+			name: "set missing value",
+			args: []string{"SET", "loglevel"},
+			want: resp.Value{T: resp.RespTError, String: "ERR wrong number of arguments for 'config|set' command"},
+		},
+		{
+			name: "rewrite",
+			args: []string{"REWRITE"},
+			want: resp.Value{T: resp.RespTString, String: "OK"},
+		},
+		{
+			name: "unknown subcommand",
+			args: []string{"reset"},
+			want: resp.Value{T: resp.RespTError, String: "ERR unknown subcommand 'reset'. Try CONFIG HELP."},
+		},
+		{
+			name: "unknown subcommand with line breaks",
+			args: []string{"re\nset"},
+			want: resp.Value{T: resp.RespTError, String: "ERR unknown subcommand 're set'. Try CONFIG HELP."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.configCommand(session, bulks(tt.args...)))
+		})
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "port 7000\n# Generated by CONFIG REWRITE\nappendfsync always\nloglevel debug\n", string(data))
+
+	got := New(nil, nil).configCommand(session, bulks("REWRITE"))
+	assert.Equal(t, resp.Value{T: resp.RespTError, String: "ERR The server is running without a config file"}, got)
+}
+
+func TestConfigLimits(t *testing.T) {
+	s := New(nil, nil)
+	got := s.configCommand(commands.NewSession(1, ""), bulks("SET", "proto-max-bulk-len", "1mb"))
+	require.Equal(t, resp.Value{T: resp.RespTString, String: "OK"}, got)
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go s.handleConn(serverConn)
+
+	go clientConn.Write([]byte("*1\r\n$2000000\r\n"))
+
+	reply, err := io.ReadAll(clientConn)
+	require.NoError(t, err)
+	assert.Equal(t, "-ERR Protocol error: invalid bulk length\r\n", string(reply))
+
+	// connected clients get the new limits from their next command
+	_, _, addr, _ := serve(t)
+	c := dial(t, addr)
+	require.Equal(t, "OK", c.do("CONFIG", "SET", "proto-max-bulk-len", "1mb").String)
+	_, err = c.conn.Write([]byte("*1\r\n$2000000\r\n"))
+	require.NoError(t, err)
+	got, err = c.reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "ERR Protocol error: invalid bulk length", got.String)
+}
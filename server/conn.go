@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/helewud/redis-clone/commands"
+	"github.com/helewud/redis-clone/resp"
+)
+
+// conn is a client connection along with its session
+type conn struct {
+	netConn net.Conn
+	session *commands.Session
+	reader  *resp.Reader
+
+	// log carries the client ID and address, and cmdHandler adds the last
+	// command to its lines
+	log *slog.Logger
+
+	// mu guards writer, pushes are written from other goroutines
+	mu     sync.Mutex
+	writer *resp.Writer
+
+	created time.Time
+	killed  atomic.Bool
+
+	// monitoring is set while the connection is in the server's monitors
+	monitoring atomic.Bool
+
+	infoMu sync.Mutex
+	info   clientInfo
+}
+
+func newConn(netConn net.Conn, id int64) *conn {
+	c := &conn{
+		netConn: netConn,
+		session: commands.NewSession(id, remoteAddr(netConn)),
+		writer:  resp.NewWriter(netConn),
+		created: time.Now(),
+	}
+	c.setLogger(slog.Default())
+	c.info.active = c.created
+	c.reader = resp.NewReader(&flushReader{conn: c})
+	c.session.SetPusher(c.push)
+
+	return c
+}
+
+// setLogger makes the connection log through l, adding the last command
+func (c *conn) setLogger(l *slog.Logger) {
+	c.log = slog.New(cmdHandler{Handler: l.Handler(), conn: c})
+}
+
+// cmdHandler adds the last command run on a connection to its log lines as
+// cmd, unless the line names a command itself. It's read when a line is
+// written, the attributes of Logger.With are fixed when it's made.
+type cmdHandler struct {
+	slog.Handler
+	conn *conn
+}
+
+func (h cmdHandler) Handle(ctx context.Context, r slog.Record) error {
+	named := false
+	r.Attrs(func(a slog.Attr) bool {
+		named = a.Key == "cmd"
+		return !named
+	})
+	if !named {
+		r.AddAttrs(slog.String("cmd", h.conn.lastCmd()))
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h cmdHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return cmdHandler{Handler: h.Handler.WithAttrs(attrs), conn: h.conn}
+}
+
+func (h cmdHandler) WithGroup(name string) slog.Handler {
+	return cmdHandler{Handler: h.Handler.WithGroup(name), conn: h.conn}
+}
+
+// reply writes the reply to a command with the protocol of the session. It
+// stays buffered until the connection waits for more input.
+func (c *conn) reply(v resp.Value) error {
+	if v.T == noReply.T {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writer.SetProtocol(c.session.Proto)
+	return c.writer.WriteValue(v)
+}
+
+// push writes an out-of-band message and flushes it right away, as the
+// connection may be blocked waiting for the client. It is encoded with the
+// protocol of the last reply.
+func (c *conn) push(v resp.Value) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.writer.WriteValue(v); err != nil {
+		return err
+	}
+
+	return c.writer.Flush()
+}
+
+// flush sends the buffered replies
+func (c *conn) flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.writer.Buffered() == 0 {
+		return nil
+	}
+
+	return c.writer.Flush()
+}
+
+// close sends the replies still buffered and closes the connection
+func (c *conn) close() {
+	c.flush()
+	c.reader.Release()
+	c.netConn.Close()
+}
+
+// flushReader sends the pending replies before blocking on the connection
+// for more input. Replies to commands that arrived together are written
+// at once, and a command split across packets never holds back the replies
+// to the ones before it.
+type flushReader struct {
+	conn *conn
+}
+
+func (f *flushReader) Read(p []byte) (int, error) {
+	if err := f.conn.flush(); err != nil {
+		return 0, err
+	}
+
+	return f.conn.netConn.Read(p)
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/helewud/redis-clone/commands"
+	"github.com/helewud/redis-clone/resp"
+)
+
+// stats are the counters of INFO stats
+type stats struct {
+	connections atomic.Int64
+	rejected    atomic.Int64
+	commands    atomic.Int64
+}
+
+// infoSections are the sections of INFO in the order they are printed, all
+// of them are in the default set
+var infoSections = []string{"server", "clients", "memory", "persistence", "stats", "replication", "keyspace"}
+
+// randomID returns 40 random hex characters, like the run and replication
+// IDs of Redis
+func randomID() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// infoWriter writes the fields of INFO sections
+type infoWriter struct {
+	strings.Builder
+}
+
+func (w *infoWriter) field(name string, value any) {
+	fmt.Fprintf(w, "%s:%v\r\n", name, value)
+}
+
+// humanBytes formats n the way the *_human fields of Redis do
+func humanBytes(n uint64) string {
+	units := []string{"K", "M", "G", "T", "P"}
+	if n < 1024 {
+		return strconv.FormatUint(n, 10) + "B"
+	}
+
+	value := float64(n) / 1024
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+
+	return fmt.Sprintf("%.2f%s", value, units[unit])
+}
+
+// infoCommand answers INFO [section ...]. Unknown sections are left out.
+func (s *Server) infoCommand(session *commands.Session, args []resp.Value) resp.Value {
+	want := map[string]bool{}
+	if len(args) == 0 {
+		args = []resp.Value{bulk("default")}
+	}
+	for _, arg := range args {
+		switch section := strings.ToLower(string(arg.Bulk)); section {
+		case "default", "all", "everything":
+			for _, name := range infoSections {
+				want[name] = true
+			}
+		default:
+			want[section] = true
+		}
+	}
+
+	var w infoWriter
+	for _, name := range infoSections {
+		if !want[name] {
+			continue
+		}
+		if w.Len() > 0 {
+			w.WriteString("\r\n")
+		}
+		fmt.Fprintf(&w, "# %s%s\r\n", strings.ToUpper(name[:1]), name[1:])
+		s.writeInfo(&w, name)
+	}
+
+	return resp.Value{T: resp.RespTVerbatim, Format: "txt", Bulk: []byte(w.String())}
+}
+
+// writeInfo writes the fields of section
+func (s *Server) writeInfo(w *infoWriter, section string) {
+	cfg := s.config.Config()
+
+	switch section {
+	case "server":
+		now := time.Now()
+		uptime := int64(now.Sub(s.started).Seconds())
+		executable, _ := os.Executable()
+
+		w.field("redis_version", commands.Version)
+		w.field("redis_git_sha1", "00000000")
+		w.field("redis_git_dirty", 0)
+		w.field("redis_mode", "standalone")
+		w.field("os", runtime.GOOS+" "+runtime.GOARCH)
+		w.field("arch_bits", strconv.IntSize)
+		w.field("go_version", runtime.Version())
+		w.field("process_id", os.Getpid())
+		w.field("run_id", s.runID)
+		w.field("tcp_port", cfg.Port)
+		w.field("server_time_usec", now.UnixMicro())
+		w.field("uptime_in_seconds", uptime)
+		w.field("uptime_in_days", uptime/(24*60*60))
+		w.field("executable", executable)
+		w.field("config_file", s.config.Path())
+	case "clients":
+		s.mu.Lock()
+		connected := len(s.conns)
+		s.mu.Unlock()
+
+		w.field("connected_clients", connected)
+		w.field("cluster_connections", 0)
+		w.field("maxclients", cfg.MaxClients)
+		w.field("blocked_clients", 0)
+		w.field("tracking_clients", 0)
+		w.field("clients_in_timeout_table", 0)
+	case "memory":
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+
+		// the heap in use is what Redis calls used memory, what the Go
+		// runtime got from the system stands in for the RSS
+		w.field("used_memory", mem.HeapAlloc)
+		w.field("used_memory_human", humanBytes(mem.HeapAlloc))
+		w.field("used_memory_rss", mem.Sys)
+		w.field("used_memory_rss_human", humanBytes(mem.Sys))
+		w.field("maxmemory", 0)
+		w.field("maxmemory_human", "0B")
+		w.field("maxmemory_policy", "noeviction")
+		w.field("mem_fragmentation_ratio", fmt.Sprintf("%.2f", float64(mem.Sys)/float64(max(mem.HeapAlloc, 1))))
+		w.field("mem_allocator", "go")
+	case "persistence":
+		w.field("loading", 0)
+		w.field("aof_enabled", boolInt(s.store != nil))
+		w.field("aof_rewrite_in_progress", 0)
+		w.field("aof_rewrite_scheduled", 0)
+		if s.store == nil {
+			w.field("aof_last_write_status", "ok")
+			return
+		}
+
+		// like Redis, a failed fsync fails the write status too
+		aof := s.store.Stats()
+		status := "ok"
+		if aof.WriteErr != nil || aof.FsyncErr != nil {
+			status = "err"
+		}
+		w.field("aof_last_write_status", status)
+		w.field("aof_current_size", aof.Size)
+		w.field("aof_base_size", aof.BaseSize)
+		w.field("aof_pending_rewrite", 0)
+		w.field("aof_buffer_length", 0)
+		w.field("aof_pending_bio_fsync", 0)
+		w.field("aof_delayed_fsync", 0)
+	case "stats":
+		w.field("total_connections_received", s.stats.connections.Load())
+		w.field("total_commands_processed", s.stats.commands.Load())
+		w.field("rejected_connections", s.stats.rejected.Load())
+		w.field("expired_keys", 0)
+		w.field("evicted_keys", 0)
+		w.field("keyspace_hits", commands.KeyspaceHits.Load())
+		w.field("keyspace_misses", commands.KeyspaceMisses.Load())
+	case "replication":
+		w.field("role", "master")
+		w.field("connected_slaves", 0)
+		w.field("master_failover_state", "no-failover")
+		w.field("master_replid", s.replID)
+		w.field("master_replid2", strings.Repeat("0", 40))
+		w.field("master_repl_offset", 0)
+		w.field("second_repl_offset", -1)
+		w.field("repl_backlog_active", 0)
+		w.field("repl_backlog_size", 1048576)
+		w.field("repl_backlog_first_byte_offset", 0)
+		w.field("repl_backlog_histlen", 0)
+	case "keyspace":
+		// every key is in db0, empty databases are left out
+		if n := commands.KeyCount(); n > 0 {
+			w.field("db0", fmt.Sprintf("keys=%d,expires=0,avg_ttl=0", n))
+		}
+	}
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/helewud/redis-clone/config"
+	"github.com/helewud/redis-clone/resp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseInfo splits an INFO reply into its sections and fields, the way
+// exporters read it
+func parseInfo(t *testing.T, reply resp.Value) map[string]map[string]string {
+	t.Helper()
+
+	require.Equal(t, resp.RespTBulk, reply.T, reply.String)
+	text := string(reply.Bulk)
+	require.True(t, strings.HasSuffix(text, "\r\n"))
+
+	sections := map[string]map[string]string{}
+	var section map[string]string
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\r\n"), "\r\n") {
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "# "):
+			section = map[string]string{}
+			sections[strings.TrimPrefix(line, "# ")] = section
+		default:
+			name, value, ok := strings.Cut(line, ":")
+			require.True(t, ok, line)
+			section[name] = value
+		}
+	}
+
+	return sections
+}
+
+func infoInt(t *testing.T, value string) int {
+	t.Helper()
+
+	n, err := strconv.Atoi(value)
+	require.NoError(t, err, value)
+
+	return n
+}
+
+func TestInfo(t *testing.T) {
+	cfg := config.Default()
+	cfg.MaxClients = 5
+	_, _, addr, _ := serveConfig(t, cfg)
+
+	c := dial(t, addr)
+	info := parseInfo(t, c.do("INFO"))
+
+	var names []string
+	for name := range info {
+		names = append(names, name)
+	}
+	assert.ElementsMatch(t, []string{"Server", "Clients", "Memory", "Persistence", "Stats", "Replication", "Keyspace"}, names)
+
+	assert.Equal(t, "7.2.0", info["Server"]["redis_version"])
+	assert.Equal(t, "6379", info["Server"]["tcp_port"])
+	assert.Len(t, info["Server"]["run_id"], 40)
+	assert.Equal(t, "1", info["Clients"]["connected_clients"])
+	assert.Equal(t, "5", info["Clients"]["maxclients"])
+	assert.Positive(t, infoInt(t, info["Memory"]["used_memory"]))
+	assert.Equal(t, "1", info["Persistence"]["aof_enabled"])
+	assert.Equal(t, "ok", info["Persistence"]["aof_last_write_status"])
+	assert.Equal(t, "master", info["Replication"]["role"])
+
+	size := infoInt(t, info["Persistence"]["aof_current_size"])
+	processed := infoInt(t, info["Stats"]["total_commands_processed"])
+	hits := infoInt(t, info["Stats"]["keyspace_hits"])
+	misses := infoInt(t, info["Stats"]["keyspace_misses"])
+
+	assert.Equal(t, "OK", c.do("SET", "info", "v").String)
+	c.do("GET", "info")
+	c.do("GET", "info:nope")
+
+	info = parseInfo(t, c.do("INFO", "persistence", "STATS", "keyspace"))
+	assert.Len(t, info, 3)
+	assert.Greater(t, infoInt(t, info["Persistence"]["aof_current_size"]), size)
+	assert.Equal(t, processed+4, infoInt(t, info["Stats"]["total_commands_processed"]))
+	assert.Equal(t, hits+1, infoInt(t, info["Stats"]["keyspace_hits"]))
+	assert.Equal(t, misses+1, infoInt(t, info["Stats"]["keyspace_misses"]))
+	assert.Regexp(t, `^keys=\d+,expires=0,avg_ttl=0$`, info["Keyspace"]["db0"])
+}
+
+func TestInfoSections(t *testing.T) {
+	_, _, addr, _ := serve(t)
+	c := dial(t, addr)
+
+	reply := c.do("INFO", "clients")
+	assert.True(t, strings.HasPrefix(string(reply.Bulk), "# Clients\r\nconnected_clients:1\r\n"))
+
+	// sections come in their usual order, unknown ones are left out
+	reply = c.do("INFO", "replication", "nope", "server")
+	text := string(reply.Bulk)
+	assert.Less(t, strings.Index(text, "# Server"), strings.Index(text, "\r\n\r\n# Replication\r\n"))
+	assert.Len(t, parseInfo(t, reply), 2)
+
+	assert.Equal(t, resp.Value{T: resp.RespTBulk, Bulk: []byte{}}, c.do("INFO", "nope"))
+	assert.Len(t, parseInfo(t, c.do("INFO", "all")), len(infoSections))
+
+	// RESP3 clients get a verbatim string
+	c.do("HELLO", "3")
+	reply = c.do("INFO", "server")
+	assert.Equal(t, resp.RespTVerbatim, reply.T)
+	assert.Equal(t, "txt", reply.Format)
+}
+
+func TestInfoConnections(t *testing.T) {
+	cfg := config.Default()
+	cfg.MaxClients = 1
+	_, _, addr, _ := serveConfig(t, cfg)
+
+	c := dial(t, addr)
+	c.do("PING")
+
+	rejected := dial(t, addr)
+	_, err := rejected.reader.Read()
+	require.NoError(t, err)
+
+	info := parseInfo(t, c.do("INFO", "stats"))
+	assert.Equal(t, "1", info["Stats"]["total_connections_received"])
+	assert.Equal(t, "1", info["Stats"]["rejected_connections"])
+}
+
+func TestHumanBytes(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want string
+	}{
+		{n: 0, want: "0B"},
+		{n: 1023, want: "1023B"},
+		{n: 1024, want: "1.00K"},
+		{n: 1536, want: "1.50K"},
+		{n: 5 << 20, want: "5.00M"},
+		{n: 3 << 30, want: "3.00G"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, humanBytes(tt.n))
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"time"
+)
+
+// ListenUnix listens on a Unix socket at path. A socket file left behind by
+// a server that is gone is removed first, one that still accepts
+// connections is an error. The socket gets permissions perm, unless perm
+// is 0. It's created inaccessible and opened up to perm afterwards, so no
+// one else can connect in between.
+func ListenUnix(path string, perm fs.FileMode) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	var ln net.Listener
+	listen := func() (err error) {
+		ln, err = net.Listen("unix", path)
+		return err
+	}
+
+	var err error
+	if perm != 0 {
+		err = withPrivateUmask(listen)
+	} else {
+		err = listen()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if perm != 0 {
+		if err := os.Chmod(path, perm); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+
+	return ln, nil
+}
+
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another server", path)
+	}
+
+	return os.Remove(path)
+}
+
+// remoteAddr returns the address of the client of conn. Unix socket clients
+// have none, they are named after the socket like Redis does.
+func remoteAddr(conn net.Conn) string {
+	if _, ok := conn.LocalAddr().(*net.UnixAddr); ok {
+		return conn.LocalAddr().String() + ":0"
+	}
+
+	return conn.RemoteAddr().String()
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redis.sock")
+
+	ln, err := ListenUnix(path, 0700)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0700), info.Mode().Perm())
+
+	go New(nil, nil).Serve(ln)
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("PING\r\n"))
+	require.NoError(t, err)
+	reply := make([]byte, len("+PONG\r\n"))
+	_, err = io.ReadFull(conn, reply)
+	require.NoError(t, err)
+	assert.Equal(t, "+PONG\r\n", string(reply))
+
+	// a second server can't take over a socket in use
+	_, err = ListenUnix(path, 0)
+	assert.Error(t, err)
+}
+
+func TestWithPrivateUmask(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("there is no umask on windows")
+	}
+
+	dir := t.TempDir()
+	private := filepath.Join(dir, "private")
+	require.NoError(t, withPrivateUmask(func() error {
+		return os.WriteFile(private, nil, 0666)
+	}))
+	info, err := os.Stat(private)
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0), info.Mode().Perm())
+
+	// the umask is restored afterwards
+	restored := filepath.Join(dir, "restored")
+	require.NoError(t, os.WriteFile(restored, nil, 0600))
+	info, err = os.Stat(restored)
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0600), info.Mode().Perm())
+}
+
+func TestListenUnixStale(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("stale socket is removed", func(t *testing.T) {
+		path := filepath.Join(dir, "stale.sock")
+
+		// a listener that doesn't unlink on close leaves the file behind,
+		// like a crashed server would
+		ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+		require.NoError(t, err)
+		ln.SetUnlinkOnClose(false)
+		require.NoError(t, ln.Close())
+		_, err = os.Stat(path)
+		require.NoError(t, err)
+
+		ln2, err := ListenUnix(path, 0)
+		require.NoError(t, err)
+		require.NoError(t, ln2.Close())
+
+		_, err = os.Stat(path)
+		assert.ErrorIs(t, err, fs.ErrNotExist, "the socket is removed on close")
+	})
+
+	t.Run("regular file is kept", func(t *testing.T) {
+		path := filepath.Join(dir, "file.sock")
+		require.NoError(t, os.WriteFile(path, []byte("data"), 0644))
+
+		_, err := ListenUnix(path, 0)
+		assert.Error(t, err)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "data", string(data))
+	})
+}
+
+func TestRemoteAddrUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redis.sock")
+	ln, err := ListenUnix(path, 0)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	go func() {
+		conn, err := net.Dial("unix", path)
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, path+":0", remoteAddr(conn))
+}
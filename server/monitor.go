@@ -0,0 +1,184 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/helewud/redis-clone/commands"
+	"github.com/helewud/redis-clone/resp"
+)
+
+// monitorBuffer is how many lines a monitor may fall behind before it is
+// dropped, so a slow one never holds up the commands it watches
+const monitorBuffer = 1024
+
+// monitorWriteTimeout is how long a monitor may take to accept its lines.
+// The connection's lock is held meanwhile, so a stalled monitor is dropped
+// rather than holding up CLIENT LIST.
+const monitorWriteTimeout = time.Second
+
+// monitor is a connection in MONITOR mode, its lines are written by a
+// goroutine of their own
+type monitor struct {
+	conn  *conn
+	lines chan string
+	done  chan struct{}
+}
+
+// run writes the lines to the connection until the monitor is removed or the
+// connection fails. Lines that queued up meanwhile go out together.
+func (m *monitor) run() {
+	for {
+		select {
+		case <-m.done:
+			return
+		case line := <-m.lines:
+			c := m.conn
+			c.mu.Lock()
+			c.writer.WriteValue(resp.Value{T: resp.RespTString, String: line})
+			for len(m.lines) > 0 {
+				c.writer.WriteValue(resp.Value{T: resp.RespTString, String: <-m.lines})
+			}
+			c.netConn.SetWriteDeadline(time.Now().Add(monitorWriteTimeout))
+			err := c.writer.Flush()
+			c.netConn.SetWriteDeadline(time.Time{})
+			c.mu.Unlock()
+
+			if err != nil {
+				c.log.Warn("dropping stalled monitor", "err", err)
+				c.netConn.Close()
+				return
+			}
+		}
+	}
+}
+
+// monitors are the connections in MONITOR mode
+type monitors struct {
+	mu  sync.RWMutex
+	all map[int64]*monitor
+
+	// n lets commands skip formatting their line when nobody monitors
+	n atomic.Int64
+}
+
+// add turns c into a monitor
+func (m *monitors) add(c *conn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.all == nil {
+		m.all = map[int64]*monitor{}
+	}
+
+	mon := &monitor{conn: c, lines: make(chan string, monitorBuffer), done: make(chan struct{})}
+	m.all[c.session.ID] = mon
+	c.monitoring.Store(true)
+	m.n.Store(int64(len(m.all)))
+	go mon.run()
+}
+
+// remove stops monitoring on the connection with the given ID. It reports
+// whether the connection was a monitor.
+func (m *monitors) remove(id int64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mon, ok := m.all[id]
+	if ok {
+		delete(m.all, id)
+		m.n.Store(int64(len(m.all)))
+		mon.conn.monitoring.Store(false)
+		close(mon.done)
+	}
+
+	return ok
+}
+
+// feed queues line for every monitor and returns the ones too far behind to
+// take it
+func (m *monitors) feed(line string) []*monitor {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var slow []*monitor
+	for _, mon := range m.all {
+		select {
+		case mon.lines <- line:
+		default:
+			slow = append(slow, mon)
+		}
+	}
+
+	return slow
+}
+
+// monitorLine is the line monitors get for a command, such as
+// 1339518083.107412 [0 127.0.0.1:60866] "SET" "key" "value"
+func monitorLine(c *conn, cmd *commands.Command, value []resp.Value, t time.Time) string {
+	addr := c.session.Addr
+	if unix, ok := c.netConn.LocalAddr().(*net.UnixAddr); ok {
+		addr = "unix:" + unix.Name
+	}
+
+	args := make([]string, len(value))
+	for i, arg := range value {
+		args[i] = string(arg.Bulk)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d.%06d [%d %s]", t.Unix(), t.Nanosecond()/1000, c.session.DB, addr)
+	for _, arg := range redact(cmd, args) {
+		b.WriteByte(' ')
+		b.WriteString(resp.QuoteArg(arg))
+	}
+
+	return b.String()
+}
+
+// feedMonitors sends the command in value, run by c at t, to the monitors.
+// Like Redis, admin commands are not shown.
+func (s *Server) feedMonitors(c *conn, cmd *commands.Command, value []resp.Value, t time.Time) {
+	if s.monitors.n.Load() == 0 {
+		return
+	}
+	flags := cmd
+	if sub, ok := cmd.Subcommand(value[1:]); ok {
+		flags = sub
+	}
+	if flags.HasFlag(commands.FlagAdmin) {
+		return
+	}
+
+	// another command may have found the same monitor behind
+	for _, mon := range s.monitors.feed(monitorLine(c, cmd, value, t)) {
+		if s.monitors.remove(mon.conn.session.ID) {
+			mon.conn.log.Warn("dropping slow monitor", "buffered", monitorBuffer)
+			mon.conn.kill(false)
+		}
+	}
+}
+
+// monitorCommand answers MONITOR, the connection gets every command run
+// from then on
+func (s *Server) monitorCommand(session *commands.Session, args []resp.Value) resp.Value {
+	c, ok := s.client(session.ID)
+	if !ok {
+		return errorValue("ERR MONITOR needs a client connection")
+	}
+	if c.monitoring.Load() {
+		// as in Redis, a second MONITOR gets no reply
+		return noReply
+	}
+
+	// the OK goes out before the first line
+	c.reply(okValue)
+	c.flush()
+	s.monitors.add(c)
+
+	return noReply
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/helewud/redis-clone/config"
+	"github.com/helewud/redis-clone/resp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonitor(t *testing.T) {
+	s, _, addr, _ := serve(t)
+
+	m := dial(t, addr)
+	assert.Equal(t, "OK", m.do("MONITOR").String)
+
+	c := dial(t, addr)
+	c.do("SET", "monitored", "a \"b\"\n")
+	c.do("AUTH", "secret")
+	c.do("CONFIG", "GET", "port")
+	c.do("GET", "monitored")
+
+	// admin commands are left out, passwords are redacted
+	prefix := `^\d+\.\d{6} \[0 ` + strings.ReplaceAll(c.conn.LocalAddr().String(), ".", `\.`) + `\] `
+	for _, want := range []string{
+		`"SET" "monitored" "a \\"b\\"\\n"`,
+		`"AUTH" "\(redacted\)"`,
+		`"GET" "monitored"`,
+	} {
+		line, err := m.reader.Read()
+		require.NoError(t, err)
+		assert.Equal(t, resp.RespTString, line.T)
+		assert.Regexp(t, prefix+want+"$", line.String)
+	}
+
+	var flags []string
+	for _, line := range strings.Split(strings.TrimSpace(string(c.do("CLIENT", "LIST").Bulk)), "\n") {
+		flags = append(flags, fields(line)["flags"])
+	}
+	assert.Equal(t, []string{"O", "N"}, flags)
+
+	// monitoring ends with the connection
+	require.NoError(t, m.conn.Close())
+	require.Eventually(t, func() bool {
+		return s.monitors.n.Load() == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestMonitorTimeout(t *testing.T) {
+	cfg := config.Default()
+	cfg.Timeout = 100 * time.Millisecond
+	_, _, addr, _ := serveConfig(t, cfg)
+
+	m := dial(t, addr)
+	assert.Equal(t, "OK", m.do("MONITOR").String)
+
+	// the monitor outlives the timeout while the other client does not
+	c := dial(t, addr)
+	time.Sleep(300 * time.Millisecond)
+	_, err := c.reader.Read()
+	assert.ErrorIs(t, err, io.EOF)
+
+	dial(t, addr).do("PING")
+	line, err := m.reader.Read()
+	require.NoError(t, err)
+	assert.Contains(t, line.String, `"PING"`)
+}
+
+func TestMonitorSlow(t *testing.T) {
+	s := New(nil, nil)
+
+	// nothing reads the pipe, so the monitor is stuck on its first line
+	client, server := net.Pipe()
+	defer client.Close()
+	slow := newConn(server, 1)
+	s.monitors.add(slow)
+
+	cmd, _ := s.commands.Lookup("ping")
+	value := bulks("PING")
+
+	// the writer buffers some lines before it blocks, then the channel
+	// fills up and the monitor is dropped
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100*monitorBuffer && s.monitors.n.Load() > 0; i++ {
+			s.feedMonitors(slow, cmd, value, time.Now())
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("feeding a slow monitor blocked")
+	}
+
+	assert.Zero(t, s.monitors.n.Load())
+	assert.True(t, slow.killed.Load())
+}
+
+func TestMonitorStalled(t *testing.T) {
+	s := New(nil, nil)
+
+	// nothing reads the pipe, so flushing the first line stalls
+	client, server := net.Pipe()
+	defer client.Close()
+	stalled := newConn(server, 1)
+	s.monitors.add(stalled)
+
+	cmd, _ := s.commands.Lookup("ping")
+	s.feedMonitors(stalled, cmd, bulks("PING"), time.Now())
+
+	// the flush times out, so CLIENT LIST is not held up for long
+	described := make(chan string)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		described <- stalled.describe()
+	}()
+	select {
+	case line := <-described:
+		assert.Contains(t, line, "flags=O")
+	case <-time.After(5 * time.Second):
+		t.Fatal("describing a stalled monitor blocked")
+	}
+
+	// and the monitor is disconnected
+	_, err := client.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+}
@@ -0,0 +1,302 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/helewud/redis-clone/acl"
+	"github.com/helewud/redis-clone/commands"
+	"github.com/helewud/redis-clone/config"
+	"github.com/helewud/redis-clone/logger"
+	"github.com/helewud/redis-clone/resp"
+	"github.com/helewud/redis-clone/storage"
+)
+
+// Server answers RESP clients from the in-memory store and persists write
+// commands to the AOF
+type Server struct {
+	store  *storage.Aof
+	config *config.Manager
+	acl    *acl.ACL
+	log    *slog.Logger
+
+	// commands are those of the commands package along with the ones
+	// that act on the server itself
+	commands commands.Table
+
+	// nextID numbers the connections
+	nextID atomic.Int64
+
+	// mu guards the listeners and connections, wg counts the connections
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[int64]*conn
+	wg        sync.WaitGroup
+
+	pause    pause
+	slowlog  slowlog
+	monitors monitors
+
+	// started, runID, replID and stats are reported by INFO
+	started time.Time
+	runID   string
+	replID  string
+	stats   stats
+
+	inShutdown   atomic.Bool
+	shutdownOnce sync.Once
+	shutdownErr  error
+	done         chan struct{}
+}
+
+// New returns a server for store, configured by cfg or with the defaults
+// when cfg is nil
+func New(store *storage.Aof, cfg *config.Manager) *Server {
+	if cfg == nil {
+		cfg = config.NewManager(config.Default(), "")
+	}
+
+	s := &Server{
+		store:     store,
+		config:    cfg,
+		log:       slog.Default(),
+		listeners: map[net.Listener]struct{}{},
+		conns:     map[int64]*conn{},
+		started:   time.Now(),
+		runID:     randomID(),
+		replID:    randomID(),
+		done:      make(chan struct{}),
+	}
+
+	s.commands = commands.Commands.Clone()
+	s.commands.Add(s.serverCommands()...)
+
+	s.acl = acl.New(s.commands)
+	if err := s.applyRequirePass(cfg.Config().RequirePass); err != nil {
+		s.log.Error("applying requirepass", "err", err)
+	}
+
+	return s
+}
+
+// Serve accepts connections on ln and handles each one in its own
+// goroutine. It returns when accepting fails, e.g. once ln is closed. After
+// Shutdown it waits for the shutdown to complete and returns
+// ErrServerClosed.
+func (s *Server) Serve(ln net.Listener) error {
+	if !s.trackListener(ln) {
+		return ErrServerClosed
+	}
+	defer s.untrackListener(ln)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if s.inShutdown.Load() {
+				<-s.done
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Server) handleConn(netConn net.Conn) {
+	// one reader and writer per connection, so pipelined commands already
+	// buffered are not lost between loops and their replies are coalesced
+	c := newConn(netConn, s.nextID.Add(1))
+	c.setLogger(s.log.With("client", c.session.ID, "addr", c.session.Addr))
+	tlsConn, isTLS := netConn.(*tls.Conn)
+
+	// the connection counts towards maxclients and is seen by Shutdown and
+	// CLIENT LIST while it handshakes
+	if err := s.trackConn(c); err != nil {
+		if errors.Is(err, errMaxClients) {
+			s.stats.rejected.Add(1)
+			c.log.Warn("rejected client", "err", err)
+			// a TLS client can't be answered without a handshake
+			if !isTLS {
+				c.reply(resp.Value{T: resp.RespTError, String: "ERR " + err.Error()})
+			}
+		}
+		c.close()
+		return
+	}
+	defer s.untrackConn(c)
+	defer c.close()
+
+	var user string
+	if isTLS {
+		var err error
+		if user, err = s.handshake(tlsConn); err != nil {
+			c.log.Log(context.Background(), logger.LevelVerbose, "tls handshake failed", "err", err)
+			return
+		}
+	}
+	s.initSession(c.session, user)
+
+	defer s.monitors.remove(c.session.ID)
+	s.stats.connections.Add(1)
+
+	c.log.Debug("client connected", "user", c.session.User)
+	defer func() { c.log.Debug("client disconnected") }()
+
+	for {
+		c.syncSession()
+
+		// a shutdown that began before the deadline is set must still
+		// cut the read off. Monitors never send anything, like in Redis
+		// they are not idle.
+		var deadline time.Time
+		if timeout := s.config.Timeout(); timeout > 0 && !c.monitoring.Load() {
+			deadline = time.Now().Add(timeout)
+		}
+		c.netConn.SetReadDeadline(deadline)
+		if s.inShutdown.Load() {
+			return
+		}
+
+		// CONFIG SET may have changed the limits since the last command
+		c.reader.SetLimits(s.config.Limits())
+
+		value, err := validateRespInput(c.reader)
+		var protoErr *resp.ProtocolError
+		switch {
+		case err != nil && (s.inShutdown.Load() || c.killed.Load()):
+			// reads are cut off when shutting down or killed
+			return
+		case err == nil:
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			c.log.Log(context.Background(), logger.LevelVerbose, "closing idle client")
+			return
+		case errors.Is(err, errEmptyRequest):
+			// like Redis, empty commands are skipped without a reply
+			continue
+		case errors.Is(err, resp.ErrUnexpectedEOF):
+			// the client went away in the middle of a command
+			c.log.Debug("client closed mid-command", "err", err)
+			return
+		case errors.As(err, &protoErr):
+			// the stream is out of sync, answer and hang up
+			c.log.Warn("protocol error", "err", err)
+
+			c.reply(resp.Value{T: resp.RespTError, String: "ERR " + protoErr.Error()})
+			c.flush()
+
+			return
+		case err == io.EOF:
+			// clean disconnect
+			return
+		default:
+			c.log.Warn("read failed", "err", err)
+			return
+		}
+
+		command := string(value.Array[0].Bulk)
+		args := value.Array[1:]
+
+		cmd, err := s.validateRespCommand(command)
+		if err != nil {
+			c.log.Debug("unknown command", "cmd", strings.ToLower(command))
+
+			c.reply(commands.UnknownCommand(command, args))
+
+			continue
+		}
+		c.begin(cmd, args)
+
+		if !cmd.CheckArity(args) {
+			c.reply(cmd.WrongArity())
+			continue
+		}
+		if sub, ok := cmd.Subcommand(args); ok && !sub.CheckArity(args) {
+			c.reply(sub.WrongArity())
+			continue
+		}
+
+		if reply, ok := s.authorize(c.session, cmd, args); !ok {
+			c.reply(reply)
+			continue
+		}
+
+		// CLIENT is never paused, so it can end the pause
+		if cmd.Name != "client" {
+			s.pause.wait(cmd.HasFlag(commands.FlagWrite))
+		}
+
+		start := time.Now()
+		reply := cmd.Handler(c.session, args)
+		s.logSlow(c, cmd, value.Array, start, time.Since(start))
+		s.feedMonitors(c, cmd, value.Array, start)
+		s.stats.commands.Add(1)
+		c.reply(reply)
+
+		// writes that went through are appended to the AOF
+		if cmd.HasFlag(commands.FlagWrite) && reply.T != resp.RespTError && s.store != nil {
+			if err := s.store.Write(*value); err != nil {
+				c.log.Error("writing the AOF", "cmd", cmd.Name, "err", err)
+			}
+		}
+
+		// commands still buffered are dropped, the client sees the
+		// connection close after the replies so far
+		if s.inShutdown.Load() || c.killed.Load() {
+			return
+		}
+	}
+
+}
+
+// errInvalidRequest is the cause of the protocol error for a command that
+// is not made of bulk strings, errEmptyRequest is a command without any
+var (
+	errInvalidRequest = errors.New("invalid request")
+	errEmptyRequest   = errors.New("empty request")
+)
+
+var errMaxClients = errors.New("max number of clients reached")
+
+func validateRespInput(reader *resp.Reader) (*resp.Value, error) {
+	value, err := reader.ReadCommand()
+	if err != nil {
+		return nil, err
+	}
+
+	// the RESP2 null array counts as empty, as in Redis
+	if value.T == resp.RespTNull || len(value.Array) == 0 {
+		return nil, errEmptyRequest
+	}
+
+	for _, arg := range value.Array {
+		if arg.T != resp.RespTBulk {
+			return nil, &resp.ProtocolError{
+				Msg:    fmt.Sprintf("expected bulk string arguments, got %s", arg.T),
+				Offset: reader.Offset(),
+				Err:    errInvalidRequest,
+			}
+		}
+	}
+
+	return &value, nil
+}
+
+func (s *Server) validateRespCommand(command string) (*commands.Command, error) {
+	cmd, ok := s.commands.Lookup(command)
+	if !ok {
+		return nil, fmt.Errorf("invalid command: %v", command)
+	}
+
+	return cmd, nil
+}
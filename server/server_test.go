@@ -0,0 +1,502 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/helewud/redis-clone/logger"
+	"github.com/helewud/redis-clone/resp"
+	"github.com/helewud/redis-clone/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRespInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []byte
+		expectError bool
+		expectIs    error
+		expectValue *resp.Value
+	}{
+		{
+			name:        "valid SET command",
+			input:       []byte("*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n"),
+			expectError: false,
+			expectValue: &resp.Value{
+				T: resp.RespTArray,
+				Array: []resp.Value{
+					{T: resp.RespTBulk, Bulk: []byte("SET")},
+					{T: resp.RespTBulk, Bulk: []byte("key")},
+					{T: resp.RespTBulk, Bulk: []byte("value")},
+				},
+			},
+		},
+		{
+			name:        "empty array",
+			input:       []byte("*0\r\n"),
+			expectError: true,
+			expectIs:    errEmptyRequest,
+		},
+		{
+			name:        "null array",
+			input:       []byte("*-1\r\n"),
+			expectError: true,
+			expectIs:    errEmptyRequest,
+		},
+		{
+			name:        "integer argument",
+			input:       []byte("*2\r\n$4\r\nECHO\r\n:1\r\n"),
+			expectError: true,
+			expectIs:    errInvalidRequest,
+		},
+		{
+			name:        "inline command",
+			input:       []byte("SET key \"hello world\"\r\n"),
+			expectError: false,
+			expectValue: &resp.Value{
+				T: resp.RespTArray,
+				Array: []resp.Value{
+					{T: resp.RespTBulk, Bulk: []byte("SET")},
+					{T: resp.RespTBulk, Bulk: []byte("key")},
+					{T: resp.RespTBulk, Bulk: []byte("hello world")},
+				},
+			},
+		},
+		{
+			name:        "empty inline command",
+			input:       []byte("\r\n"),
+			expectError: true,
+			expectIs:    errEmptyRequest,
+		},
+		{
+			name:        "inline command with unbalanced quotes",
+			input:       []byte("SET key \"hello\r\n"),
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientConn, serverConn := net.Pipe()
+			defer clientConn.Close()
+			defer serverConn.Close()
+
+			// Write input in a separate goroutine
+			go func(data []byte) {
+				_, _ = clientConn.Write(data)
+				_ = clientConn.Close() // signal EOF to the other end
+			}(tt.input)
+
+			// Now read from `serverConn` as if it's the "server side"
+			value, err := validateRespInput(resp.NewReader(serverConn))
+
+			if tt.expectError {
+				assert.Error(t, err, "expected an error but got none")
+				if tt.expectIs != nil {
+					assert.ErrorIs(t, err, tt.expectIs)
+				}
+				return
+			}
+
+			require.NoError(t, err, "unexpected error when reading input")
+			assert.Equal(t, tt.expectValue, value, "parsed Value mismatch")
+		})
+	}
+}
+
+// Test validateRespCommand
+func TestValidateRespCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		command     string
+		expectError bool
+	}{
+		{
+			name:        "valid SET command",
+			command:     "SET",
+			expectError: false,
+		},
+		{
+			name:        "valid GET command",
+			command:     "GET",
+			expectError: false,
+		},
+		{
+			name:        "valid server command",
+			command:     "SHUTDOWN",
+			expectError: false,
+		},
+		{
+			name:        "invalid command",
+			command:     "INVALID",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := New(nil, nil).validateRespCommand(tt.command)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, handler)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, handler)
+			}
+		})
+	}
+}
+
+func TestHandleConnProtocolError(t *testing.T) {
+	store, err := storage.NewAof(filepath.Join(t.TempDir(), "storage.store"))
+	require.NoError(t, err)
+	defer store.Close()
+	s := New(store, nil)
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	done := make(chan struct{})
+	go func() {
+		s.handleConn(serverConn)
+		close(done)
+	}()
+
+	go func() {
+		_, _ = clientConn.Write([]byte("*1\r\n$600000000\r\n"))
+	}()
+
+	reply, err := io.ReadAll(clientConn)
+	require.NoError(t, err)
+	assert.Equal(t, "-ERR Protocol error: invalid bulk length\r\n", string(reply))
+
+	<-done
+}
+
+func TestHandleConnErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		replies []resp.Value
+
+		// closed is whether the server hangs up after the replies
+		closed bool
+	}{
+		{
+			name:  "unknown command",
+			input: "*3\r\n$4\r\nNOPE\r\n$1\r\na\r\n$1\r\nb\r\n",
+			replies: []resp.Value{
+				{T: resp.RespTError, String: "ERR unknown command 'NOPE', with args beginning with: 'a' 'b' "},
+			},
+		},
+		{
+			name:  "unknown inline command",
+			input: "nope\r\n",
+			replies: []resp.Value{
+				{T: resp.RespTError, String: "ERR unknown command 'nope', with args beginning with: "},
+			},
+		},
+		{
+			name:  "unknown command with long args",
+			input: fmt.Sprintf("NOPE %s b\r\n", strings.Repeat("a", 200)),
+			replies: []resp.Value{
+				{T: resp.RespTError, String: "ERR unknown command 'NOPE', with args beginning with: '" + strings.Repeat("a", 128) + "' "},
+			},
+		},
+		{
+			name:  "line breaks in the name",
+			input: "*1\r\n$6\r\nNO\r\nPE\r\n",
+			replies: []resp.Value{
+				{T: resp.RespTError, String: "ERR unknown command 'NO  PE', with args beginning with: "},
+			},
+		},
+		{
+			name:  "empty commands are skipped",
+			input: "*0\r\n\r\n*-1\r\n",
+		},
+		{
+			name:  "argument that is not a bulk string",
+			input: "*2\r\n$4\r\nECHO\r\n:1\r\n",
+			replies: []resp.Value{
+				{T: resp.RespTError, String: "ERR Protocol error: expected bulk string arguments, got integer"},
+			},
+			closed: true,
+		},
+		{
+			name:  "invalid length",
+			input: "*1\r\n$-5\r\n",
+			replies: []resp.Value{
+				{T: resp.RespTError, String: "ERR Protocol error: invalid bulk length"},
+			},
+			closed: true,
+		},
+	}
+
+	_, _, addr, _ := serve(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := dial(t, addr)
+
+			// a PING after the input tells whether the connection is
+			// still served
+			_, err := c.conn.Write([]byte(tt.input + "PING\r\n"))
+			require.NoError(t, err)
+
+			for _, want := range tt.replies {
+				reply, err := c.reader.Read()
+				require.NoError(t, err)
+				assert.Equal(t, want, reply)
+			}
+
+			reply, err := c.reader.Read()
+			if tt.closed {
+				assert.ErrorIs(t, err, io.EOF)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, resp.Value{T: resp.RespTString, String: "PONG"}, reply)
+		})
+	}
+}
+
+func TestHandleConnEOF(t *testing.T) {
+	s, _, addr, _ := serve(t)
+
+	c := dial(t, addr)
+	require.Equal(t, "PONG", c.do("PING").String)
+	require.Len(t, s.clients(), 1)
+
+	// the goroutine ends and the client is gone from the registry
+	require.NoError(t, c.conn.Close())
+	require.Eventually(t, func() bool {
+		return len(s.clients()) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestHandleConnDisconnect(t *testing.T) {
+	store, err := storage.NewAof(filepath.Join(t.TempDir(), "storage.store"))
+	require.NoError(t, err)
+	defer store.Close()
+	s := New(store, nil)
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "clean disconnect", input: "*1\r\n$4\r\nPING\r\n"},
+		{name: "disconnect mid command", input: "*1\r\n$4\r\nPING\r\n*2\r\n$3\r\nGET\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientConn, serverConn := net.Pipe()
+
+			done := make(chan struct{})
+			go func() {
+				s.handleConn(serverConn)
+				close(done)
+			}()
+
+			_, err := clientConn.Write([]byte(tt.input))
+			require.NoError(t, err)
+
+			reply := make([]byte, len("+PONG\r\n"))
+			_, err = io.ReadFull(clientConn, reply)
+			require.NoError(t, err)
+			assert.Equal(t, "+PONG\r\n", string(reply))
+
+			require.NoError(t, clientConn.Close())
+			<-done
+		})
+	}
+}
+
+func TestHandleConnHello(t *testing.T) {
+	store, err := storage.NewAof(filepath.Join(t.TempDir(), "storage.store"))
+	require.NoError(t, err)
+	defer store.Close()
+	s := New(store, nil)
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go s.handleConn(serverConn)
+
+	reader := resp.NewReader(clientConn)
+	defer reader.Release()
+
+	_, err = clientConn.Write([]byte("*2\r\n$5\r\nHELLO\r\n$1\r\n3\r\n"))
+	require.NoError(t, err)
+
+	reply, err := reader.Read()
+	require.NoError(t, err)
+	require.Equal(t, resp.RespTMap, reply.T)
+	assert.Equal(t, resp.Value{T: resp.RespTInteger, Number: 3}, reply.Array[5])
+	assert.Equal(t, resp.RespTInteger, reply.Array[7].T, "connection id")
+
+	_, err = clientConn.Write([]byte("*4\r\n$4\r\nHSET\r\n$5\r\nhello\r\n$1\r\nf\r\n$1\r\nv\r\n*2\r\n$7\r\nHGETALL\r\n$5\r\nhello\r\n"))
+	require.NoError(t, err)
+
+	_, err = reader.Read()
+	require.NoError(t, err)
+	reply, err = reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, resp.RespTMap, reply.T)
+}
+
+func TestConnPush(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	c := newConn(serverConn, 1)
+	defer c.close()
+	c.session.Proto = resp.Resp3
+	c.writer.SetProtocol(resp.Resp3)
+
+	go c.session.Push(resp.Value{
+		T: resp.RespTPush,
+		Array: []resp.Value{
+			{T: resp.RespTBulk, Bulk: []byte("message")},
+			{T: resp.RespTBulk, Bulk: []byte("hello")},
+		},
+	})
+
+	reader := resp.NewReader(clientConn)
+	defer reader.Release()
+
+	v, err := reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, resp.RespTPush, v.T)
+	assert.Equal(t, []byte("hello"), v.Array[1].Bulk)
+}
+
+func TestServePipelining(t *testing.T) {
+	store, err := storage.NewAof(filepath.Join(t.TempDir(), "storage.store"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go New(store, nil).Serve(ln)
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	const n = 10000
+
+	// every command goes out in a single write
+	var batch []byte
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("pipeline:%d", i)
+		cmd := resp.Value{
+			T: resp.RespTArray,
+			Array: []resp.Value{
+				{T: resp.RespTBulk, Bulk: []byte("SET")},
+				{T: resp.RespTBulk, Bulk: []byte(key)},
+				{T: resp.RespTBulk, Bulk: []byte(strconv.Itoa(i))},
+			},
+		}
+		batch = cmd.Append(batch)
+	}
+	batch = append(batch, "*2\r\n$3\r\nGET\r\n$13\r\npipeline:9999\r\n"...)
+
+	go conn.Write(batch)
+
+	reader := resp.NewReader(conn)
+	defer reader.Release()
+
+	for i := 0; i < n; i++ {
+		reply, err := reader.Read()
+		require.NoError(t, err, "reply %d", i)
+		require.Equal(t, resp.Value{T: resp.RespTString, String: "OK"}, reply, "reply %d", i)
+	}
+
+	reply, err := reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "9999", string(reply.Bulk))
+}
+
+// syncBuffer collects log lines written from the connection goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func TestConnLog(t *testing.T) {
+	require.NoError(t, logger.SetLevel("debug"))
+	t.Cleanup(func() { logger.SetLevel("notice") })
+
+	var logs syncBuffer
+	s := New(nil, nil)
+	s.log = logger.New(&logs, "json")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go s.Serve(ln)
+
+	c := dial(t, ln.Addr().String())
+	id := c.do("CLIENT", "ID").Number
+	c.do("NOPE")
+
+	// a request that is not made of bulk strings ends the connection
+	_, err = c.conn.Write([]byte("*1\r\n:1\r\n"))
+	require.NoError(t, err)
+	reply, err := c.reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, resp.RespTError, reply.T)
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(logs.String(), "client disconnected")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var fields map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &fields))
+		lines = append(lines, fields)
+	}
+
+	// every line carries the client and command, the last one run outside
+	// of commands
+	require.Len(t, lines, 4)
+	for _, line := range lines {
+		assert.Equal(t, float64(id), line["client"])
+		assert.Equal(t, c.conn.LocalAddr().String(), line["addr"])
+		assert.Contains(t, line, "cmd")
+	}
+	assert.Equal(t, "client connected", lines[0]["msg"])
+	assert.Equal(t, "unknown command", lines[1]["msg"])
+	assert.Equal(t, "nope", lines[1]["cmd"])
+	assert.Equal(t, "protocol error", lines[2]["msg"])
+	assert.Equal(t, "client|id", lines[2]["cmd"])
+	assert.Equal(t, "client disconnected", lines[3]["msg"])
+	assert.Equal(t, "client|id", lines[3]["cmd"])
+}
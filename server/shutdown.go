@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/helewud/redis-clone/commands"
+	"github.com/helewud/redis-clone/resp"
+)
+
+// ErrServerClosed is returned by Serve once the server is shut down
+var ErrServerClosed = errors.New("server closed")
+
+// ShutdownTimeout is how long in-flight commands get to finish, on SHUTDOWN
+// or a signal
+const ShutdownTimeout = 10 * time.Second
+
+// noReply is returned by handlers that answer by closing the connection
+var noReply = resp.Value{}
+
+func (s *Server) trackListener(ln net.Listener) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.inShutdown.Load() {
+		return false
+	}
+	s.listeners[ln] = struct{}{}
+
+	return true
+}
+
+func (s *Server) untrackListener(ln net.Listener) {
+	s.mu.Lock()
+	delete(s.listeners, ln)
+	s.mu.Unlock()
+}
+
+func (s *Server) trackConn(c *conn) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.inShutdown.Load() {
+		return ErrServerClosed
+	}
+	if len(s.conns) >= s.config.Config().MaxClients {
+		return errMaxClients
+	}
+	s.conns[c.session.ID] = c
+	s.wg.Add(1)
+
+	return nil
+}
+
+func (s *Server) untrackConn(c *conn) {
+	s.mu.Lock()
+	delete(s.conns, c.session.ID)
+	s.mu.Unlock()
+
+	s.wg.Done()
+}
+
+// Shutdown stops accepting connections and lets the commands being run
+// finish until ctx is done, then closes every client and finally the AOF,
+// which flushes it to disk. Commands the clients sent afterwards are not
+// run. Calling Shutdown again waits for the first call to complete.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.startShutdown(ctx)
+	<-s.done
+
+	return s.shutdownErr
+}
+
+// startShutdown begins the shutdown without waiting for it to complete
+func (s *Server) startShutdown(ctx context.Context) {
+	s.shutdownOnce.Do(func() {
+		s.mu.Lock()
+		s.inShutdown.Store(true)
+		for ln := range s.listeners {
+			ln.Close()
+		}
+		// idle clients are blocked reading, wake them up. Busy ones finish
+		// their command and notice the shutdown.
+		for _, c := range s.conns {
+			c.netConn.SetReadDeadline(time.Now())
+		}
+		s.mu.Unlock()
+		s.pause.lift()
+
+		go func() {
+			err := s.drain(ctx)
+			if s.store != nil {
+				if closeErr := s.store.Close(); err == nil {
+					err = closeErr
+				}
+			}
+
+			s.shutdownErr = err
+			close(s.done)
+		}()
+	})
+}
+
+// drain waits for the connections to close, forcing them closed when ctx
+// is done first
+func (s *Server) drain(ctx context.Context) error {
+	drained := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	for _, c := range s.conns {
+		c.netConn.Close()
+	}
+	s.mu.Unlock()
+	<-drained
+
+	return ctx.Err()
+}
+
+// shutdownCommand answers SHUTDOWN [SAVE|NOSAVE]. There are no snapshots,
+// so both modes only flush the AOF, as Redis does with AOF enabled. The
+// client gets no reply, the connection closes once the server is down.
+func (s *Server) shutdownCommand(session *commands.Session, args []resp.Value) resp.Value {
+	if len(args) > 1 {
+		return resp.Value{T: resp.RespTError, String: "ERR syntax error"}
+	}
+	if len(args) == 1 {
+		switch strings.ToUpper(string(args[0].Bulk)) {
+		case "SAVE", "NOSAVE":
+		default:
+			return resp.Value{T: resp.RespTError, String: "ERR syntax error"}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	s.startShutdown(ctx)
+	go func() {
+		<-s.done
+		cancel()
+	}()
+
+	return noReply
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/helewud/redis-clone/commands"
+	"github.com/helewud/redis-clone/config"
+	"github.com/helewud/redis-clone/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serve runs a server on a random local port, the returned channel gets the
+// result of Serve
+func serve(t *testing.T) (*Server, *storage.Aof, string, <-chan error) {
+	t.Helper()
+
+	return serveConfig(t, config.Default())
+}
+
+// serveConfig is serve with the given settings
+func serveConfig(t *testing.T, cfg config.Config) (*Server, *storage.Aof, string, <-chan error) {
+	t.Helper()
+
+	store, err := storage.NewAof(filepath.Join(t.TempDir(), "storage.store"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	s := New(store, config.NewManager(cfg, ""))
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(ln) }()
+
+	return s, store, ln.Addr().String(), served
+}
+
+func TestShutdown(t *testing.T) {
+	s, store, addr, served := serve(t)
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("*1\r\n$4\r\nPING\r\n"))
+	require.NoError(t, err)
+	reply := make([]byte, len("+PONG\r\n"))
+	_, err = io.ReadFull(conn, reply)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, s.Shutdown(ctx))
+	assert.ErrorIs(t, <-served, ErrServerClosed)
+
+	// the idle client was disconnected and the AOF closed
+	_, err = conn.Read(reply)
+	assert.Equal(t, io.EOF, err)
+	assert.ErrorIs(t, store.Sync(), os.ErrClosed)
+
+	_, err = net.Dial("tcp", addr)
+	assert.Error(t, err)
+
+	// later calls wait for the same shutdown
+	require.NoError(t, s.Shutdown(ctx))
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	store, err := storage.NewAof(filepath.Join(t.TempDir(), "storage.store"))
+	require.NoError(t, err)
+	s := New(store, nil)
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	done := make(chan struct{})
+	go func() {
+		s.handleConn(serverConn)
+		close(done)
+	}()
+
+	// the client never reads its reply, so the server is stuck writing it
+	_, err = clientConn.Write([]byte("*1\r\n$4\r\nPING\r\n"))
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.Shutdown(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(t, store.Sync(), os.ErrClosed)
+	<-done
+}
+
+func TestShutdownCommand(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		down  bool
+	}{
+		{
+			name:  "no arguments",
+			input: "*1\r\n$8\r\nSHUTDOWN\r\n",
+			down:  true,
+		},
+		{
+			name:  "nosave",
+			input: "*2\r\n$8\r\nSHUTDOWN\r\n$6\r\nNOSAVE\r\n",
+			down:  true,
+		},
+		{
+			name: "commands after shutdown are dropped",
+			input: "*3\r\n$3\r\nSET\r\n$15\r\nshutdown:before\r\n$1\r\n1\r\n" +
+				"*1\r\n$8\r\nSHUTDOWN\r\n" +
+				"*3\r\n$3\r\nSET\r\n$14\r\nshutdown:after\r\n$1\r\n1\r\n",
+			want: "+OK\r\n",
+			down: true,
+		},
+		{
+			name:  "bad option",
+			input: "*2\r\n$8\r\nSHUTDOWN\r\n$5\r\nLATER\r\n",
+			want:  "-ERR syntax error\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, store, addr, served := serve(t)
+
+			conn, err := net.Dial("tcp", addr)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = conn.Write([]byte(tt.input))
+			require.NoError(t, err)
+
+			if !tt.down {
+				reply := make([]byte, len(tt.want))
+				_, err = io.ReadFull(conn, reply)
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, string(reply))
+				return
+			}
+
+			reply, err := io.ReadAll(conn)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(reply))
+			assert.ErrorIs(t, <-served, ErrServerClosed)
+			assert.ErrorIs(t, store.Sync(), os.ErrClosed)
+
+			commands.SETsMu.RLock()
+			_, ok := commands.SETs["shutdown:after"]
+			commands.SETsMu.RUnlock()
+			assert.False(t, ok)
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/helewud/redis-clone/commands"
+	"github.com/helewud/redis-clone/resp"
+)
+
+// Entries keep at most this many arguments and bytes of each, like Redis
+const (
+	slowlogMaxArgs  = 32
+	slowlogMaxBytes = 128
+)
+
+// slowlogEntry is a command that ran slower than slowlog-log-slower-than
+type slowlogEntry struct {
+	id       int64
+	time     time.Time
+	duration time.Duration
+	args     []string
+	addr     string
+	name     string
+}
+
+// slowlog keeps the latest slow commands in a ring buffer
+type slowlog struct {
+	mu     sync.Mutex
+	nextID int64
+
+	// ring holds up to slowlog-max-len entries, next is where the next one
+	// goes and n how many there are
+	ring []slowlogEntry
+	next int
+	n    int
+}
+
+// add records e, numbering it. The ring is resized to maxLen first.
+func (l *slowlog) add(e slowlogEntry, maxLen int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.id = l.nextID
+	l.nextID++
+
+	l.resize(maxLen)
+	if maxLen == 0 {
+		return
+	}
+
+	l.ring[l.next] = e
+	l.next = (l.next + 1) % len(l.ring)
+	l.n = min(l.n+1, len(l.ring))
+}
+
+// setMaxLen resizes the ring when slowlog-max-len changes
+func (l *slowlog) setMaxLen(maxLen int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.resize(maxLen)
+}
+
+// resize makes room for maxLen entries, keeping the newest ones. The caller
+// holds mu.
+func (l *slowlog) resize(maxLen int) {
+	if maxLen == len(l.ring) {
+		return
+	}
+
+	entries := l.newest(min(l.n, maxLen))
+	l.ring = make([]slowlogEntry, maxLen)
+	l.n = len(entries)
+	for i, entry := range entries {
+		l.ring[l.n-1-i] = entry
+	}
+	l.next = l.n % max(maxLen, 1)
+}
+
+// newest returns up to count entries, newest first. The caller holds mu.
+func (l *slowlog) newest(count int) []slowlogEntry {
+	count = min(count, l.n)
+	entries := make([]slowlogEntry, count)
+	for i := range entries {
+		entries[i] = l.ring[(l.next-1-i+len(l.ring))%len(l.ring)]
+	}
+
+	return entries
+}
+
+// entries returns up to count entries, all of them when count is negative
+func (l *slowlog) entries(count int) []slowlogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if count < 0 {
+		count = l.n
+	}
+
+	return l.newest(count)
+}
+
+func (l *slowlog) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.n
+}
+
+func (l *slowlog) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	clear(l.ring)
+	l.next, l.n = 0, 0
+}
+
+// redact hides the passwords among the arguments of a command, name
+// included, before they are logged
+func redact(cmd *commands.Command, args []string) []string {
+	const redacted = "(redacted)"
+
+	args = append([]string{}, args...)
+	switch {
+	case cmd.Name == "auth":
+		for i := 1; i < len(args); i++ {
+			args[i] = redacted
+		}
+	case cmd.Name == "acl" && len(args) > 2 && strings.EqualFold(args[1], "SETUSER"):
+		// the rules after the user name may set passwords
+		for i := 3; i < len(args); i++ {
+			args[i] = redacted
+		}
+	case cmd.Name == "config" && len(args) > 2 && strings.EqualFold(args[1], "SET"):
+		for i := 2; i+1 < len(args); i += 2 {
+			if strings.EqualFold(args[i], "requirepass") {
+				args[i+1] = redacted
+			}
+		}
+	}
+
+	return args
+}
+
+// slowlogArgs are the arguments of an entry, cut to the limits
+func slowlogArgs(cmd *commands.Command, value []resp.Value) []string {
+	n := min(len(value), slowlogMaxArgs)
+	args := make([]string, n)
+	for i := range args {
+		args[i] = string(value[i].Bulk)
+	}
+	args = redact(cmd, args)
+
+	for i, arg := range args {
+		if len(arg) > slowlogMaxBytes {
+			args[i] = fmt.Sprintf("%s... (%d more bytes)", arg[:slowlogMaxBytes], len(arg)-slowlogMaxBytes)
+		}
+	}
+	if len(value) > slowlogMaxArgs {
+		args[n-1] = fmt.Sprintf("... (%d more arguments)", len(value)-slowlogMaxArgs+1)
+	}
+
+	return args
+}
+
+// logSlow records the command in value in the slow log when it took longer
+// than the threshold
+func (s *Server) logSlow(c *conn, cmd *commands.Command, value []resp.Value, start time.Time, duration time.Duration) {
+	slowerThan, maxLen := s.config.Slowlog()
+	if slowerThan < 0 || duration < slowerThan {
+		return
+	}
+
+	s.slowlog.add(slowlogEntry{
+		time:     start,
+		duration: duration,
+		args:     slowlogArgs(cmd, value),
+		addr:     c.session.Addr,
+		name:     c.session.Name,
+	}, maxLen)
+}
+
+// slowlogCommand answers the SLOWLOG subcommands
+func (s *Server) slowlogCommand(session *commands.Session, args []resp.Value) resp.Value {
+	name := string(args[0].Bulk)
+
+	switch strings.ToUpper(name) {
+	case "GET":
+		count := 10
+		if len(args) > 2 {
+			return errorValue("ERR wrong number of arguments for 'slowlog|get' command")
+		}
+		if len(args) == 2 {
+			n, err := strconv.Atoi(string(args[1].Bulk))
+			if err != nil {
+				return errorValue("ERR value is not an integer or out of range")
+			}
+			if n < -1 {
+				return errorValue("ERR count should be greater than or equal to -1")
+			}
+			count = n
+		}
+
+		entries := s.slowlog.entries(count)
+		res := resp.Value{T: resp.RespTArray, Array: make([]resp.Value, len(entries))}
+		for i, e := range entries {
+			res.Array[i] = resp.Value{
+				T: resp.RespTArray,
+				Array: []resp.Value{
+					integer(int(e.id)),
+					integer(int(e.time.Unix())),
+					integer(int(e.duration / time.Microsecond)),
+					bulkArray(e.args),
+					bulk(e.addr),
+					bulk(e.name),
+				},
+			}
+		}
+		return res
+	case "LEN":
+		return integer(s.slowlog.len())
+	case "RESET":
+		s.slowlog.reset()
+		return okValue
+	case "HELP":
+		return helpReply(
+			"SLOWLOG <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+			"GET [<count>]",
+			"    Return top <count> entries from the slowlog (default: 10, -1 mean all).",
+			"    Entries are made of:",
+			"    id, timestamp, time in microseconds, arguments array, client IP and port,",
+			"    client name",
+			"LEN",
+			"    Return the length of the slowlog.",
+			"RESET",
+			"    Reset the slowlog.",
+			"HELP",
+			"    Print this help.",
+		)
+	default:
+		return commands.ErrorReply(fmt.Sprintf("ERR unknown subcommand '%s'. Try SLOWLOG HELP.", name))
+	}
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/helewud/redis-clone/config"
+	"github.com/helewud/redis-clone/resp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func slowlogIDs(entries []slowlogEntry) []int64 {
+	ids := make([]int64, len(entries))
+	for i, e := range entries {
+		ids[i] = e.id
+	}
+
+	return ids
+}
+
+func TestSlowlogRing(t *testing.T) {
+	var l slowlog
+
+	for range 5 {
+		l.add(slowlogEntry{}, 3)
+	}
+	assert.Equal(t, 3, l.len())
+	assert.Equal(t, []int64{4, 3, 2}, slowlogIDs(l.entries(-1)))
+	assert.Equal(t, []int64{4, 3}, slowlogIDs(l.entries(2)))
+
+	// a shorter ring keeps the newest entries
+	l.add(slowlogEntry{}, 2)
+	assert.Equal(t, []int64{5, 4}, slowlogIDs(l.entries(10)))
+
+	// and a longer one all of them
+	l.add(slowlogEntry{}, 4)
+	l.add(slowlogEntry{}, 4)
+	l.add(slowlogEntry{}, 4)
+	assert.Equal(t, []int64{8, 7, 6, 5}, slowlogIDs(l.entries(-1)))
+
+	// shrinking takes effect right away
+	l.setMaxLen(3)
+	assert.Equal(t, []int64{8, 7, 6}, slowlogIDs(l.entries(-1)))
+
+	l.add(slowlogEntry{}, 0)
+	assert.Equal(t, 0, l.len())
+
+	l.add(slowlogEntry{}, 2)
+	l.reset()
+	assert.Empty(t, l.entries(-1))
+	l.add(slowlogEntry{}, 2)
+	assert.Equal(t, []int64{11}, slowlogIDs(l.entries(-1)))
+}
+
+func TestSlowlogArgs(t *testing.T) {
+	s := New(nil, nil)
+
+	args := make([]string, 40)
+	for i := range args {
+		args[i] = fmt.Sprint(i)
+	}
+	args[1] = strings.Repeat("a", 130)
+
+	cmd, _ := s.commands.Lookup("hset")
+	got := slowlogArgs(cmd, bulks(args...))
+	require.Len(t, got, slowlogMaxArgs)
+	assert.Equal(t, strings.Repeat("a", 128)+"... (2 more bytes)", got[1])
+	assert.Equal(t, "30", got[30])
+	assert.Equal(t, "... (9 more arguments)", got[31])
+
+	auth, _ := s.commands.Lookup("auth")
+	assert.Equal(t, []string{"AUTH", "(redacted)", "(redacted)"}, slowlogArgs(auth, bulks("AUTH", "user", "secret")))
+
+	acl, _ := s.commands.Lookup("acl")
+	assert.Equal(t, []string{"ACL", "SETUSER", "bob", "(redacted)", "(redacted)"},
+		slowlogArgs(acl, bulks("ACL", "SETUSER", "bob", "on", ">secret")))
+
+	cfg, _ := s.commands.Lookup("config")
+	assert.Equal(t, []string{"CONFIG", "SET", "maxclients", "10", "REQUIREPASS", "(redacted)"},
+		slowlogArgs(cfg, bulks("CONFIG", "SET", "maxclients", "10", "REQUIREPASS", "secret")))
+}
+
+func TestSlowlogCommand(t *testing.T) {
+	cfg := config.Default()
+	cfg.SlowlogLogSlowerThan = 0
+	_, _, addr, _ := serveConfig(t, cfg)
+
+	c := dial(t, addr)
+	assert.Equal(t, "OK", c.do("CLIENT", "SETNAME", "slow").String)
+	assert.Equal(t, "OK", c.do("SET", "slow", "v").String)
+
+	// SLOWLOG GET runs before it is logged itself
+	entries := c.do("SLOWLOG", "GET", "1")
+	require.Len(t, entries.Array, 1)
+	entry := entries.Array[0].Array
+	require.Len(t, entry, 6)
+	assert.Equal(t, resp.RespTInteger, entry[0].T)
+	assert.Positive(t, entry[1].Number)
+	assert.GreaterOrEqual(t, entry[2].Number, 0)
+	assert.Equal(t, []string{"SET", "slow", "v"}, strs(entry[3]))
+	assert.Equal(t, c.conn.LocalAddr().String(), string(entry[4].Bulk))
+	assert.Equal(t, "slow", string(entry[5].Bulk))
+
+	assert.Len(t, c.do("SLOWLOG", "GET").Array, 3)
+	assert.Len(t, c.do("SLOWLOG", "GET", "-1").Array, 4)
+	assert.Equal(t, 5, c.do("SLOWLOG", "LEN").Number)
+
+	assert.Equal(t, "OK", c.do("SLOWLOG", "RESET").String)
+	assert.Equal(t, 1, c.do("SLOWLOG", "LEN").Number)
+
+	assert.Equal(t, "ERR count should be greater than or equal to -1", c.do("SLOWLOG", "GET", "-2").String)
+	assert.Equal(t, "ERR value is not an integer or out of range", c.do("SLOWLOG", "GET", "x").String)
+	assert.Equal(t, "ERR wrong number of arguments for 'slowlog|len' command", c.do("SLOWLOG", "LEN", "x").String)
+	assert.Equal(t, "ERR unknown subcommand 'nope'. Try SLOWLOG HELP.", c.do("SLOWLOG", "nope").String)
+	assert.Equal(t, "ERR unknown subcommand 'no  pe'. Try SLOWLOG HELP.", c.do("SLOWLOG", "no\r\npe").String)
+
+	// the threshold and length can change at runtime, a shorter log is
+	// trimmed without waiting for the next entry
+	assert.Equal(t, "OK", c.do("CONFIG", "SET", "slowlog-max-len", "2", "slowlog-log-slower-than", "-1").String)
+	assert.Equal(t, 2, c.do("SLOWLOG", "LEN").Number)
+	assert.Len(t, c.do("SLOWLOG", "GET", "-1").Array, 2)
+
+	c.do("SLOWLOG", "RESET")
+	c.do("PING")
+	assert.Equal(t, 0, c.do("SLOWLOG", "LEN").Number)
+}
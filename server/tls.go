@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/helewud/redis-clone/config"
+)
+
+// handshakeTimeout bounds how long a TLS client may take to handshake
+const handshakeTimeout = 10 * time.Second
+
+// TLS holds the certificates of the TLS listeners. Reload reads the files
+// again, new connections use the new certificates while established ones
+// are left alone.
+type TLS struct {
+	certFile   string
+	keyFile    string
+	caFile     string
+	clientAuth tls.ClientAuthType
+
+	current atomic.Pointer[tls.Config]
+}
+
+// NewTLS loads the certificates named by cfg
+func NewTLS(cfg config.Config) (*TLS, error) {
+	t := &TLS{
+		certFile: cfg.TLSCertFile,
+		keyFile:  cfg.TLSKeyFile,
+		caFile:   cfg.TLSCACertFile,
+	}
+
+	switch cfg.TLSAuthClients {
+	case "yes":
+		t.clientAuth = tls.RequireAndVerifyClientCert
+	case "optional":
+		t.clientAuth = tls.VerifyClientCertIfGiven
+	default:
+		t.clientAuth = tls.NoClientCert
+	}
+
+	if t.certFile == "" || t.keyFile == "" {
+		return nil, errors.New("tls-cert-file and tls-key-file are required for TLS")
+	}
+	if t.clientAuth != tls.NoClientCert && t.caFile == "" {
+		return nil, errors.New("tls-ca-cert-file is required to authenticate clients")
+	}
+
+	if err := t.Reload(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Reload reads the certificate files again. On error the previous
+// certificates stay in use.
+func (t *TLS) Reload() error {
+	cert, err := tls.LoadX509KeyPair(t.certFile, t.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading TLS certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   t.clientAuth,
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if t.caFile != "" {
+		pem, err := os.ReadFile(t.caFile)
+		if err != nil {
+			return fmt.Errorf("loading TLS CA certificates: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("loading TLS CA certificates: no certificate found in %s", t.caFile)
+		}
+		tlsConfig.ClientCAs = pool
+	}
+
+	t.current.Store(tlsConfig)
+
+	return nil
+}
+
+// Config returns a TLS configuration that always uses the latest
+// certificates
+func (t *TLS) Config() *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return t.current.Load(), nil
+		},
+	}
+}
+
+// ListenTLS listens for TLS connections on the TCP address addr
+func ListenTLS(addr string, t *TLS) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return tls.NewListener(ln, t.Config()), nil
+}
+
+// handshake completes the TLS handshake of conn and returns the user its
+// certificate maps to, empty when it doesn't map to any
+func (s *Server) handshake(conn *tls.Conn) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+
+	if err := conn.HandshakeContext(ctx); err != nil {
+		return "", err
+	}
+
+	if s.config.Config().TLSAuthClientsUser != "CN" {
+		return "", nil
+	}
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return "", nil
+	}
+
+	return certs[0].Subject.CommonName, nil
+}
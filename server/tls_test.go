@@ -0,0 +1,300 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/helewud/redis-clone/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA issues certificates for the TLS tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+	file string
+}
+
+func newTestCA(t *testing.T, dir string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	ca := &testCA{cert: cert, key: key, pool: x509.NewCertPool(), file: filepath.Join(dir, "ca.crt")}
+	ca.pool.AddCert(cert)
+	require.NoError(t, os.WriteFile(ca.file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+
+	return ca
+}
+
+// issue writes a certificate for cn and its key to dir/name.crt and
+// dir/name.key
+func (ca *testCA) issue(t *testing.T, dir, name, cn string, serial int64) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	return certFile, keyFile
+}
+
+func (ca *testCA) clientCert(t *testing.T, dir, cn string) tls.Certificate {
+	t.Helper()
+
+	certFile, keyFile := ca.issue(t, dir, cn, cn, 100)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+
+	return cert
+}
+
+// serveTLS runs a server with a TLS listener configured by cfg
+func serveTLS(t *testing.T, cfg config.Config) (*Server, *TLS, string) {
+	t.Helper()
+
+	certs, err := NewTLS(cfg)
+	require.NoError(t, err)
+
+	ln, err := ListenTLS("127.0.0.1:0", certs)
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	s := New(nil, config.NewManager(cfg, ""))
+	go s.Serve(ln)
+
+	return s, certs, ln.Addr().String()
+}
+
+func ping(t *testing.T, conn net.Conn) error {
+	t.Helper()
+
+	if _, err := conn.Write([]byte("PING\r\n")); err != nil {
+		return err
+	}
+
+	reply := make([]byte, len("+PONG\r\n"))
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	assert.Equal(t, "+PONG\r\n", string(reply))
+
+	return nil
+}
+
+func TestTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+
+	cfg := config.Default()
+	cfg.TLSCertFile, cfg.TLSKeyFile = ca.issue(t, dir, "server", "server", 2)
+	_, _, addr := serveTLS(t, cfg)
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: ca.pool})
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, ping(t, conn))
+
+	// plaintext clients don't get through
+	plain, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer plain.Close()
+	assert.Error(t, ping(t, plain))
+}
+
+func TestTLSHandshakeTracked(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+
+	cfg := config.Default()
+	cfg.TLSCertFile, cfg.TLSKeyFile = ca.issue(t, dir, "server", "server", 2)
+	cfg.MaxClients = 1
+	s, _, addr := serveTLS(t, cfg)
+
+	// a client that never handshakes takes the only slot
+	stalled, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer stalled.Close()
+	require.Eventually(t, func() bool {
+		return len(s.clients()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err = tls.Dial("tcp", addr, &tls.Config{RootCAs: ca.pool})
+	assert.Error(t, err)
+
+	// and doesn't hold up a shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, s.Shutdown(ctx))
+}
+
+func TestTLSClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	alice := ca.clientCert(t, dir, "alice")
+
+	otherCA := newTestCA(t, t.TempDir())
+	mallory := otherCA.clientCert(t, t.TempDir(), "mallory")
+
+	cfg := config.Default()
+	cfg.TLSCertFile, cfg.TLSKeyFile = ca.issue(t, dir, "server", "server", 2)
+	cfg.TLSCACertFile = ca.file
+
+	tests := []struct {
+		name    string
+		auth    string
+		certs   []tls.Certificate
+		wantErr bool
+	}{
+		{name: "required and given", auth: "yes", certs: []tls.Certificate{alice}},
+		{name: "required and missing", auth: "yes", wantErr: true},
+		{name: "required and untrusted", auth: "yes", certs: []tls.Certificate{mallory}, wantErr: true},
+		{name: "optional and missing", auth: "optional"},
+		{name: "optional and untrusted", auth: "optional", certs: []tls.Certificate{mallory}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cfg
+			cfg.TLSAuthClients = tt.auth
+			_, _, addr := serveTLS(t, cfg)
+
+			conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: ca.pool, Certificates: tt.certs})
+			if err == nil {
+				defer conn.Close()
+				// TLS 1.3 reports a rejected client certificate on first use
+				err = ping(t, conn)
+			}
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("CA file required", func(t *testing.T) {
+		cfg := cfg
+		cfg.TLSAuthClients = "yes"
+		cfg.TLSCACertFile = ""
+		_, err := NewTLS(cfg)
+		assert.Error(t, err)
+	})
+}
+
+func TestTLSUserFromCN(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	alice := ca.clientCert(t, dir, "alice")
+
+	cfg := config.Default()
+	cfg.TLSCertFile, cfg.TLSKeyFile = ca.issue(t, dir, "server", "server", 2)
+	cfg.TLSCACertFile = ca.file
+	cfg.TLSAuthClients = "yes"
+
+	for _, mapping := range []string{"CN", "off"} {
+		t.Run(mapping, func(t *testing.T) {
+			cfg := cfg
+			cfg.TLSAuthClientsUser = mapping
+			certs, err := NewTLS(cfg)
+			require.NoError(t, err)
+			s := New(nil, config.NewManager(cfg, ""))
+
+			clientConn, serverConn := net.Pipe()
+			defer clientConn.Close()
+			go func() {
+				conn := tls.Client(clientConn, &tls.Config{
+					RootCAs:      ca.pool,
+					Certificates: []tls.Certificate{alice},
+					ServerName:   "127.0.0.1",
+				})
+				conn.Handshake()
+			}()
+
+			user, err := s.handshake(tls.Server(serverConn, certs.Config()))
+			require.NoError(t, err)
+			if mapping == "CN" {
+				assert.Equal(t, "alice", user)
+			} else {
+				assert.Empty(t, user)
+			}
+		})
+	}
+}
+
+func TestTLSReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+
+	cfg := config.Default()
+	cfg.TLSCertFile, cfg.TLSKeyFile = ca.issue(t, dir, "server", "server", 2)
+	_, certs, addr := serveTLS(t, cfg)
+
+	serial := func() int64 {
+		conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: ca.pool})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+	}
+	assert.Equal(t, int64(2), serial())
+
+	// a renewed certificate is picked up by new connections
+	ca.issue(t, dir, "server", "server", 3)
+	require.NoError(t, certs.Reload())
+	assert.Equal(t, int64(3), serial())
+
+	// a broken file keeps the last good certificate
+	require.NoError(t, os.WriteFile(cfg.TLSKeyFile, []byte("garbage"), 0600))
+	assert.Error(t, certs.Reload())
+	assert.Equal(t, int64(3), serial())
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package server
+
+// withPrivateUmask runs f, there is no umask to set on this platform
+func withPrivateUmask(f func() error) error {
+	return f()
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package server
+
+import "syscall"
+
+// withPrivateUmask runs f with a umask that lets no one access the files it
+// creates. The umask is process wide, so files created meanwhile elsewhere
+// err on the private side.
+func withPrivateUmask(f func() error) error {
+	old := syscall.Umask(0o777)
+	defer syscall.Umask(old)
+
+	return f()
+}
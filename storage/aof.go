@@ -2,6 +2,8 @@ package storage
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"sync"
@@ -10,10 +12,52 @@ import (
 	"github.com/helewud/redis-clone/resp"
 )
 
+// FsyncPolicy is when writes to the AOF are flushed to disk
+type FsyncPolicy string
+
+const (
+	// FsyncAlways flushes after every write
+	FsyncAlways FsyncPolicy = "always"
+
+	// FsyncEverySec flushes once a second, losing at most a second of
+	// writes on a crash
+	FsyncEverySec FsyncPolicy = "everysec"
+
+	// FsyncNo leaves flushing to the operating system
+	FsyncNo FsyncPolicy = "no"
+)
+
 type Aof struct {
 	file   *os.File
 	reader *bufio.Reader
+	buf    []byte
 	mu     sync.Mutex
+	fsync  FsyncPolicy
+
+	// size is the length of the file, baseSize its length once replayed
+	size     int64
+	baseSize int64
+
+	// writeErr and fsyncErr are the errors of the last write and fsync
+	writeErr error
+	fsyncErr error
+
+	// done stops the sync goroutine, closed tells Close it already ran
+	done   chan struct{}
+	closed bool
+}
+
+// Stats describes the AOF, as reported by INFO
+type Stats struct {
+	// Size is the length of the file, BaseSize its length when it was
+	// last replayed
+	Size     int64
+	BaseSize int64
+
+	// WriteErr and FsyncErr are the errors of the last write and fsync,
+	// nil when they succeeded
+	WriteErr error
+	FsyncErr error
 }
 
 func NewAof(path string) (*Aof, error) {
@@ -22,43 +66,123 @@ func NewAof(path string) (*Aof, error) {
 		return nil, err
 	}
 
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
 	aof := &Aof{
-		file:   f,
-		reader: bufio.NewReader(f),
+		file:     f,
+		reader:   bufio.NewReader(f),
+		fsync:    FsyncEverySec,
+		size:     info.Size(),
+		baseSize: info.Size(),
+		done:     make(chan struct{}),
 	}
 
 	// Start a goroutine to sync AOF to disk every 1 second
-	go func() {
-		for {
-			aof.mu.Lock()
+	go aof.syncLoop(time.Second)
 
-			aof.file.Sync()
+	return aof, nil
+}
 
-			aof.mu.Unlock()
+func (aof *Aof) syncLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-			time.Sleep(time.Second)
+	for {
+		select {
+		case <-aof.done:
+			return
+		case <-ticker.C:
+			aof.mu.Lock()
+			if !aof.closed && aof.fsync == FsyncEverySec {
+				aof.sync()
+			}
+			aof.mu.Unlock()
 		}
-	}()
+	}
+}
 
-	return aof, nil
+// SetFsync changes when writes are flushed to disk, the default is
+// FsyncEverySec
+func (aof *Aof) SetFsync(policy FsyncPolicy) {
+	aof.mu.Lock()
+	defer aof.mu.Unlock()
+
+	aof.fsync = policy
 }
 
+// Sync flushes the file to disk
+func (aof *Aof) Sync() error {
+	aof.mu.Lock()
+	defer aof.mu.Unlock()
+
+	if aof.closed {
+		return os.ErrClosed
+	}
+
+	return aof.sync()
+}
+
+// sync flushes the file and records the outcome, the caller holds mu
+func (aof *Aof) sync() error {
+	aof.fsyncErr = aof.file.Sync()
+	return aof.fsyncErr
+}
+
+// Stats returns the size of the file and the outcome of the last write and
+// fsync
+func (aof *Aof) Stats() Stats {
+	aof.mu.Lock()
+	defer aof.mu.Unlock()
+
+	return Stats{
+		Size:     aof.size,
+		BaseSize: aof.baseSize,
+		WriteErr: aof.writeErr,
+		FsyncErr: aof.fsyncErr,
+	}
+}
+
+// Close stops the sync goroutine, flushes the file to disk a last time and
+// closes it. Closing twice is a no-op.
 func (aof *Aof) Close() error {
 	aof.mu.Lock()
 	defer aof.mu.Unlock()
 
-	return aof.file.Close()
+	if aof.closed {
+		return nil
+	}
+	aof.closed = true
+	close(aof.done)
+
+	syncErr := aof.file.Sync()
+	if err := aof.file.Close(); err != nil {
+		return err
+	}
+
+	return syncErr
 }
 
 func (aof *Aof) Write(value resp.Value) error {
 	aof.mu.Lock()
 	defer aof.mu.Unlock()
 
-	_, err := aof.file.Write(value.Marshal())
+	aof.buf = value.Append(aof.buf[:0])
+
+	n, err := aof.file.Write(aof.buf)
+	aof.size += int64(n)
+	aof.writeErr = err
 	if err != nil {
 		return err
 	}
 
+	if aof.fsync == FsyncAlways {
+		return aof.sync()
+	}
+
 	return nil
 }
 
@@ -75,12 +199,31 @@ func (aof *Aof) Read(callback func(value resp.Value) error) error {
 	// Reset the reader with the file
 	aof.reader = bufio.NewReader(aof.file)
 	reader := resp.NewReader(aof.reader)
+	defer reader.Release()
 
 	for {
+		start := reader.Offset()
+
 		value, err := reader.Read()
 		if err == io.EOF {
 			break
 		}
+		if errors.Is(err, resp.ErrUnexpectedEOF) {
+			// the last command was cut short, e.g. by a crash in the middle
+			// of a write, so drop it and carry on from the last good one
+			if err := aof.file.Truncate(start); err != nil {
+				return err
+			}
+			if _, err := aof.file.Seek(start, io.SeekStart); err != nil {
+				return err
+			}
+			aof.size = start
+			break
+		}
+		var protoErr *resp.ProtocolError
+		if errors.As(err, &protoErr) {
+			return fmt.Errorf("aof corrupted at offset %d: %w", protoErr.Offset, err)
+		}
 		if err != nil {
 			return err
 		}
@@ -88,6 +231,7 @@ func (aof *Aof) Read(callback func(value resp.Value) error) error {
 			return err
 		}
 	}
+	aof.baseSize = aof.size
 
 	return nil
 }
@@ -49,7 +49,7 @@ func TestAofWrite(t *testing.T) {
 	t.Run("single write", func(t *testing.T) {
 		value := resp.Value{
 			T:    resp.RespTBulk,
-			Bulk: "test data",
+			Bulk: []byte("test data"),
 		}
 
 		err := aof.Write(value)
@@ -71,7 +71,7 @@ func TestAofWrite(t *testing.T) {
 				defer wg.Done()
 				value := resp.Value{
 					T:    resp.RespTBulk,
-					Bulk: fmt.Sprintf("data-%d", i),
+					Bulk: []byte(fmt.Sprintf("data-%d", i)),
 				}
 				err := aof.Write(value)
 				require.NoError(t, err)
@@ -102,9 +102,9 @@ func TestAofRead(t *testing.T) {
 
 	// Write test data
 	testValues := []resp.Value{
-		{T: resp.RespTBulk, Bulk: "test1"},
-		{T: resp.RespTBulk, Bulk: "test2"},
-		{T: resp.RespTBulk, Bulk: "test3"},
+		{T: resp.RespTBulk, Bulk: []byte("test1")},
+		{T: resp.RespTBulk, Bulk: []byte("test2")},
+		{T: resp.RespTBulk, Bulk: []byte("test3")},
 	}
 
 	for _, value := range testValues {
@@ -115,14 +115,14 @@ func TestAofRead(t *testing.T) {
 	t.Run("read all values", func(t *testing.T) {
 		var readValues []string
 		err := aof.Read(func(value resp.Value) error {
-			readValues = append(readValues, value.Bulk)
+			readValues = append(readValues, string(value.Bulk))
 			return nil
 		})
 		require.NoError(t, err)
 
 		assert.Equal(t, len(testValues), len(readValues))
 		for i, value := range testValues {
-			assert.Equal(t, value.Bulk, readValues[i])
+			assert.Equal(t, string(value.Bulk), readValues[i])
 		}
 	})
 
@@ -145,7 +145,7 @@ func TestAofSync(t *testing.T) {
 	defer aof.Close()
 
 	// Write test data
-	value := resp.Value{T: resp.RespTBulk, Bulk: "sync test"}
+	value := resp.Value{T: resp.RespTBulk, Bulk: []byte("sync test")}
 	err = aof.Write(value)
 	require.NoError(t, err)
 
@@ -167,7 +167,7 @@ func TestAofClose(t *testing.T) {
 	require.NoError(t, err)
 
 	// Write some data
-	value := resp.Value{T: resp.RespTBulk, Bulk: "close test"}
+	value := resp.Value{T: resp.RespTBulk, Bulk: []byte("close test")}
 	err = aof.Write(value)
 	require.NoError(t, err)
 
@@ -200,7 +200,7 @@ func TestAofLocking(t *testing.T) {
 				defer wg.Done()
 				value := resp.Value{
 					T:    resp.RespTBulk,
-					Bulk: fmt.Sprintf("concurrent-%d", i),
+					Bulk: []byte(fmt.Sprintf("concurrent-%d", i)),
 				}
 				err := aof.Write(value)
 				require.NoError(t, err)
@@ -233,8 +233,8 @@ func TestAofRecovery(t *testing.T) {
 	require.NoError(t, err)
 
 	initialData := []resp.Value{
-		{T: resp.RespTBulk, Bulk: "data1"},
-		{T: resp.RespTBulk, Bulk: "data2"},
+		{T: resp.RespTBulk, Bulk: []byte("data1")},
+		{T: resp.RespTBulk, Bulk: []byte("data2")},
 	}
 
 	for _, value := range initialData {
@@ -252,13 +252,107 @@ func TestAofRecovery(t *testing.T) {
 
 	var recoveredData []string
 	err = aof2.Read(func(value resp.Value) error {
-		recoveredData = append(recoveredData, value.Bulk)
+		recoveredData = append(recoveredData, string(value.Bulk))
 		return nil
 	})
 	require.NoError(t, err)
 
 	assert.Equal(t, len(initialData), len(recoveredData))
 	for i, value := range initialData {
-		assert.Equal(t, value.Bulk, recoveredData[i])
+		assert.Equal(t, string(value.Bulk), recoveredData[i])
 	}
 }
+
+// TestAofTruncatedTail tests that a command cut short by a crash is dropped
+func TestAofTruncatedTail(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "truncated.aof")
+
+	complete := resp.Value{T: resp.RespTBulk, Bulk: []byte("complete")}.Marshal()
+	data := append(complete, []byte("$9\r\nincom")...)
+	require.NoError(t, os.WriteFile(path, data, 0666))
+
+	aof, err := NewAof(path)
+	require.NoError(t, err)
+	defer aof.Close()
+
+	var recovered []string
+	err = aof.Read(func(value resp.Value) error {
+		recovered = append(recovered, string(value.Bulk))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"complete"}, recovered)
+
+	// new writes continue right after the last complete command
+	require.NoError(t, aof.Write(resp.Value{T: resp.RespTBulk, Bulk: []byte("next")}))
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "$8\r\ncomplete\r\n$4\r\nnext\r\n", string(contents))
+}
+
+// TestAofCorrupted tests that corruption is reported with its offset
+func TestAofCorrupted(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "corrupted.aof")
+
+	require.NoError(t, os.WriteFile(path, []byte("$2\r\nok\r\n$x\r\n"), 0666))
+
+	aof, err := NewAof(path)
+	require.NoError(t, err)
+	defer aof.Close()
+
+	err = aof.Read(func(value resp.Value) error {
+		return nil
+	})
+	require.ErrorIs(t, err, resp.ErrProtocol)
+	assert.Contains(t, err.Error(), "offset 12")
+}
+
+// TestAofCloseTwice tests that Close syncs once and can be called again
+func TestAofCloseTwice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "close_twice_test.aof")
+
+	aof, err := NewAof(path)
+	require.NoError(t, err)
+
+	require.NoError(t, aof.Write(resp.Value{T: resp.RespTBulk, Bulk: []byte("last write")}))
+	require.NoError(t, aof.Close())
+	require.NoError(t, aof.Close())
+
+	assert.ErrorIs(t, aof.Sync(), os.ErrClosed)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "$10\r\nlast write\r\n", string(data))
+}
+
+// TestAofStats tests the sizes and write status reported for INFO
+func TestAofStats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.aof")
+
+	complete := resp.Value{T: resp.RespTBulk, Bulk: []byte("complete")}.Marshal()
+	require.NoError(t, os.WriteFile(path, append(complete, []byte("$9\r\nincom")...), 0666))
+
+	aof, err := NewAof(path)
+	require.NoError(t, err)
+	defer aof.Close()
+
+	// the cut short command is dropped when replaying
+	require.NoError(t, aof.Read(func(resp.Value) error { return nil }))
+	assert.Equal(t, Stats{Size: int64(len(complete)), BaseSize: int64(len(complete))}, aof.Stats())
+
+	next := resp.Value{T: resp.RespTBulk, Bulk: []byte("next")}
+	require.NoError(t, aof.Write(next))
+	require.NoError(t, aof.Sync())
+	assert.Equal(t, Stats{Size: int64(len(complete) + len(next.Marshal())), BaseSize: int64(len(complete))}, aof.Stats())
+
+	// the file can't be written once closed under the AOF
+	aof.file.Close()
+	assert.Error(t, aof.Write(next))
+	assert.Error(t, aof.Sync())
+	stats := aof.Stats()
+	assert.Error(t, stats.WriteErr)
+	assert.Error(t, stats.FsyncErr)
+}